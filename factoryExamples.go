@@ -0,0 +1,44 @@
+package main
+
+import (
+	"hybfkuf/pkg/k8s"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func factoryExamples() {
+	var (
+		name = "nginx"
+	)
+	// all handlers created by the factory share one rest.Config and one set of clients.
+	factory, err := k8s.NewFactory(ctx, *kubeconfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 1. get deployment
+	if deploy, err := factory.Deployments(NAMESPACE).Get(name); err != nil {
+		log.Error("get deployment failed")
+		log.Error(err)
+	} else {
+		log.Infof("get deployment %q success.", deploy.Name)
+	}
+	// 2. list pods
+	if podList, err := factory.Pods(NAMESPACE).ListAll(); err != nil {
+		log.Error("list pods failed")
+		log.Error(err)
+	} else {
+		for _, pod := range podList.Items {
+			log.Info(pod.Name)
+		}
+	}
+	// 3. list nodes
+	if nodeList, err := factory.Nodes().ListAll(); err != nil {
+		log.Error("list nodes failed")
+		log.Error(err)
+	} else {
+		for _, node := range nodeList.Items {
+			log.Info(node.Name)
+		}
+	}
+}
@@ -1,10 +1,11 @@
 module hybfkuf
 
-go 1.17
+go 1.18
 
 replace extractapply_appsv1 => ./extractapply/apps/v1
 
 require (
+	github.com/evanphx/json-patch v4.12.0+incompatible
 	github.com/sirupsen/logrus v1.8.1
 	golang.org/x/crypto v0.0.0-20220331220935-ae2d96664a29
 	k8s.io/api v0.23.5
@@ -12,6 +13,7 @@ require (
 	k8s.io/client-go v0.23.5
 	k8s.io/metrics v0.23.5
 	k8s.io/utils v0.0.0-20220210201930-3a6ce19ff2f9
+	sigs.k8s.io/yaml v1.2.0
 )
 
 require (
@@ -27,6 +29,7 @@ require (
 	github.com/moby/spdystream v0.2.0 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/net v0.0.0-20211209124913-491a49abca63 // indirect
 	golang.org/x/oauth2 v0.0.0-20210819190943-2bc19b11175f // indirect
@@ -43,5 +46,4 @@ require (
 	k8s.io/kube-openapi v0.0.0-20211115234752-e816edb12b65 // indirect
 	sigs.k8s.io/json v0.0.0-20211020170558-c049b76a60c6 // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.2.1 // indirect
-	sigs.k8s.io/yaml v1.2.0 // indirect
 )
@@ -14,14 +14,27 @@ func kubectl() {
 		//yamlfile = "./testData/nginx-sts.yaml"
 		err error
 	)
-	err = k8s.ApplyF(ctx, *kubeconfig, yamlfile)
+	// 查看 apply 会修改哪些字段, 类似 "kubectl diff"
+	report, err := k8s.DiffF(ctx, *kubeconfig, yamlfile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Info("\n", report.Diff())
+
+	report, err = k8s.ApplyF(ctx, *kubeconfig, yamlfile)
+	if report != nil {
+		log.Info("\n", report)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	time.Sleep(time.Second * 30)
 	log.Info()
-	err = k8s.DeleteF(ctx, *kubeconfig, yamlfile)
+	report, err = k8s.DeleteF(ctx, *kubeconfig, yamlfile)
+	if report != nil {
+		log.Info("\n", report)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
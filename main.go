@@ -27,6 +27,7 @@ func init() {
 }
 
 func main() {
+	//factoryExamples()
 	//clusterrolebindingExamples()
 	//clusterroleExamples()
 	//configmapExamples()
@@ -8,7 +8,6 @@ package k8s
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/sirupsen/logrus"
 	appsv1 "k8s.io/api/apps/v1"
@@ -70,13 +69,8 @@ func DeploymentInformer(ctx context.Context, clientset *kubernetes.Clientset, na
 			},
 		},
 	)
-	stop := make(chan struct{})
-	defer close(stop)
-	go controller.Run(stop)
-	for {
-		time.Sleep(time.Second)
-	}
-
+	// run the informer until ctx is done.
+	controller.Run(ctx.Done())
 }
 
 func ServiceInformer(ctx context.Context, clientset *kubernetes.Clientset, namespace string) {
@@ -112,10 +106,6 @@ func ServiceInformer(ctx context.Context, clientset *kubernetes.Clientset, names
 	//     cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
 	// })
 	// go serviceInformer.Run(stop)
-	stop := make(chan struct{})
-	defer close(stop)
-	go controller.Run(stop)
-	for {
-		time.Sleep(time.Second)
-	}
+	// run the informer until ctx is done.
+	controller.Run(ctx.Done())
 }
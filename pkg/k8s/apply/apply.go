@@ -1,199 +1,440 @@
-//package apply
-
-//import (
-//    "bytes"
-//    "context"
-//    "fmt"
-//    "io"
-
-//    corev1 "k8s.io/api/core/v1"
-//    "k8s.io/apimachinery/pkg/api/meta"
-//    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-//    "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-//    "k8s.io/apimachinery/pkg/runtime"
-//    "k8s.io/apimachinery/pkg/runtime/serializer/yaml"
-//    "k8s.io/apimachinery/pkg/types"
-//    k8syaml "k8s.io/apimachinery/pkg/util/yaml"
-//    "k8s.io/client-go/discovery"
-//    "k8s.io/client-go/dynamic"
-//    "k8s.io/client-go/restmapper"
-//    "k8s.io/klog"
-//    "k8s.io/kubectl/pkg/scheme"
-//)
-
-//const DefaultDecoderBufferSize = 500
-
-//type applyOptions struct {
-//    dynamicClient   dynamic.Interface
-//    discoveryClient discovery.DiscoveryInterface
-//    serverSide      bool
-//}
-
-//func NewApplyOptions(dynamicClient dynamic.Interface, discoveryClient discovery.DiscoveryInterface) *applyOptions {
-//    return &applyOptions{
-//        dynamicClient:   dynamicClient,
-//        discoveryClient: discoveryClient,
-//    }
-//}
-//func (o *applyOptions) WithServerSide(serverSide bool) *applyOptions {
-//    o.serverSide = serverSide
-//    return o
-//}
-
-//// discovery.DiscoveryInterface -> []*APIGroupResources -> meta.RESTMapper
-//func (o *applyOptions) ToRESTMapper() (meta.RESTMapper, error) {
-//    gv, err := restmapper.GetAPIGroupResources(o.discoveryClient)
-//    if err != nil {
-//        return nil, err
-//    }
-//    restMapper := restmapper.NewDiscoveryRESTMapper(gv)
-//    return restMapper, nil
-//}
-
-//// data []byte + RESTMapper -> apply yaml file
-//func (o *applyOptions) Apply(ctx context.Context, data []byte) error {
-//    restMapper, err := o.ToRESTMapper()
-//    if err != nil {
-//        return err
-//    }
-//    unstructList, err := Decode(data)
-//    if err != nil {
-//        return err
-//    }
-
-//    for _, unstruct := range unstructList {
-//        klog.V(5).Infof("Apply object: %#v", unstruct)
-//        j
-//    }
-//    return nil
-//}
-
-//// data []byte -> []unstructured.Unstructured
-//// []byte -> runtime.Object -> map[string]interface{} -> unstructured.Unstructured -> []unstructured.Unstructured
-//func Decode(data []byte) (unstructList []unstructured.Unstructured, err error) {
-//    decoder := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), DefaultDecoderBufferSize)
-
-//    i := 1
-//    for {
-//        var reqObj runtime.RawExtension
-//        if err = decoder.Decode(&reqObj); err != nil {
-//            break
-//        }
-//        klog.V(5).Infof("The section:[%d] raw content: %s", i, string(reqObj.Raw))
-//        obj, gvk, err := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme).Decode(reqObj.Raw, nil, nil)
-//        if err != nil {
-//            err = fmt.Errorf("serialize the section:[%d] content error, %v", i, err)
-//            break
-//        }
-//        klog.V(5).Infof("The section:[%d] GroupVersionKind: %#v  object: %#v", i, gvk, obj)
-
-//        unstruct, err := ConvertSingleObjectToUnstructured(obj)
-//        if err != nil {
-//            err = fmt.Errorf("serialize the section:[%d] content error, %v", i, err)
-//            break
-//        }
-//        unstructList = append(unstructList, unstruct)
-//        i++
-//    }
-//    if err != io.EOF {
-//        return unstructList, fmt.Errorf("parsing the section:[%d] content error, %v", i, err)
-//    }
-
-//    return unstructList, nil
-//}
-
-//// []byte -> runtime.Object
-//// runtime.Object 可以转换成 unstructured.Unstructured 或 *appsv1.Deployment
-//// runtime.Object 转换成 unstructured.Unstructured 使用 dynamic 客户端来操作 k8s
-//// runtime.Object 转换成 appsv1.Deployment 等使用 clientset 客户端来操作 k8s
-//func Decode2(data []byte) (object runtime.Object, err error) {
-//    decode := scheme.Codecs.UniversalDeserializer().Decode
-//    object, _, err = decode(data, nil, nil)
-//    return
-//}
-
-//func ApplyUnstructured(ctx context.Context,
-//    dynamicClient dynamic.Interface, restMapper meta.RESTMapper,
-//    unstructuredObj unstructured.Unstructured, serverSide bool) (*unstructured.Unstructured, error) {
-//    if len(unstructuredObj.GetName()) == 0 {
-//        metadata, _ := meta.Accessor(unstructuredObj)
-//        generateName := metadata.GetGenerateName()
-//        if len(generateName) > 0 {
-//            return nil, fmt.Errorf("from %s: cannot use generate name with apply", generateName)
-//        }
-//    }
-//    // unstructuredObj -> JSON []byte
-//    b, err := unstructuredObj.MarshalJSON()
-//    if err != nil {
-//        return nil, err
-//    }
-//    // JSON []byte ->  runtime.Object
-//    obj, gvk, err := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme).Decode(b, nil, nil)
-//    if err != nil {
-//        return nil, err
-//    }
-
-//    mapping, err := restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
-//    if err != nil {
-//        return nil, err
-//    }
-//    klog.V(5).Infof("mapping: %v", mapping.Scope.Name())
-//    var dir dynamic.ResourceInterface
-//    if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
-//        if unstructuredObj.GetNamespace() == "" {
-//            unstructuredObj.SetNamespace("default")
-//        }
-//        dri = dynamicClient.Resource(mapping.Resource).Namespace(unstructuredObj.GetNamespace())
-//    } else {
-//        dri = dynamicClient.Resource(mapping.Resource)
-//    }
-//    if serverSide {
-//        klog.V(2).Infof("Using server-side apply")
-//        if _, ok := unstructuredObj.GetAnnotations()[corev1.LastAppliedConfigAnnotation]; ok {
-//            annotations := unstructuredObj.GetAnnotations()
-//            delete(annotations, corev1.LastAppliedConfigAnnotation)
-//            unstructuredObj.SetAnnotations(annotations)
-//        }
-//        unstructuredObj.SetManagedFields(nil)
-//        klog.V(4).Infof("Need remove managedFields before apply, %#v", unstructuredObj)
-
-//        force := true
-//        opts := metav1.PatchOptions{FieldManager: "k8sutil", Force: &force}
-//        if _, err := dri.Patch(ctx, unstructuredObj.GetName(), types.ApplyPatchType, b, opts); err != nil {
-//            if isIncompatibleServerError(err) {
-//                err = fmt.Errorf("server-side apply not available on the server: (%v)", err)
-//            }
-//            return nil, err
-//        }
-//        return nil, err
-//    }
-
-//    return nil, nil
-//}
-
-//func ConvertObjectToUnstructuredList(obj runtime.Object) ([]unstructured.Unstructured, error) {
-//    list := make([]unstructured.Unstructured, 0, 0)
-//    if meta.IsListType(obj) {
-//        if _, ok := obj.(*unstructured.UnstructuredList); !ok {
-//            return nil, fmt.Errorf("unable to convert runtime object to list")
-//        }
-//        for _, u := range obj.(*unstructured.UnstructuredList).Items {
-//            list = append(list, u)
-//        }
-//        return list, nil
-//    }
-//    unstructuredMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
-//    if err != nil {
-//        return nil, err
-//    }
-//    unstructuredObj := unstructured.Unstructured{Object: unstructuredMap}
-//    list = append(list, unstructuredObj)
-//    return list, nil
-//}
-//func ConvertSingleObjectToUnstructured(object runtime.Object) (unstructured.Unstructured, error) {
-//    unstructuredMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
-//    if err != nil {
-//        return unstructured.Unstructured{}, err
-//    }
-//    return unstructured.Unstructured{Object: unstructuredMap}, nil
-//}
+package apply
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/restmapper"
+)
+
+const (
+	// DefaultFieldManager is the field manager used by server-side apply.
+	DefaultFieldManager = "client-go"
+)
+
+// Mode is the strategy used to apply an object.
+type Mode string
+
+const (
+	// ModeServerSide sends the object as an ApplyPatchType patch, the api
+	// server merges it with the live object and tracks the field ownership.
+	ModeServerSide Mode = "ServerSide"
+	// ModeCreateOrUpdate creates the object, and replaces it with a full
+	// update if it already exists.
+	ModeCreateOrUpdate Mode = "CreateOrUpdate"
+	// ModeClientSide works like "kubectl apply" without --server-side,
+	// the manifest is saved in the last-applied-configuration annotation and
+	// a three-way merge patch is sent, fields removed from the manifest are
+	// removed from the live object too.
+	ModeClientSide Mode = "ClientSide"
+)
+
+// Applier applies or deletes any kind of object the api server knows about,
+// including CRDs, HPAs and PDBs. The resource of the object is found by a
+// discovery RESTMapper, whether the resource is namespaced or cluster scoped
+// is resolved from the mapping, and the object is sent by the dynamic client.
+type Applier struct {
+	ctx             context.Context
+	dynamicClient   dynamic.Interface
+	discoveryClient discovery.DiscoveryInterface
+	restMapper      *restmapper.DeferredDiscoveryRESTMapper
+
+	namespace         string
+	mode              Mode
+	fieldManager      string
+	force             bool
+	dryRun            bool
+	propagationPolicy metav1.DeletionPropagation
+	waitTimeout       time.Duration
+
+	applySet        string
+	prune           bool
+	pruneNamespaces []string
+	pruneKinds      []schema.GroupKind
+
+	render       RenderFunc
+	renderOutput io.Writer
+}
+
+// New returns an Applier, the namespaced objects without namespace are
+// applied in the "default" namespace, the objects are applied with server-side
+// apply and deleted in the background.
+func New(ctx context.Context, dynamicClient dynamic.Interface, discoveryClient discovery.DiscoveryInterface) *Applier {
+	return &Applier{
+		ctx:               ctx,
+		dynamicClient:     dynamicClient,
+		discoveryClient:   discoveryClient,
+		restMapper:        NewRESTMapper(discoveryClient),
+		namespace:         metav1.NamespaceDefault,
+		mode:              ModeServerSide,
+		fieldManager:      DefaultFieldManager,
+		propagationPolicy: metav1.DeletePropagationBackground,
+		waitTimeout:       DefaultWaitTimeout,
+	}
+}
+
+// NewRESTMapper returns a RESTMapper caching the discovery information, it's
+// fetched on the first use, and fetched again by Reset, eg: after a CRD is
+// established. Share it between the Appliers with SetRESTMapper to avoid the
+// discovery of every Applier.
+func NewRESTMapper(discoveryClient discovery.DiscoveryInterface) *restmapper.DeferredDiscoveryRESTMapper {
+	return restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+}
+
+// SetRESTMapper set the RESTMapper of the Applier, eg: a RESTMapper shared by
+// the Appliers of the same cluster, see NewRESTMapper.
+func (a *Applier) SetRESTMapper(mapper *restmapper.DeferredDiscoveryRESTMapper) {
+	if mapper != nil {
+		a.restMapper = mapper
+	}
+}
+
+// SetNamespace set the namespace of the namespaced objects without namespace.
+func (a *Applier) SetNamespace(namespace string) {
+	if len(namespace) != 0 {
+		a.namespace = namespace
+	}
+}
+
+// SetMode set the strategy used to apply objects, default to ModeServerSide.
+func (a *Applier) SetMode(mode Mode) {
+	if len(mode) != 0 {
+		a.mode = mode
+	}
+}
+
+// SetFieldManager set the field manager used by server-side apply.
+func (a *Applier) SetFieldManager(name string) {
+	if len(name) != 0 {
+		a.fieldManager = name
+	}
+}
+
+// SetForceConflicts force server-side apply to take the ownership of
+// fields managed by others.
+func (a *Applier) SetForceConflicts(force bool) {
+	a.force = force
+}
+
+// SetPropagationPolicy set the propagation policy used to delete objects.
+func (a *Applier) SetPropagationPolicy(policy metav1.DeletionPropagation) {
+	a.propagationPolicy = policy
+}
+
+// SetWaitTimeout set how long to wait for a CRD to be established.
+func (a *Applier) SetWaitTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		a.waitTimeout = timeout
+	}
+}
+
+// SetRender set how to render the manifests read by the file and reader
+// methods, eg: Template(values) or Env(nil). The manifests are not rendered
+// by default.
+func (a *Applier) SetRender(render RenderFunc) {
+	a.render = render
+}
+
+// SetRenderOutput set where the rendered manifests are dumped to.
+func (a *Applier) SetRenderOutput(w io.Writer) {
+	a.renderOutput = w
+}
+
+// Loader returns the Loader decoding the manifests of the file and reader
+// methods, with the render and the RESTMapper of the Applier.
+func (a *Applier) Loader() *Loader {
+	return &Loader{Render: a.render, Output: a.renderOutput, RESTMapper: a.restMapper}
+}
+
+// WithDryRun returns a copy of the Applier, the requests of the copy are
+// sent with dryRun=All. The copy shares the RESTMapper.
+func (a *Applier) WithDryRun() *Applier {
+	applier := *a
+	applier.dryRun = true
+	return &applier
+}
+
+// RESTMapper returns the discovery RESTMapper of the Applier, the discovery
+// information is cached and fetched again when ResourceFor doesn't find a kind.
+func (a *Applier) RESTMapper() meta.RESTMapper {
+	return a.restMapper
+}
+
+// ResourceFor returns the dynamic resource client of the object. The namespace
+// of a namespaced object defaults to the namespace of the Applier, and the
+// namespace of a cluster scoped object is ignored.
+func (a *Applier) ResourceFor(obj *unstructured.Unstructured) (dynamic.ResourceInterface, *meta.RESTMapping, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := a.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		// the kind may be created after the discovery, eg: by a CRD.
+		a.restMapper.Reset()
+		mapping, err = a.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if len(obj.GetNamespace()) == 0 {
+			obj.SetNamespace(a.namespace)
+		}
+		return a.dynamicClient.Resource(mapping.Resource).Namespace(obj.GetNamespace()), mapping, nil
+	}
+	obj.SetNamespace("")
+	return a.dynamicClient.Resource(mapping.Resource), mapping, nil
+}
+
+// Apply applies the object with the Mode of the Applier, the result reports
+// whether the object is created, configured or unchanged.
+func (a *Applier) Apply(obj *unstructured.Unstructured) Result {
+	if len(obj.GetName()) == 0 {
+		return a.newResult(obj).done(nil, "", fmt.Errorf("can't apply %s without name", obj.GetKind()))
+	}
+	a.setApplySetLabel(obj)
+	ri, _, err := a.ResourceFor(obj)
+	result := a.newResult(obj)
+	if err != nil {
+		return result.done(nil, "", err)
+	}
+	switch a.mode {
+	case ModeCreateOrUpdate:
+		return result.done(a.createOrUpdate(ri, obj))
+	case ModeClientSide:
+		return result.done(a.clientSideApply(ri, obj))
+	default:
+		return result.done(a.serverSideApply(ri, obj))
+	}
+}
+
+// Delete deletes the object with the propagation policy of the Applier,
+// the object which doesn't exist is skipped.
+func (a *Applier) Delete(obj *unstructured.Unstructured) Result {
+	ri, _, err := a.ResourceFor(obj)
+	result := a.newResult(obj)
+	if err != nil {
+		return result.done(nil, "", err)
+	}
+	err = ri.Delete(a.ctx, obj.GetName(), metav1.DeleteOptions{
+		PropagationPolicy: &a.propagationPolicy,
+		DryRun:            a.dryRunOptions(),
+	})
+	if k8serrors.IsNotFound(err) {
+		return result.done(nil, ActionSkipped, nil)
+	}
+	return result.done(nil, ActionDeleted, err)
+}
+
+// ApplyAll applies the objects sorted by InstallOrder, it doesn't stop on
+// failure. The applied CRDs are waited to be established before the objects
+// after them are applied, so the custom resources in the same manifest can
+// be found by the RESTMapper. The objects of the apply set not in objects
+// are pruned at last if prune is enabled.
+func (a *Applier) ApplyAll(objects []*unstructured.Unstructured) *Report {
+	report := &Report{}
+	for _, obj := range SortForApply(objects) {
+		result := a.Apply(obj)
+		if result.Err == nil && isCRD(obj) && !a.dryRun {
+			if err := a.waitCRD(obj); err != nil {
+				result.Action, result.Err = ActionFailed, err
+			}
+		}
+		report.add(result)
+	}
+	if a.prune && report.Err() == nil {
+		pruned, err := a.Prune(objects)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("prune: %w", err))
+		} else {
+			report.Results = append(report.Results, pruned.Results...)
+		}
+	}
+	return report
+}
+
+// ApplyFile decodes the manifest file and applies the objects with ApplyAll,
+// the file is read from stdin if path is "-". The error is the decode error
+// or the aggregated error of the report.
+func (a *Applier) ApplyFile(path string) (*Report, error) {
+	objects, err := a.Loader().File(path)
+	if err != nil {
+		return nil, err
+	}
+	report := a.ApplyAll(objects)
+	return report, report.Err()
+}
+
+// ApplyReader is the same as ApplyFile, except that the manifest is read from r.
+func (a *Applier) ApplyReader(r io.Reader) (*Report, error) {
+	objects, err := a.Loader().Reader(r)
+	if err != nil {
+		return nil, err
+	}
+	report := a.ApplyAll(objects)
+	return report, report.Err()
+}
+
+// DeleteAll deletes the objects in the reverse order of ApplyAll, it doesn't
+// stop on failure.
+func (a *Applier) DeleteAll(objects []*unstructured.Unstructured) *Report {
+	report := &Report{}
+	for _, obj := range SortForDelete(objects) {
+		report.add(a.Delete(obj))
+	}
+	return report
+}
+
+// DeleteFile decodes the manifest file and deletes the objects with DeleteAll,
+// the file is read from stdin if path is "-".
+func (a *Applier) DeleteFile(path string) (*Report, error) {
+	objects, err := a.Loader().File(path)
+	if err != nil {
+		return nil, err
+	}
+	report := a.DeleteAll(objects)
+	return report, report.Err()
+}
+
+// DeleteReader is the same as DeleteFile, except that the manifest is read from r.
+func (a *Applier) DeleteReader(r io.Reader) (*Report, error) {
+	objects, err := a.Loader().Reader(r)
+	if err != nil {
+		return nil, err
+	}
+	report := a.DeleteAll(objects)
+	return report, report.Err()
+}
+
+// waitCRD waits the CRD to be established, and resets the RESTMapper to
+// discover the new kind.
+func (a *Applier) waitCRD(crd *unstructured.Unstructured) error {
+	ri, _, err := a.ResourceFor(crd)
+	if err != nil {
+		return err
+	}
+	if err := a.waitEstablished(ri, crd.GetName()); err != nil {
+		return err
+	}
+	a.restMapper.Reset()
+	return nil
+}
+
+func (a *Applier) serverSideApply(ri dynamic.ResourceInterface, obj *unstructured.Unstructured) (*unstructured.Unstructured, Action, error) {
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return nil, "", err
+	}
+	live, err := ri.Get(a.ctx, obj.GetName(), metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, "", err
+	}
+	force := a.force
+	applied, err := ri.Patch(a.ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: a.fieldManager,
+		Force:        &force,
+		DryRun:       a.dryRunOptions(),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	switch {
+	case live == nil:
+		return applied, ActionCreated, nil
+	case equalObjects(live, applied):
+		return applied, ActionUnchanged, nil
+	default:
+		return applied, ActionConfigured, nil
+	}
+}
+
+func (a *Applier) createOrUpdate(ri dynamic.ResourceInterface, obj *unstructured.Unstructured) (*unstructured.Unstructured, Action, error) {
+	created, err := ri.Create(a.ctx, obj, metav1.CreateOptions{DryRun: a.dryRunOptions()})
+	if k8serrors.IsAlreadyExists(err) {
+		updated, err := ri.Update(a.ctx, obj, metav1.UpdateOptions{DryRun: a.dryRunOptions()})
+		return updated, ActionConfigured, err
+	}
+	return created, ActionCreated, err
+}
+
+// clientSideApply creates the object with the last-applied-configuration
+// annotation, or patches the live object with a three-way patch. Built-in
+// kinds are patched with strategic merge patch, the others (eg: CRDs) with
+// json merge patch.
+func (a *Applier) clientSideApply(ri dynamic.ResourceInterface, obj *unstructured.Unstructured) (*unstructured.Unstructured, Action, error) {
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return nil, "", err
+	}
+	modified, err := GetModifiedConfiguration(data)
+	if err != nil {
+		return nil, "", err
+	}
+	live, err := ri.Get(a.ctx, obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return nil, "", err
+		}
+		desired := &unstructured.Unstructured{}
+		if err := desired.UnmarshalJSON(modified); err != nil {
+			return nil, "", err
+		}
+		created, err := ri.Create(a.ctx, desired, metav1.CreateOptions{DryRun: a.dryRunOptions()})
+		return created, ActionCreated, err
+	}
+
+	current, err := live.MarshalJSON()
+	if err != nil {
+		return nil, "", err
+	}
+	patch, patchType, err := CreateThreeWayPatch(GetOriginalConfiguration(live), modified, current, dataStructFor(obj))
+	if err != nil {
+		return nil, "", err
+	}
+	if IsEmptyPatch(patch) {
+		return live, ActionUnchanged, nil
+	}
+	patched, err := ri.Patch(a.ctx, obj.GetName(), patchType, patch, metav1.PatchOptions{DryRun: a.dryRunOptions()})
+	return patched, ActionConfigured, err
+}
+
+func (a *Applier) dryRunOptions() []string {
+	if a.dryRun {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
+// dataStructFor returns the typed object of a built-in kind, nil if the kind
+// is not registered in the client-go scheme.
+func dataStructFor(obj *unstructured.Unstructured) runtime.Object {
+	typed, err := scheme.Scheme.New(obj.GroupVersionKind())
+	if err != nil {
+		return nil
+	}
+	return typed
+}
+
+// equalObjects reports whether the objects are the same, ignoring the fields
+// changed by the api server on every write.
+func equalObjects(a, b *unstructured.Unstructured) bool {
+	a, b = a.DeepCopy(), b.DeepCopy()
+	for _, obj := range []*unstructured.Unstructured{a, b} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "managedFields")
+		unstructured.RemoveNestedField(obj.Object, "metadata", "resourceVersion")
+		unstructured.RemoveNestedField(obj.Object, "metadata", "generation")
+	}
+	return equality.Semantic.DeepEqual(a.Object, b.Object)
+}
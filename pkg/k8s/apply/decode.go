@@ -0,0 +1,238 @@
+package apply
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// DefaultDecoderBufferSize is how far into the stream the decoder looks to
+// figure out whether it is a json stream.
+const DefaultDecoderBufferSize = 500
+
+// yamlSeparator is the line separating the yaml documents.
+const yamlSeparator = "---"
+
+// yamlErrorLine matches the line in the error of the yaml parser, the line
+// is relative to the start of the document.
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// DecodeError is the error of a document in the manifest.
+type DecodeError struct {
+	// Index is the index of the document in the manifest, starts from 1.
+	Index int
+	// Line is the line in the manifest where the document starts, or where
+	// the syntax error is if the parser reports it.
+	Line int
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("document %d (line %d): %v", e.Index, e.Line, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeFile decodes the manifest file, the manifest is read from stdin if
+// the path is "-". The path can also be a directory, whose yaml and json files
+// are decoded recursively, or a kustomization, which is built by Kustomize.
+func DecodeFile(path string) ([]*unstructured.Unstructured, error) {
+	return (&Loader{}).File(path)
+}
+
+// Decode decodes the yaml or json documents into unstructured objects.
+func Decode(data []byte) ([]*unstructured.Unstructured, error) {
+	return DecodeReader(bytes.NewReader(data))
+}
+
+// DecodeReader decodes a stream of yaml documents separated by "---", or a
+// stream of json objects, into unstructured objects. The empty documents are
+// skipped and the items of "kind: List" are flattened. The error of a document
+// is a *DecodeError reporting the index and line of the document.
+func DecodeReader(r io.Reader) ([]*unstructured.Unstructured, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	_, _, isJSON := utilyaml.GuessJSONStream(bytes.NewReader(data), DefaultDecoderBufferSize)
+	next := yamlDocuments(data)
+	if isJSON {
+		next = jsonDocuments(data)
+	}
+
+	var objects []*unstructured.Unstructured
+	for index := 1; ; index++ {
+		raw, line, err := next()
+		if err != nil {
+			if err == io.EOF {
+				return objects, nil
+			}
+			return nil, &DecodeError{Index: index, Line: line, Err: err}
+		}
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		if err := json.Unmarshal(raw, &obj.Object); err != nil {
+			return nil, &DecodeError{Index: index, Line: line, Err: err}
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		items, err := flatten(obj)
+		if err != nil {
+			return nil, &DecodeError{Index: index, Line: line, Err: err}
+		}
+		objects = append(objects, items...)
+	}
+}
+
+// yamlDocuments returns a function reading the next yaml document as json
+// and the line where it starts, the line of a syntax error is converted to
+// the line in the manifest.
+func yamlDocuments(data []byte) func() ([]byte, int, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), DefaultDecoderBufferSize)
+	docs, scanErr := yamlDocumentLines(data)
+	index := 0
+	return func() ([]byte, int, error) {
+		if scanErr != nil {
+			return nil, 1, scanErr
+		}
+		doc := yamlDocument{start: 1, content: 1}
+		if index < len(docs) {
+			doc = docs[index]
+		}
+		index++
+		var raw runtime.RawExtension
+		err := decoder.Decode(&raw)
+		if e, ok := err.(utilyaml.YAMLSyntaxError); ok {
+			if m := yamlErrorLine.FindStringSubmatch(e.Error()); m != nil {
+				n, _ := strconv.Atoi(m[1])
+				return nil, doc.start + n - 1, err
+			}
+			if doc.invalid != 0 {
+				return nil, doc.invalid, err
+			}
+		}
+		return raw.Raw, doc.content, err
+	}
+}
+
+// jsonDocuments returns a function reading the next object of the json stream
+// and the line where it starts.
+func jsonDocuments(data []byte) func() ([]byte, int, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	return func() ([]byte, int, error) {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			line := lineOf(data, int(decoder.InputOffset()))
+			if e, ok := err.(*json.SyntaxError); ok {
+				line = lineOf(data, int(e.Offset))
+			}
+			return nil, line, err
+		}
+		return raw, lineOf(data, int(decoder.InputOffset())-len(raw)), nil
+	}
+}
+
+// flatten returns the items of "kind: List", or the object itself.
+func flatten(obj *unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	if len(obj.GetAPIVersion()) == 0 || len(obj.GetKind()) == 0 {
+		return nil, fmt.Errorf("object %q has no apiVersion or kind", obj.GetName())
+	}
+	if !obj.IsList() {
+		return []*unstructured.Unstructured{obj}, nil
+	}
+	list, err := obj.ToList()
+	if err != nil {
+		return nil, err
+	}
+	var objects []*unstructured.Unstructured
+	for i := range list.Items {
+		items, err := flatten(&list.Items[i])
+		if err != nil {
+			return nil, fmt.Errorf("item %d of %s: %v", i, obj.GetKind(), err)
+		}
+		objects = append(objects, items...)
+	}
+	return objects, nil
+}
+
+// yamlDocument is the position of a yaml document in the manifest.
+type yamlDocument struct {
+	// start is the first line of the document, the lines reported by the
+	// yaml parser are relative to it.
+	start int
+	// content is the first line which is not empty or comment.
+	content int
+	// invalid is the first line starting with "---" followed by something
+	// else than a comment, which utilyaml.YAMLReader rejects.
+	invalid int
+}
+
+// yamlDocumentLines returns the position of every non-empty yaml document,
+// the documents are split the same way as utilyaml.YAMLReader.
+func yamlDocumentLines(data []byte) ([]yamlDocument, error) {
+	var (
+		docs    []yamlDocument
+		doc     yamlDocument
+		scanner = bufio.NewScanner(bytes.NewReader(data))
+	)
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.HasPrefix(text, yamlSeparator) {
+			if rest := strings.TrimSpace(text[len(yamlSeparator):]); len(rest) != 0 && rest[0] != '#' {
+				if doc.start == 0 {
+					doc.start = line
+				}
+				if doc.invalid == 0 {
+					doc.invalid = line
+				}
+				continue
+			}
+			if doc.start != 0 {
+				docs = append(docs, doc)
+			}
+			doc = yamlDocument{}
+			continue
+		}
+		if doc.start == 0 {
+			doc.start = line
+		}
+		trimmed := strings.TrimSpace(text)
+		if doc.content == 0 && len(trimmed) != 0 && !strings.HasPrefix(trimmed, "#") {
+			doc.content = line
+		}
+	}
+	if doc.start != 0 {
+		docs = append(docs, doc)
+	}
+	for i := range docs {
+		if docs[i].content == 0 {
+			docs[i].content = docs[i].start
+		}
+	}
+	return docs, scanner.Err()
+}
+
+// lineOf returns the line of the offset in data.
+func lineOf(data []byte, offset int) int {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
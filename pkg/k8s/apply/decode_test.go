@@ -0,0 +1,179 @@
+package apply
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     []string
+	}{
+		{
+			name: "yaml",
+			manifest: `# leading comment
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: script
+data:
+  run.sh: |
+    # not a comment
+    echo "---"
+    ---not a separator
+  image: nginx@sha256:abc # comment
+---
+---
+# empty document
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: nginx
+`,
+			want: []string{"ConfigMap/script", "Service/nginx"},
+		},
+		{
+			name: "json stream",
+			manifest: `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"a"}}
+{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"b"}}`,
+			want: []string{"ConfigMap/a", "ConfigMap/b"},
+		},
+		{
+			name: "list",
+			manifest: `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: a
+- apiVersion: v1
+  kind: List
+  items:
+  - apiVersion: apps/v1
+    kind: Deployment
+    metadata:
+      name: b
+`,
+			want: []string{"ConfigMap/a", "Deployment/b"},
+		},
+	}
+	for _, test := range tests {
+		objects, err := DecodeReader(strings.NewReader(test.manifest))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		var got []string
+		for _, obj := range objects {
+			got = append(got, obj.GetKind()+"/"+obj.GetName())
+		}
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("%s: objects = %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	objects, _ := Decode([]byte(tests[0].manifest))
+	if script := objects[0].Object["data"].(map[string]interface{})["run.sh"]; script != "# not a comment\necho \"---\"\n---not a separator\n" {
+		t.Errorf("data = %q", script)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		index    int
+		line     int
+	}{
+		{
+			name: "yaml syntax",
+			manifest: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+---
+
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: b
+ data: {}
+`,
+			index: 2,
+			line:  10,
+		},
+		{
+			name: "no kind",
+			manifest: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+---
+# comment
+apiVersion: v1
+metadata:
+  name: b
+`,
+			index: 2,
+			line:  7,
+		},
+		{
+			name: "block scalar",
+			manifest: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+data:
+  script: |
+    ---x
+    ---
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: b
+ data: {}
+`,
+			index: 2,
+			line:  13,
+		},
+		{
+			name: "invalid separator",
+			manifest: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+---x
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: b
+`,
+			index: 1,
+			line:  5,
+		},
+		{
+			name:     "json syntax",
+			manifest: "{\"apiVersion\":\"v1\",\"kind\":\"ConfigMap\",\"metadata\":{\"name\":\"a\"}}\n{\"apiVersion\":\"v1\",\n\"kind\":}",
+			index:    2,
+			line:     3,
+		},
+	}
+	for _, test := range tests {
+		_, err := Decode([]byte(test.manifest))
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) {
+			t.Errorf("%s: error = %v, want a DecodeError", test.name, err)
+			continue
+		}
+		if decodeErr.Index != test.index || decodeErr.Line != test.line {
+			t.Errorf("%s: error = %v, want document %d (line %d)", test.name, err, test.index, test.line)
+		}
+	}
+}
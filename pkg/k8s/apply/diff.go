@@ -0,0 +1,340 @@
+package apply
+
+import (
+	"fmt"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// DiffContext is the number of unchanged lines around the changes in a diff.
+const DiffContext = 3
+
+// Diff returns what applying the object would change, like "kubectl diff".
+// The object is applied with dry run, Result.Diff is the unified diff from the
+// live object to the dry-run result, empty if nothing would change.
+func (a *Applier) Diff(obj *unstructured.Unstructured) Result {
+	dryRun := a.WithDryRun()
+	obj = obj.DeepCopy()
+	if len(obj.GetName()) == 0 {
+		return dryRun.newResult(obj).done(nil, "", fmt.Errorf("can't diff %s without name", obj.GetKind()))
+	}
+	ri, _, err := dryRun.ResourceFor(obj)
+	if err != nil {
+		return dryRun.newResult(obj).done(nil, "", err)
+	}
+	var live map[string]interface{}
+	current, err := ri.Get(a.ctx, obj.GetName(), metav1.GetOptions{})
+	switch {
+	case err == nil:
+		live = current.Object
+	case !k8serrors.IsNotFound(err):
+		return dryRun.newResult(obj).done(nil, "", err)
+	}
+
+	result := dryRun.Apply(obj)
+	if result.Err != nil {
+		return result
+	}
+	if result.Diff, err = DiffObjects(result.Ref(), live, result.Object.Object); err != nil {
+		return result.done(nil, "", err)
+	}
+	return result
+}
+
+// DiffAll returns the diffs of the objects in the order of ApplyAll.
+func (a *Applier) DiffAll(objects []*unstructured.Unstructured) *Report {
+	report := &Report{}
+	for _, obj := range SortForApply(objects) {
+		report.add(a.Diff(obj))
+	}
+	return report
+}
+
+// DiffFile decodes the manifest file and returns the diffs of the objects,
+// the file is read from stdin if path is "-".
+func (a *Applier) DiffFile(path string) (*Report, error) {
+	objects, err := a.Loader().File(path)
+	if err != nil {
+		return nil, err
+	}
+	report := a.DiffAll(objects)
+	return report, report.Err()
+}
+
+// DiffObjects returns the unified diff between the yaml of the live and the
+// merged object, the fields changed on every write (managedFields,
+// resourceVersion, generation) and status are stripped. The live object is
+// nil if it doesn't exist.
+func DiffObjects(name string, live, merged map[string]interface{}) (string, error) {
+	from, err := diffYAML(live)
+	if err != nil {
+		return "", err
+	}
+	to, err := diffYAML(merged)
+	if err != nil {
+		return "", err
+	}
+	return UnifiedDiff("live/"+name, "merged/"+name, from, to, DiffContext), nil
+}
+
+// StripForDiff removes the fields which are noise in a diff.
+func StripForDiff(obj map[string]interface{}) {
+	unstructured.RemoveNestedField(obj, "metadata", "managedFields")
+	unstructured.RemoveNestedField(obj, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(obj, "metadata", "generation")
+	unstructured.RemoveNestedField(obj, "status")
+}
+
+func diffYAML(obj map[string]interface{}) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+	obj = runtime.DeepCopyJSON(obj)
+	StripForDiff(obj)
+	data, err := yaml.Marshal(obj)
+	return string(data), err
+}
+
+// UnifiedDiff returns the unified diff of the lines of from and to, with
+// context lines around the changes. It returns "" if they are the same.
+func UnifiedDiff(fromName, toName, from, to string, context int) string {
+	a, b := splitLines(from), splitLines(to)
+	ops := diffLines(a, b)
+
+	var out strings.Builder
+	for start := 0; start < len(ops); {
+		// find the next change.
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		// the hunk ends when there are more than 2*context unchanged lines.
+		end, same := start, 0
+		for i := start; i < len(ops) && same <= 2*context; i++ {
+			if ops[i].kind == ' ' {
+				same++
+			} else {
+				end, same = i+1, 0
+			}
+		}
+		first, last := start-context, end+context
+		if first < 0 {
+			first = 0
+		}
+		if last > len(ops) {
+			last = len(ops)
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		aStart, bStart, aLen, bLen := ops[first].aLine, ops[first].bLine, 0, 0
+		for _, op := range ops[first:last] {
+			if op.kind != '+' {
+				aLen++
+			}
+			if op.kind != '-' {
+				bLen++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+		for _, op := range ops[first:last] {
+			fmt.Fprintf(&out, "%c%s\n", op.kind, op.line)
+		}
+		start = last
+	}
+	return out.String()
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+	// aLine and bLine are the line numbers (from 1) before the op is done.
+	aLine, bLine int
+}
+
+// diffMaxWork bounds the work of finding the shortest diff of a changed
+// range, about the edit distance times the number of lines. The range is
+// diffed as all lines removed and added when it's exceeded, so the diff is
+// still correct but not the shortest.
+var diffMaxWork = 1 << 26
+
+// diffLines returns the ops turning a into b, computed by the linear space
+// variant of the Myers O(ND) diff algorithm.
+func diffLines(a, b []string) []diffOp {
+	d := &differ{a: a, b: b}
+	d.diff(0, len(a), 0, len(b))
+	return groupChanges(d.ops)
+}
+
+type differ struct {
+	a, b []string
+	ops  []diffOp
+}
+
+// diff appends the ops turning a[a0:a1] into b[b0:b1].
+func (d *differ) diff(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.ops = append(d.ops, diffOp{' ', d.a[a0], a0 + 1, b0 + 1})
+		a0, b0 = a0+1, b0+1
+	}
+	suffix := 0
+	for a0 < a1-suffix && b0 < b1-suffix && d.a[a1-suffix-1] == d.b[b1-suffix-1] {
+		suffix++
+	}
+	a1, b1 = a1-suffix, b1-suffix
+
+	if x, y, ok := d.bisect(a0, a1, b0, b1); ok {
+		d.diff(a0, x, b0, y)
+		d.diff(x, a1, y, b1)
+	} else {
+		for i := a0; i < a1; i++ {
+			d.ops = append(d.ops, diffOp{'-', d.a[i], i + 1, b0 + 1})
+		}
+		for j := b0; j < b1; j++ {
+			d.ops = append(d.ops, diffOp{'+', d.b[j], a1 + 1, j + 1})
+		}
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.ops = append(d.ops, diffOp{' ', d.a[a1+i], a1 + i + 1, b1 + i + 1})
+	}
+}
+
+// bisect finds the middle of the shortest edit path of a[a0:a1] and b[b0:b1]
+// by searching forward from the start and backward from the end at the same
+// time, it returns false if the ranges have nothing in common, one of them is
+// empty, or the search exceeds diffMaxWork.
+func (d *differ) bisect(a0, a1, b0, b1 int) (int, int, bool) {
+	n, m := a1-a0, b1-b0
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	// forward[offset+k] and backward[offset+k] are the furthest x reached on
+	// the diagonal k, the backward diagonals are counted from the end.
+	forward, backward := make([]int, 2*maxD+2), make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+	delta := n - m
+	// the paths meet on the forward search if delta is odd.
+	front := delta%2 != 0
+	// the diagonals running out of the ranges are skipped.
+	var kfStart, kfEnd, kbStart, kbEnd int
+	for step := 0; step < maxD; step++ {
+		if step*(n+m) > diffMaxWork {
+			return 0, 0, false
+		}
+		for k := -step + kfStart; k <= step-kfEnd; k += 2 {
+			var x int
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x, y = x+1, y+1
+			}
+			forward[offset+k] = x
+			switch {
+			case x > n:
+				kfEnd += 2
+			case y > m:
+				kfStart += 2
+			case front:
+				if kb := offset + delta - k; kb >= 0 && kb < len(backward) && backward[kb] != -1 && x >= n-backward[kb] {
+					return a0 + x, b0 + y, true
+				}
+			}
+		}
+		for k := -step + kbStart; k <= step-kbEnd; k += 2 {
+			var x int
+			if k == -step || (k != step && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[a1-x-1] == d.b[b1-y-1] {
+				x, y = x+1, y+1
+			}
+			backward[offset+k] = x
+			switch {
+			case x > n:
+				kbEnd += 2
+			case y > m:
+				kbStart += 2
+			case !front:
+				if kf := offset + delta - k; kf >= 0 && kf < len(forward) && forward[kf] != -1 {
+					fx := forward[kf]
+					if fx >= n-x {
+						return a0 + fx, b0 + fx - (kf - offset), true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// groupChanges moves the removed lines before the added lines in every run
+// of changes, like the output of diff and git.
+func groupChanges(ops []diffOp) []diffOp {
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		end := start
+		var removed, added []diffOp
+		for ; end < len(ops) && ops[end].kind != ' '; end++ {
+			if ops[end].kind == '-' {
+				removed = append(removed, ops[end])
+			} else {
+				added = append(added, ops[end])
+			}
+		}
+		aLine, bLine := ops[start].aLine, ops[start].bLine
+		i := start
+		for _, op := range removed {
+			ops[i] = diffOp{'-', op.line, aLine, bLine}
+			i, aLine = i+1, aLine+1
+		}
+		for _, op := range added {
+			ops[i] = diffOp{'+', op.line, aLine, bLine}
+			i, bLine = i+1, bLine+1
+		}
+		start = end
+	}
+	return ops
+}
+
+// hunkRange formats the range of a hunk, the start of an empty range is the
+// line before it.
+func hunkRange(start, length int) string {
+	if length == 0 {
+		start--
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, length)
+}
+
+func splitLines(s string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,135 @@
+package apply
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(from, to int) string {
+		var s []string
+		for i := from; i <= to; i++ {
+			s = append(s, string(rune('a'+i-1)))
+		}
+		return strings.Join(s, "\n") + "\n"
+	}
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+	}{
+		{
+			name: "same",
+			from: lines(1, 5),
+			to:   lines(1, 5),
+		},
+		{
+			name: "created",
+			to:   "a\nb\n",
+			want: "--- live\n+++ merged\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "deleted",
+			from: "a\n",
+			want: "--- live\n+++ merged\n@@ -1 +0,0 @@\n-a\n",
+		},
+		{
+			name: "changed",
+			from: lines(1, 10),
+			to:   strings.Replace(lines(1, 10), "e\n", "E\n", 1),
+			want: "--- live\n+++ merged\n@@ -2,7 +2,7 @@\n b\n c\n d\n-e\n+E\n f\n g\n h\n",
+		},
+		{
+			name: "two hunks",
+			from: lines(1, 20),
+			to:   "A\n" + strings.TrimPrefix(lines(1, 20), "a\n") + "u\n",
+			want: "--- live\n+++ merged\n@@ -1,4 +1,4 @@\n-a\n+A\n b\n c\n d\n@@ -18,3 +18,4 @@\n r\n s\n t\n+u\n",
+		},
+	}
+	for _, test := range tests {
+		if got := UnifiedDiff("live", "merged", test.from, test.to, DiffContext); got != test.want {
+			t.Errorf("%s: diff =\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDiffObjects(t *testing.T) {
+	live := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":            "nginx",
+			"resourceVersion": "1",
+			"managedFields":   []interface{}{map[string]interface{}{"manager": "kubectl"}},
+		},
+		"data": map[string]interface{}{"a": "1"},
+	}
+	merged := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":            "nginx",
+			"resourceVersion": "2",
+			"managedFields":   []interface{}{map[string]interface{}{"manager": "client-go"}},
+		},
+		"data":   map[string]interface{}{"a": "2"},
+		"status": map[string]interface{}{"phase": "Active"},
+	}
+	diff, err := DiffObjects("configmap/nginx", live, merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `--- live/configmap/nginx
++++ merged/configmap/nginx
+@@ -1,6 +1,6 @@
+ apiVersion: v1
+ data:
+-  a: "1"
++  a: "2"
+ kind: ConfigMap
+ metadata:
+   name: nginx
+`
+	if diff != want {
+		t.Errorf("diff =\n%s\nwant\n%s", diff, want)
+	}
+	if _, ok := live["metadata"].(map[string]interface{})["resourceVersion"]; !ok {
+		t.Error("the live object is modified")
+	}
+}
+
+func TestUnifiedDiffLarge(t *testing.T) {
+	var from, to strings.Builder
+	for i := 0; i < 200000; i++ {
+		fmt.Fprintf(&from, "line %d\n", i)
+		if i%50000 == 0 {
+			fmt.Fprintf(&to, "changed %d\n", i)
+		} else {
+			fmt.Fprintf(&to, "line %d\n", i)
+		}
+	}
+	diff := UnifiedDiff("live", "merged", from.String(), to.String(), DiffContext)
+	if n := strings.Count(diff, "\n-line "); n != 4 {
+		t.Errorf("%d lines removed, want 4:\n%s", n, diff)
+	}
+	if n := strings.Count(diff, "\n+changed "); n != 4 {
+		t.Errorf("%d lines added, want 4:\n%s", n, diff)
+	}
+
+	// nothing in common, the lines are all removed and added when the work
+	// exceeds diffMaxWork.
+	maxWork := diffMaxWork
+	defer func() { diffMaxWork = maxWork }()
+	diffMaxWork = 1000
+	from.Reset()
+	to.Reset()
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&from, "a%d\n", i)
+		fmt.Fprintf(&to, "b%d\n", i)
+	}
+	diff = UnifiedDiff("live", "merged", from.String(), to.String(), DiffContext)
+	if !strings.HasPrefix(diff, "--- live\n+++ merged\n@@ -1,5000 +1,5000 @@\n-a0\n") || strings.Count(diff, "\n+b") != 5000 {
+		t.Errorf("diff = %.100s...", diff)
+	}
+}
@@ -0,0 +1,742 @@
+package apply
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
+)
+
+// KustomizationFileNames is the file names of a kustomization in a directory.
+var KustomizationFileNames = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// Kustomization is the subset of the kustomization.yaml of kustomize which is
+// built in memory by Kustomize. Generators, components and remote resources
+// are not supported, a kustomization with unknown fields is an error.
+type Kustomization struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+
+	// Resources is the manifest files, directories or kustomization
+	// directories, relative to the kustomization.
+	Resources []string `json:"resources,omitempty"`
+	// Bases is the deprecated alias of Resources.
+	Bases []string `json:"bases,omitempty"`
+
+	Namespace         string            `json:"namespace,omitempty"`
+	NamePrefix        string            `json:"namePrefix,omitempty"`
+	NameSuffix        string            `json:"nameSuffix,omitempty"`
+	CommonLabels      map[string]string `json:"commonLabels,omitempty"`
+	CommonAnnotations map[string]string `json:"commonAnnotations,omitempty"`
+	Images            []Image           `json:"images,omitempty"`
+
+	// PatchesStrategicMerge is the strategic merge patch files, or inline
+	// patches. The patched object is found by the kind and name of a patch.
+	PatchesStrategicMerge []string        `json:"patchesStrategicMerge,omitempty"`
+	PatchesJson6902       []PatchJson6902 `json:"patchesJson6902,omitempty"`
+	// Patches is strategic merge or json6902 patches, a json6902 patch
+	// requires the target.
+	Patches []Patch `json:"patches,omitempty"`
+}
+
+// Image overrides the name, tag or digest of the container images named Name.
+type Image struct {
+	Name    string `json:"name"`
+	NewName string `json:"newName,omitempty"`
+	NewTag  string `json:"newTag,omitempty"`
+	Digest  string `json:"digest,omitempty"`
+}
+
+// PatchTarget selects the objects to patch, the empty fields match any object.
+type PatchTarget struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// PatchJson6902 is a json patch (RFC 6902) of the target, in the file of Path
+// or inline in Patch, in yaml or json.
+type PatchJson6902 struct {
+	Target *PatchTarget `json:"target"`
+	Path   string       `json:"path,omitempty"`
+	Patch  string       `json:"patch,omitempty"`
+}
+
+// Patch is a strategic merge patch or a json patch, in the file of Path or
+// inline in Patch.
+type Patch struct {
+	Target *PatchTarget `json:"target,omitempty"`
+	Path   string       `json:"path,omitempty"`
+	Patch  string       `json:"patch,omitempty"`
+}
+
+// clusterScopedKinds is the built-in kinds whose namespace is not overridden,
+// it's used if the scope of a kind can't be resolved by Loader.RESTMapper.
+var clusterScopedKinds = map[string]bool{
+	"Namespace":                      true,
+	"Node":                           true,
+	"PersistentVolume":               true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"StorageClass":                   true,
+	"CSIDriver":                      true,
+	"IngressClass":                   true,
+	"PriorityClass":                  true,
+	"RuntimeClass":                   true,
+	"PodSecurityPolicy":              true,
+	"APIService":                     true,
+	"MutatingWebhookConfiguration":   true,
+	"ValidatingWebhookConfiguration": true,
+	KindCustomResourceDefinition:     true,
+}
+
+// podTemplatePaths is where the pod template of the workloads is.
+var podTemplatePaths = map[string][]string{
+	"Deployment":            {"spec", "template"},
+	"ReplicaSet":            {"spec", "template"},
+	"DaemonSet":             {"spec", "template"},
+	"StatefulSet":           {"spec", "template"},
+	"Job":                   {"spec", "template"},
+	"ReplicationController": {"spec", "template"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template"},
+}
+
+// selectorPaths is where the label selector of the workloads and services is.
+var selectorPaths = map[string][]string{
+	"Deployment":            {"spec", "selector", "matchLabels"},
+	"ReplicaSet":            {"spec", "selector", "matchLabels"},
+	"DaemonSet":             {"spec", "selector", "matchLabels"},
+	"StatefulSet":           {"spec", "selector", "matchLabels"},
+	"ReplicationController": {"spec", "selector"},
+	"Service":               {"spec", "selector"},
+}
+
+// nameReferences is the fields referring to other objects by name, they are
+// updated when the referred object is renamed by the name prefix or suffix.
+// The fields of the pod spec are relative to the pod spec, the "[]" suffix
+// iterates over a list.
+var nameReferences = []struct {
+	kind   string // kind of the referring object, "" is the pod spec of any kind
+	kindOf string // kind of the referred object, or the field of the kind
+	path   string
+}{
+	{kindOf: "ConfigMap", path: "volumes[].configMap.name"},
+	{kindOf: "ConfigMap", path: "volumes[].projected.sources[].configMap.name"},
+	{kindOf: "ConfigMap", path: "containers[].envFrom[].configMapRef.name"},
+	{kindOf: "ConfigMap", path: "containers[].env[].valueFrom.configMapKeyRef.name"},
+	{kindOf: "ConfigMap", path: "initContainers[].envFrom[].configMapRef.name"},
+	{kindOf: "ConfigMap", path: "initContainers[].env[].valueFrom.configMapKeyRef.name"},
+	{kindOf: "Secret", path: "volumes[].secret.secretName"},
+	{kindOf: "Secret", path: "volumes[].projected.sources[].secret.name"},
+	{kindOf: "Secret", path: "containers[].envFrom[].secretRef.name"},
+	{kindOf: "Secret", path: "containers[].env[].valueFrom.secretKeyRef.name"},
+	{kindOf: "Secret", path: "initContainers[].envFrom[].secretRef.name"},
+	{kindOf: "Secret", path: "initContainers[].env[].valueFrom.secretKeyRef.name"},
+	{kindOf: "Secret", path: "imagePullSecrets[].name"},
+	{kindOf: "PersistentVolumeClaim", path: "volumes[].persistentVolumeClaim.claimName"},
+	{kindOf: "ServiceAccount", path: "serviceAccountName"},
+	{kind: "StatefulSet", kindOf: "Service", path: "spec.serviceName"},
+	{kind: "Ingress", kindOf: "Service", path: "spec.defaultBackend.service.name"},
+	{kind: "Ingress", kindOf: "Service", path: "spec.rules[].http.paths[].backend.service.name"},
+	{kind: "HorizontalPodAutoscaler", kindOf: "spec.scaleTargetRef.kind", path: "spec.scaleTargetRef.name"},
+	{kind: "RoleBinding", kindOf: "roleRef.kind", path: "roleRef.name"},
+	{kind: "ClusterRoleBinding", kindOf: "roleRef.kind", path: "roleRef.name"},
+}
+
+// IsKustomizationFile reports whether the file name is a kustomization.
+func IsKustomizationFile(path string) bool {
+	for _, name := range KustomizationFileNames {
+		if filepath.Base(path) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// findKustomization returns the kustomization file in the directory, "" if
+// the directory has no kustomization.
+func findKustomization(dir string) string {
+	for _, name := range KustomizationFileNames {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && !info.IsDir() {
+			return filepath.Join(dir, name)
+		}
+	}
+	return ""
+}
+
+// Kustomize builds the kustomization in memory, path is a kustomization file
+// or a directory with a kustomization file. The objects of the resources are
+// patched, then namespace, name prefix and suffix, common labels and
+// annotations and images are overridden, like "kubectl kustomize".
+func Kustomize(path string) ([]*unstructured.Unstructured, error) {
+	return (&Loader{}).Kustomize(path)
+}
+
+// Kustomize builds the kustomization like the package level Kustomize, the
+// resources and patches are rendered by the Loader.
+func (l *Loader) Kustomize(path string) ([]*unstructured.Unstructured, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		if file := findKustomization(path); len(file) != 0 {
+			path = file
+		} else {
+			return nil, fmt.Errorf("no kustomization in directory %q", path)
+		}
+	}
+	resources, err := (&kustomizer{loader: l, visiting: make(map[string]bool)}).build(path)
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]*unstructured.Unstructured, 0, len(resources))
+	for _, r := range resources {
+		objects = append(objects, r.obj)
+	}
+	return objects, nil
+}
+
+// decodeDir decodes the yaml and json files in the directory recursively,
+// in lexical order of the paths.
+func (l *Loader) decodeDir(dir string) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		if IsKustomizationFile(path) {
+			return nil
+		}
+		decoded, err := l.decodeManifestFile(path)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, decoded...)
+		return nil
+	})
+	return objects, err
+}
+
+func (l *Loader) decodeManifestFile(path string) ([]*unstructured.Unstructured, error) {
+	data, err := l.readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return objects, nil
+}
+
+// resource is an object being built, names is the names it has had, so the
+// patches of an overlay can refer to the name before the prefix of the base.
+type resource struct {
+	obj   *unstructured.Unstructured
+	names []string
+}
+
+func (r *resource) hasName(name string) bool {
+	for _, n := range r.names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+type kustomizer struct {
+	loader *Loader
+	// visiting is the kustomizations being built, to detect cycles.
+	visiting map[string]bool
+}
+
+func (k *kustomizer) build(path string) ([]*resource, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if k.visiting[abs] {
+		return nil, fmt.Errorf("kustomization %q is included recursively", path)
+	}
+	k.visiting[abs] = true
+	defer delete(k.visiting, abs)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	kustomization := &Kustomization{}
+	if err := yaml.UnmarshalStrict(data, kustomization); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	resources, err := k.buildKustomization(filepath.Dir(path), kustomization)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return resources, nil
+}
+
+func (k *kustomizer) buildKustomization(dir string, kustomization *Kustomization) ([]*resource, error) {
+	var resources []*resource
+	for _, name := range append(append([]string(nil), kustomization.Bases...), kustomization.Resources...) {
+		loaded, err := k.load(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, loaded...)
+	}
+
+	for _, name := range kustomization.PatchesStrategicMerge {
+		patch, err := k.readStrategicMergePatch(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		if err := patchStrategicMerge(resources, nil, patch); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range kustomization.PatchesJson6902 {
+		if p.Target == nil {
+			return nil, fmt.Errorf("json6902 patch %q has no target", p.Path)
+		}
+		patch, err := k.readPatch(dir, p.Path, p.Patch)
+		if err != nil {
+			return nil, err
+		}
+		if err := patchJson6902(resources, p.Target, patch); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range kustomization.Patches {
+		patch, err := k.readPatch(dir, p.Path, p.Patch)
+		if err != nil {
+			return nil, err
+		}
+		// a json patch is a list of operations.
+		if strings.HasPrefix(strings.TrimSpace(string(patch)), "[") {
+			if p.Target == nil {
+				return nil, fmt.Errorf("json6902 patch %q has no target", p.Path)
+			}
+			err = patchJson6902(resources, p.Target, patch)
+		} else {
+			err = patchStrategicMerge(resources, p.Target, patch)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	renamed := make(map[string]string)
+	for _, r := range resources {
+		obj := r.obj
+		if len(kustomization.Namespace) != 0 && !k.clusterScoped(obj) {
+			obj.SetNamespace(kustomization.Namespace)
+		}
+		if kind := obj.GetKind(); kind != "Namespace" && kind != KindCustomResourceDefinition {
+			if name := kustomization.NamePrefix + obj.GetName() + kustomization.NameSuffix; name != obj.GetName() {
+				renamed[kind+"/"+obj.GetName()] = name
+				obj.SetName(name)
+				r.names = append(r.names, name)
+			}
+		}
+	}
+	for _, r := range resources {
+		if err := updateReferences(r.obj, renamed); err != nil {
+			return nil, err
+		}
+		if err := setCommonLabels(r.obj, kustomization.CommonLabels); err != nil {
+			return nil, err
+		}
+		if err := setCommonAnnotations(r.obj, kustomization.CommonAnnotations); err != nil {
+			return nil, err
+		}
+		setImages(r.obj, kustomization.Images)
+	}
+	return resources, nil
+}
+
+// load loads the resource of the kustomization, a manifest file, a directory
+// of manifests or a kustomization directory.
+func (k *kustomizer) load(dir, name string) ([]*resource, error) {
+	if strings.Contains(name, "://") || strings.HasPrefix(name, "git@") || strings.HasPrefix(name, "github.com/") {
+		return nil, fmt.Errorf("remote resource %q is not supported", name)
+	}
+	path := filepath.Join(dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []*unstructured.Unstructured
+	switch {
+	case info.IsDir() && len(findKustomization(path)) != 0:
+		return k.build(findKustomization(path))
+	case info.IsDir():
+		objects, err = k.loader.decodeDir(path)
+	case IsKustomizationFile(path):
+		return k.build(path)
+	default:
+		objects, err = k.loader.decodeManifestFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resources := make([]*resource, 0, len(objects))
+	for _, obj := range objects {
+		resources = append(resources, &resource{obj: obj, names: []string{obj.GetName()}})
+	}
+	return resources, nil
+}
+
+// readPatch returns the patch in the file, or the inline patch.
+func (k *kustomizer) readPatch(dir, path, inline string) ([]byte, error) {
+	switch {
+	case len(path) != 0:
+		return k.loader.readFile(filepath.Join(dir, path))
+	case len(inline) != 0:
+		return []byte(inline), nil
+	default:
+		return nil, fmt.Errorf("patch has no path or content")
+	}
+}
+
+// readStrategicMergePatch returns the patch in the file, or the inline patch
+// if there is no such file, eg: a patch in json or a multiline yaml.
+func (k *kustomizer) readStrategicMergePatch(dir, name string) ([]byte, error) {
+	path := filepath.Join(dir, name)
+	info, err := os.Stat(path)
+	if err == nil && !info.IsDir() {
+		return k.loader.readFile(path)
+	}
+	if objects, decodeErr := Decode([]byte(name)); decodeErr == nil && len(objects) != 0 {
+		return []byte(name), nil
+	}
+	if err == nil {
+		err = fmt.Errorf("%q is a directory", path)
+	}
+	return nil, fmt.Errorf("strategic merge patch is not a file or an inline patch: %w", err)
+}
+
+// clusterScoped reports whether the object is cluster scoped, the scope is
+// resolved by the RESTMapper of the loader, so the cluster scoped custom
+// resources are known too.
+func (k *kustomizer) clusterScoped(obj *unstructured.Unstructured) bool {
+	if k.loader.RESTMapper != nil {
+		gvk := obj.GroupVersionKind()
+		if mapping, err := k.loader.RESTMapper.RESTMapping(gvk.GroupKind(), gvk.Version); err == nil {
+			return mapping.Scope.Name() == meta.RESTScopeNameRoot
+		}
+	}
+	return clusterScopedKinds[obj.GetKind()]
+}
+
+func (t *PatchTarget) match(r *resource) bool {
+	gvk := r.obj.GroupVersionKind()
+	return (len(t.Group) == 0 || t.Group == gvk.Group) &&
+		(len(t.Version) == 0 || t.Version == gvk.Version) &&
+		(len(t.Kind) == 0 || t.Kind == gvk.Kind) &&
+		(len(t.Name) == 0 || r.hasName(t.Name)) &&
+		(len(t.Namespace) == 0 || t.Namespace == r.obj.GetNamespace())
+}
+
+// patchStrategicMerge patches the objects with the strategic merge patches,
+// the target is the kind and name of every patch if target is nil. The
+// custom resources are patched with json merge patch.
+func patchStrategicMerge(resources []*resource, target *PatchTarget, data []byte) error {
+	patches, err := Decode(data)
+	if err != nil {
+		return err
+	}
+	for _, patch := range patches {
+		t := target
+		if t == nil {
+			t = &PatchTarget{Kind: patch.GetKind(), Name: patch.GetName(), Namespace: patch.GetNamespace()}
+		}
+		// keep the name and namespace set by the kustomization.
+		patch = patch.DeepCopy()
+		unstructured.RemoveNestedField(patch.Object, "metadata", "name")
+		unstructured.RemoveNestedField(patch.Object, "metadata", "namespace")
+
+		matched := false
+		for _, r := range resources {
+			if !t.match(r) {
+				continue
+			}
+			matched = true
+			if dataStruct := dataStructFor(r.obj); dataStruct != nil {
+				patched, err := strategicpatch.StrategicMergeMapPatch(r.obj.Object, patch.Object, dataStruct)
+				if err != nil {
+					return fmt.Errorf("patch %s %s: %w", r.obj.GetKind(), r.obj.GetName(), err)
+				}
+				r.obj.Object = patched
+				continue
+			}
+			patchJson, err := patch.MarshalJSON()
+			if err != nil {
+				return err
+			}
+			if err := patchJSON(r.obj, func(doc []byte) ([]byte, error) { return jsonpatch.MergePatch(doc, patchJson) }); err != nil {
+				return err
+			}
+		}
+		if !matched {
+			return fmt.Errorf("no object matches the patch of %s %s", t.Kind, t.Name)
+		}
+	}
+	return nil
+}
+
+// patchJson6902 patches the target objects with the json patch.
+func patchJson6902(resources []*resource, target *PatchTarget, data []byte) error {
+	patchJson, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return err
+	}
+	patch, err := jsonpatch.DecodePatch(patchJson)
+	if err != nil {
+		return err
+	}
+	matched := false
+	for _, r := range resources {
+		if !target.match(r) {
+			continue
+		}
+		matched = true
+		if err := patchJSON(r.obj, patch.Apply); err != nil {
+			return err
+		}
+	}
+	if !matched {
+		return fmt.Errorf("no object matches the json6902 patch of %s %s", target.Kind, target.Name)
+	}
+	return nil
+}
+
+// patchJSON patches the json of the object with the patch function.
+func patchJSON(obj *unstructured.Unstructured, patch func(doc []byte) ([]byte, error)) error {
+	doc, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	patched, err := patch(doc)
+	if err != nil {
+		return fmt.Errorf("patch %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	return obj.UnmarshalJSON(patched)
+}
+
+// updateReferences updates the references to the renamed objects, renamed is
+// keyed by kind/name.
+func updateReferences(obj *unstructured.Unstructured, renamed map[string]string) error {
+	if len(renamed) == 0 {
+		return nil
+	}
+	for _, ref := range nameReferences {
+		var root interface{} = obj.Object
+		if len(ref.kind) == 0 {
+			spec, ok := podSpec(obj)
+			if !ok {
+				continue
+			}
+			root = spec
+		} else if ref.kind != obj.GetKind() {
+			continue
+		}
+		kind := ref.kindOf
+		if strings.Contains(kind, ".") {
+			kind, _, _ = unstructured.NestedString(obj.Object, strings.Split(kind, ".")...)
+		}
+		updateField(root, strings.Split(ref.path, "."), func(value interface{}) interface{} {
+			if name, ok := value.(string); ok {
+				if newName, ok := renamed[kind+"/"+name]; ok {
+					return newName
+				}
+			}
+			return value
+		})
+	}
+	// the service accounts in the subjects of the role bindings.
+	subjects, _, _ := unstructured.NestedSlice(obj.Object, "subjects")
+	for _, s := range subjects {
+		if subject, ok := s.(map[string]interface{}); ok && subject["kind"] == "ServiceAccount" {
+			if newName, ok := renamed[fmt.Sprintf("ServiceAccount/%v", subject["name"])]; ok {
+				subject["name"] = newName
+			}
+		}
+	}
+	if len(subjects) != 0 {
+		return unstructured.SetNestedSlice(obj.Object, subjects, "subjects")
+	}
+	return nil
+}
+
+// updateField replaces the value of the field at the path with the return of
+// fn, the "[]" suffix of a path element iterates over a list.
+func updateField(obj interface{}, path []string, fn func(value interface{}) interface{}) {
+	m, ok := obj.(map[string]interface{})
+	if !ok || len(path) == 0 {
+		return
+	}
+	field := strings.TrimSuffix(path[0], "[]")
+	value, ok := m[field]
+	if !ok {
+		return
+	}
+	switch {
+	case field != path[0]:
+		items, _ := value.([]interface{})
+		for _, item := range items {
+			updateField(item, path[1:], fn)
+		}
+	case len(path) == 1:
+		m[field] = fn(value)
+	default:
+		updateField(value, path[1:], fn)
+	}
+}
+
+// podSpec returns the pod spec of a pod or the pod template of a workload.
+func podSpec(obj *unstructured.Unstructured) (map[string]interface{}, bool) {
+	path := []string{"spec"}
+	if template, ok := podTemplatePaths[obj.GetKind()]; ok {
+		path = append(append([]string(nil), template...), "spec")
+	} else if obj.GetKind() != "Pod" {
+		return nil, false
+	}
+	// not NestedMap, which returns a copy.
+	spec := obj.Object
+	for _, field := range path {
+		var ok bool
+		if spec, ok = spec[field].(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	return spec, true
+}
+
+// setCommonLabels adds the labels to the object, the selector and the pod
+// template of the workloads and services.
+func setCommonLabels(obj *unstructured.Unstructured, labels map[string]string) error {
+	if len(labels) == 0 {
+		return nil
+	}
+	obj.SetLabels(mergeStringMap(obj.GetLabels(), labels))
+	if path, ok := selectorPaths[obj.GetKind()]; ok {
+		if err := addNestedStringMap(obj, labels, path...); err != nil {
+			return err
+		}
+	}
+	if path, ok := podTemplatePaths[obj.GetKind()]; ok {
+		return addNestedStringMap(obj, labels, append(append([]string(nil), path...), "metadata", "labels")...)
+	}
+	return nil
+}
+
+// setCommonAnnotations adds the annotations to the object and the pod
+// template of the workloads.
+func setCommonAnnotations(obj *unstructured.Unstructured, annotations map[string]string) error {
+	if len(annotations) == 0 {
+		return nil
+	}
+	obj.SetAnnotations(mergeStringMap(obj.GetAnnotations(), annotations))
+	if path, ok := podTemplatePaths[obj.GetKind()]; ok {
+		return addNestedStringMap(obj, annotations, append(append([]string(nil), path...), "metadata", "annotations")...)
+	}
+	return nil
+}
+
+func addNestedStringMap(obj *unstructured.Unstructured, values map[string]string, fields ...string) error {
+	current, _, err := unstructured.NestedStringMap(obj.Object, fields...)
+	if err != nil {
+		return err
+	}
+	return unstructured.SetNestedStringMap(obj.Object, mergeStringMap(current, values), fields...)
+}
+
+func mergeStringMap(dst, src map[string]string) map[string]string {
+	if dst == nil {
+		dst = make(map[string]string, len(src))
+	}
+	keys := make([]string, 0, len(src))
+	for key := range src {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		dst[key] = src[key]
+	}
+	return dst
+}
+
+// setImages overrides the images of the containers and init containers.
+func setImages(obj *unstructured.Unstructured, images []Image) {
+	if len(images) == 0 {
+		return
+	}
+	spec, ok := podSpec(obj)
+	if !ok {
+		return
+	}
+	for _, path := range []string{"containers[].image", "initContainers[].image"} {
+		updateField(spec, strings.Split(path, "."), func(value interface{}) interface{} {
+			image, ok := value.(string)
+			if !ok {
+				return value
+			}
+			for _, override := range images {
+				if newImage, ok := override.apply(image); ok {
+					return newImage
+				}
+			}
+			return image
+		})
+	}
+}
+
+// apply returns the overridden image if the name of the image is i.Name.
+func (i Image) apply(image string) (string, bool) {
+	name, tag := splitImage(image)
+	if name != i.Name {
+		return image, false
+	}
+	if len(i.NewName) != 0 {
+		name = i.NewName
+	}
+	switch {
+	case len(i.Digest) != 0:
+		tag = "@" + i.Digest
+	case len(i.NewTag) != 0:
+		tag = ":" + i.NewTag
+	}
+	return name + tag, true
+}
+
+// splitImage splits the image into the name and the tag or digest with the
+// separator, eg: "nginx:1.21" to "nginx" and ":1.21".
+func splitImage(image string) (name, tag string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		return image[:i], image[i:]
+	}
+	// the colon before the last slash is the port of the registry.
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i:]
+	}
+	return image, ""
+}
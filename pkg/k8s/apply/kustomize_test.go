@@ -0,0 +1,319 @@
+package apply
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const testBaseDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: nginx
+  template:
+    metadata:
+      labels:
+        app: nginx
+    spec:
+      serviceAccountName: nginx
+      containers:
+      - name: nginx
+        image: nginx:1.21
+        envFrom:
+        - configMapRef:
+            name: nginx
+      - name: sidecar
+        image: registry.local:5000/busybox
+`
+
+const testBaseOthers = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: nginx
+data:
+  key: value
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: nginx
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: nginx
+spec:
+  selector:
+    app: nginx
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+`
+
+func TestKustomize(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"base/deployment.yaml": testBaseDeployment,
+		"base/others.yaml":     testBaseOthers,
+		"base/kustomization.yaml": `resources:
+- deployment.yaml
+- others.yaml
+commonLabels:
+  team: web
+`,
+		"overlays/prod/kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- ../../base
+namespace: prod
+namePrefix: prod-
+commonAnnotations:
+  owner: ops
+images:
+- name: nginx
+  newTag: "1.22"
+- name: registry.local:5000/busybox
+  newName: busybox
+  digest: sha256:abc
+patchesStrategicMerge:
+- replicas.yaml
+patchesJson6902:
+- target:
+    group: apps
+    version: v1
+    kind: Deployment
+    name: nginx
+  patch: |
+    - op: add
+      path: /spec/template/spec/containers/0/args
+      value: ["-g", "daemon off;"]
+patches:
+- patch: |
+    apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: nginx
+    data:
+      env: prod
+`,
+		"overlays/prod/replicas.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: nginx
+        resources:
+          limits:
+            cpu: "1"
+`,
+	})
+
+	// the directory and the kustomization file build the same objects.
+	for _, path := range []string{filepath.Join(dir, "overlays/prod"), filepath.Join(dir, "overlays/prod/kustomization.yaml")} {
+		objects, err := DecodeFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(objects) != 5 {
+			t.Fatalf("%d objects, want 5", len(objects))
+		}
+		byKind := make(map[string]*unstructured.Unstructured)
+		for _, obj := range objects {
+			byKind[obj.GetKind()] = obj
+		}
+
+		deploy := byKind["Deployment"]
+		if deploy.GetName() != "prod-nginx" || deploy.GetNamespace() != "prod" {
+			t.Errorf("deployment %s/%s, want prod/prod-nginx", deploy.GetNamespace(), deploy.GetName())
+		}
+		if deploy.GetLabels()["team"] != "web" || deploy.GetAnnotations()["owner"] != "ops" {
+			t.Errorf("labels = %v, annotations = %v", deploy.GetLabels(), deploy.GetAnnotations())
+		}
+		selector, _, _ := unstructured.NestedStringMap(deploy.Object, "spec", "selector", "matchLabels")
+		templateLabels, _, _ := unstructured.NestedStringMap(deploy.Object, "spec", "template", "metadata", "labels")
+		if selector["team"] != "web" || templateLabels["team"] != "web" {
+			t.Errorf("selector = %v, template labels = %v", selector, templateLabels)
+		}
+		if replicas, _, _ := unstructured.NestedInt64(deploy.Object, "spec", "replicas"); replicas != 3 {
+			t.Errorf("replicas = %d, want 3", replicas)
+		}
+		spec, _ := podSpec(deploy)
+		if sa := spec["serviceAccountName"]; sa != "prod-nginx" {
+			t.Errorf("serviceAccountName = %v, want prod-nginx", sa)
+		}
+		containers := spec["containers"].([]interface{})
+		nginx, sidecar := containers[0].(map[string]interface{}), containers[1].(map[string]interface{})
+		if nginx["image"] != "nginx:1.22" || sidecar["image"] != "busybox@sha256:abc" {
+			t.Errorf("images = %v, %v", nginx["image"], sidecar["image"])
+		}
+		if nginx["resources"] == nil || len(nginx["args"].([]interface{})) != 2 {
+			t.Errorf("nginx container not patched: %v", nginx)
+		}
+		if envFrom := nginx["envFrom"].([]interface{})[0].(map[string]interface{}); envFrom["configMapRef"].(map[string]interface{})["name"] != "prod-nginx" {
+			t.Errorf("envFrom = %v, want the prefixed configmap", envFrom)
+		}
+
+		cm := byKind["ConfigMap"]
+		if data, _, _ := unstructured.NestedStringMap(cm.Object, "data"); data["key"] != "value" || data["env"] != "prod" {
+			t.Errorf("configmap data = %v", data)
+		}
+		svc := byKind["Service"]
+		if selector, _, _ := unstructured.NestedStringMap(svc.Object, "spec", "selector"); selector["team"] != "web" {
+			t.Errorf("service selector = %v", selector)
+		}
+		role := byKind["ClusterRole"]
+		if role.GetName() != "prod-reader" || len(role.GetNamespace()) != 0 {
+			t.Errorf("clusterrole %q/%q, want prod-reader without namespace", role.GetNamespace(), role.GetName())
+		}
+	}
+}
+
+func TestDecodeDir(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"b/deployment.yaml": testBaseDeployment,
+		"a.yaml":            testBaseOthers,
+		"README.md":         "not a manifest",
+	})
+	objects, err := DecodeFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, obj := range objects {
+		got = append(got, obj.GetKind())
+	}
+	if want := "ConfigMap,ServiceAccount,Service,ClusterRole,Deployment"; strings.Join(got, ",") != want {
+		t.Errorf("objects = %v, want %s", got, want)
+	}
+}
+
+func TestKustomizeError(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "remote",
+			files: map[string]string{
+				"kustomization.yaml": "resources:\n- https://github.com/example/manifests\n",
+			},
+			want: "remote resource",
+		},
+		{
+			name: "unknown field",
+			files: map[string]string{
+				"kustomization.yaml": "configMapGenerator:\n- name: a\n",
+			},
+			want: "unknown field",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"kustomization.yaml":   "resources:\n- a\n",
+				"a/kustomization.yaml": "resources:\n- ..\n",
+			},
+			want: "recursively",
+		},
+		{
+			name: "no target",
+			files: map[string]string{
+				"kustomization.yaml": "resources:\n- cm.yaml\npatchesStrategicMerge:\n- |\n  apiVersion: v1\n  kind: ConfigMap\n  metadata:\n    name: other\n",
+				"cm.yaml":            "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n",
+			},
+			want: "no object matches",
+		},
+	}
+	for _, test := range tests {
+		_, err := Kustomize(writeTestFiles(t, test.files))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: error = %v, want %q", test.name, err, test.want)
+		}
+	}
+}
+
+func TestKustomizeScope(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"kustomization.yaml": `resources:
+- objects.yaml
+namespace: prod
+patchesStrategicMerge:
+- '{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"nginx"},"data":{"env":"prod"}}'
+`,
+		"objects.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: nginx
+---
+apiVersion: example.com/v1
+kind: Cluster
+metadata:
+  name: a
+---
+apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: b
+`,
+	})
+	gv := schema.GroupVersion{Group: "example.com", Version: "v1"}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gv})
+	mapper.Add(gv.WithKind("Cluster"), meta.RESTScopeRoot)
+	mapper.Add(gv.WithKind("Foo"), meta.RESTScopeNamespace)
+
+	objects, err := (&Loader{RESTMapper: mapper}).Kustomize(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	namespaces := make(map[string]string)
+	for _, obj := range objects {
+		namespaces[obj.GetKind()] = obj.GetNamespace()
+	}
+	// the ConfigMap isn't known by the mapper, the built-in scope is used.
+	if namespaces["ConfigMap"] != "prod" || namespaces["Cluster"] != "" || namespaces["Foo"] != "prod" {
+		t.Errorf("namespaces = %v", namespaces)
+	}
+	if data, _, _ := unstructured.NestedStringMap(objects[0].Object, "data"); data["env"] != "prod" {
+		t.Errorf("configmap data = %v, want the inline patch applied", data)
+	}
+
+	// a patch which is neither a file nor a manifest.
+	dir = writeTestFiles(t, map[string]string{
+		"kustomization.yaml": "resources:\n- objects.yaml\npatchesStrategicMerge:\n- notexist.yaml\n",
+		"objects.yaml":       "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: nginx\n",
+	})
+	if _, err := Kustomize(dir); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("error = %v, want not exist", err)
+	}
+}
@@ -0,0 +1,81 @@
+package apply
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// LastAppliedConfigAnnotation is the annotation used by client-side apply to
+// store the manifest of the last apply.
+const LastAppliedConfigAnnotation = corev1.LastAppliedConfigAnnotation
+
+// GetModifiedConfiguration returns the json of the manifest with the
+// last-applied-configuration annotation set to the manifest itself,
+// same as "kubectl apply" without --server-side.
+func GetModifiedConfiguration(objJson []byte) ([]byte, error) {
+	obj := &unstructured.Unstructured{}
+	if err := json.Unmarshal(objJson, &obj.Object); err != nil {
+		return nil, err
+	}
+
+	// the last-applied-configuration never contains itself.
+	annotations := obj.GetAnnotations()
+	delete(annotations, LastAppliedConfigAnnotation)
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	obj.SetAnnotations(annotations)
+	original, err := json.Marshal(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[LastAppliedConfigAnnotation] = string(original)
+	obj.SetAnnotations(annotations)
+	return json.Marshal(obj.Object)
+}
+
+// GetOriginalConfiguration returns the last-applied-configuration of the
+// live object, nil if the object was never applied by client-side apply.
+func GetOriginalConfiguration(obj metav1.Object) []byte {
+	original, ok := obj.GetAnnotations()[LastAppliedConfigAnnotation]
+	if !ok {
+		return nil
+	}
+	return []byte(original)
+}
+
+// CreateThreeWayPatch computes the patch from the last applied (original),
+// the desired (modified) and the live (current) object. Fields removed from
+// the manifest since the last apply are removed, fields set by others are kept.
+//
+// dataStruct is the typed object of a built-in kind (eg: new(appsv1.Deployment)),
+// its patch strategies are used to create a strategic merge patch. if dataStruct
+// is nil (CRDs and other unstructured objects), a json merge patch is created.
+func CreateThreeWayPatch(original, modified, current []byte, dataStruct interface{}) ([]byte, types.PatchType, error) {
+	if dataStruct == nil {
+		patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch(original, modified, current)
+		return patch, types.MergePatchType, err
+	}
+
+	lookupPatchMeta, err := strategicpatch.NewPatchMetaFromStruct(dataStruct)
+	if err != nil {
+		return nil, "", err
+	}
+	patch, err := strategicpatch.CreateThreeWayMergePatch(original, modified, current, lookupPatchMeta, true)
+	return patch, types.StrategicMergePatchType, err
+}
+
+// IsEmptyPatch reports whether the patch changes nothing.
+func IsEmptyPatch(patch []byte) bool {
+	return len(patch) == 0 || string(patch) == "{}"
+}
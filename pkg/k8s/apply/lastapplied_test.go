@@ -0,0 +1,57 @@
+package apply
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestGetModifiedConfiguration(t *testing.T) {
+	manifest := []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"nginx","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"stale"}}}`)
+	modified, err := GetModifiedConfiguration(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(modified); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"nginx"}}`
+	if got := string(GetOriginalConfiguration(obj)); got != want {
+		t.Errorf("last-applied-configuration = %s, want %s", got, want)
+	}
+}
+
+func TestCreateThreeWayPatch(t *testing.T) {
+	original := []byte(`{"metadata":{"name":"foo","labels":{"a":"1","b":"2"}},"spec":{"size":1}}`)
+	modified := []byte(`{"metadata":{"name":"foo","labels":{"a":"1"}},"spec":{"size":2}}`)
+	current := []byte(`{"metadata":{"name":"foo","labels":{"a":"1","b":"2","c":"3"}},"spec":{"size":1}}`)
+
+	// unstructured objects, eg: custom resources
+	patch, patchType, err := CreateThreeWayPatch(original, modified, current, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"metadata":{"labels":{"b":null}},"spec":{"size":2}}`
+	if patchType != types.MergePatchType || string(patch) != want {
+		t.Errorf("patch = %s %s, want %s %s", patchType, patch, types.MergePatchType, want)
+	}
+
+	// built-in kinds
+	original = []byte(`{"spec":{"template":{"spec":{"containers":[{"name":"nginx","image":"nginx"},{"name":"sidecar","image":"busybox"}]}}}}`)
+	modified = []byte(`{"spec":{"template":{"spec":{"containers":[{"name":"nginx","image":"nginx"}]}}}}`)
+	patch, patchType, err = CreateThreeWayPatch(original, modified, original, new(appsv1.Deployment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{"spec":{"template":{"spec":{"$setElementOrder/containers":[{"name":"nginx"}],"containers":[{"$patch":"delete","name":"sidecar"}]}}}}`
+	if patchType != types.StrategicMergePatchType || string(patch) != want {
+		t.Errorf("patch = %s %s, want %s %s", patchType, patch, types.StrategicMergePatchType, want)
+	}
+
+	if patch, _, _ := CreateThreeWayPatch(modified, modified, modified, nil); !IsEmptyPatch(patch) {
+		t.Errorf("patch = %s, want empty patch", patch)
+	}
+}
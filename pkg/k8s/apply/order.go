@@ -0,0 +1,129 @@
+package apply
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+)
+
+// KindCustomResourceDefinition is the kind of CRD.
+const KindCustomResourceDefinition = "CustomResourceDefinition"
+
+// DefaultWaitTimeout is how long to wait for a CRD to be established.
+const DefaultWaitTimeout = time.Minute
+
+// InstallOrder is the order of kinds to apply, same as helm except that the
+// CRDs are applied just after the namespaces. The kinds not in the list, eg:
+// custom resources, are applied at last. The objects are deleted in reverse order.
+var InstallOrder = []string{
+	"Namespace",
+	KindCustomResourceDefinition,
+	"NetworkPolicy",
+	"ResourceQuota",
+	"LimitRange",
+	"PodSecurityPolicy",
+	"PodDisruptionBudget",
+	"ServiceAccount",
+	"Secret",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicationController",
+	"ReplicaSet",
+	"Deployment",
+	"HorizontalPodAutoscaler",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"IngressClass",
+	"Ingress",
+	"APIService",
+	"MutatingWebhookConfiguration",
+	"ValidatingWebhookConfiguration",
+}
+
+var installOrder = func() map[string]int {
+	order := make(map[string]int, len(InstallOrder))
+	for i, kind := range InstallOrder {
+		order[kind] = i
+	}
+	return order
+}()
+
+func kindOrder(obj *unstructured.Unstructured) int {
+	if order, ok := installOrder[obj.GetKind()]; ok {
+		return order
+	}
+	return len(InstallOrder)
+}
+
+// SortForApply returns a copy of the objects sorted by InstallOrder, the
+// objects of the same kind keep the order in the manifest.
+func SortForApply(objects []*unstructured.Unstructured) []*unstructured.Unstructured {
+	sorted := append([]*unstructured.Unstructured(nil), objects...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return kindOrder(sorted[i]) < kindOrder(sorted[j])
+	})
+	return sorted
+}
+
+// SortForDelete returns a copy of the objects sorted in the reverse order
+// of SortForApply.
+func SortForDelete(objects []*unstructured.Unstructured) []*unstructured.Unstructured {
+	sorted := SortForApply(objects)
+	for i, j := 0, len(sorted)-1; i < j; i, j = i+1, j-1 {
+		sorted[i], sorted[j] = sorted[j], sorted[i]
+	}
+	return sorted
+}
+
+// isCRD reports whether the object is a CustomResourceDefinition.
+func isCRD(obj *unstructured.Unstructured) bool {
+	return obj.GetKind() == KindCustomResourceDefinition &&
+		obj.GroupVersionKind().Group == "apiextensions.k8s.io"
+}
+
+// crdEstablished reports whether the Established condition of the CRD is True.
+func crdEstablished(crd *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(crd.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condition["type"] == "Established" && condition["status"] == string(metav1.ConditionTrue) {
+			return true
+		}
+	}
+	return false
+}
+
+// waitEstablished waits the CRD to be established, so the api server serves
+// its custom resources.
+func (a *Applier) waitEstablished(ri dynamic.ResourceInterface, name string) error {
+	err := wait.PollImmediateWithContext(a.ctx, time.Second, a.waitTimeout, func(ctx context.Context) (bool, error) {
+		crd, err := ri.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return crdEstablished(crd), nil
+	})
+	if err != nil {
+		return fmt.Errorf("wait for customresourcedefinition %q to be established: %w", name, err)
+	}
+	return nil
+}
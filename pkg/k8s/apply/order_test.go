@@ -0,0 +1,92 @@
+package apply
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSortForApply(t *testing.T) {
+	objects, err := Decode([]byte(`
+apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: foo
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: a
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: reader
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: b
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: reader
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: foos.example.com
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: test
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := func(objects []*unstructured.Unstructured) string {
+		var names []string
+		for _, obj := range objects {
+			names = append(names, obj.GetKind()+"/"+obj.GetName())
+		}
+		return strings.Join(names, ",")
+	}
+
+	want := "Namespace/test,CustomResourceDefinition/foos.example.com,ServiceAccount/reader,RoleBinding/reader,Deployment/a,Deployment/b,Foo/foo"
+	if got := names(SortForApply(objects)); got != want {
+		t.Errorf("apply order = %s, want %s", got, want)
+	}
+	want = "Foo/foo,Deployment/b,Deployment/a,RoleBinding/reader,ServiceAccount/reader,CustomResourceDefinition/foos.example.com,Namespace/test"
+	if got := names(SortForDelete(objects)); got != want {
+		t.Errorf("delete order = %s, want %s", got, want)
+	}
+	if objects[0].GetKind() != "Foo" {
+		t.Error("the objects are sorted in place")
+	}
+}
+
+func TestCRDEstablished(t *testing.T) {
+	crd := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+	}}
+	if !isCRD(crd) || crdEstablished(crd) {
+		t.Errorf("crd without status")
+	}
+	unstructured.SetNestedSlice(crd.Object, []interface{}{
+		map[string]interface{}{"type": "NamesAccepted", "status": "True"},
+		map[string]interface{}{"type": "Established", "status": "False"},
+	}, "status", "conditions")
+	if crdEstablished(crd) {
+		t.Errorf("crd not established")
+	}
+	unstructured.SetNestedSlice(crd.Object, []interface{}{
+		map[string]interface{}{"type": "Established", "status": "True"},
+	}, "status", "conditions")
+	if !crdEstablished(crd) {
+		t.Errorf("crd established")
+	}
+}
@@ -0,0 +1,179 @@
+package apply
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// LabelApplySet is the label of the objects applied by an Applier with an
+// apply set, the value is the name of the apply set.
+const LabelApplySet = "client-go/apply-set"
+
+// DefaultPruneKinds is the kinds pruned besides the kinds in the manifest,
+// same as the default allowlist of "kubectl apply --prune".
+var DefaultPruneKinds = []schema.GroupKind{
+	{Kind: "ConfigMap"},
+	{Kind: "Endpoints"},
+	{Kind: "Namespace"},
+	{Kind: "PersistentVolumeClaim"},
+	{Kind: "PersistentVolume"},
+	{Kind: "Pod"},
+	{Kind: "ReplicationController"},
+	{Kind: "Secret"},
+	{Kind: "Service"},
+	{Group: "batch", Kind: "Job"},
+	{Group: "batch", Kind: "CronJob"},
+	{Group: "networking.k8s.io", Kind: "Ingress"},
+	{Group: "apps", Kind: "DaemonSet"},
+	{Group: "apps", Kind: "Deployment"},
+	{Group: "apps", Kind: "ReplicaSet"},
+	{Group: "apps", Kind: "StatefulSet"},
+}
+
+// SetApplySet set the name of the apply set, the applied objects are labeled
+// with LabelApplySet=name, so the objects removed from the manifest can be
+// found and pruned by a later apply.
+func (a *Applier) SetApplySet(name string) {
+	a.applySet = name
+}
+
+// SetPrune enables ApplyAll to prune the objects of the apply set which are
+// not in the manifest any more. The prune is skipped if any object failed
+// to apply.
+func (a *Applier) SetPrune(prune bool) {
+	a.prune = prune
+}
+
+// SetPruneNamespaces set the namespaces to prune, default to the namespaces
+// of the objects in the manifest.
+func (a *Applier) SetPruneNamespaces(namespaces ...string) {
+	a.pruneNamespaces = namespaces
+}
+
+// SetPruneKinds set the kinds to prune, default to DefaultPruneKinds and the
+// kinds in the manifest.
+func (a *Applier) SetPruneKinds(kinds ...schema.GroupKind) {
+	a.pruneKinds = kinds
+}
+
+// setApplySetLabel labels the object with the apply set.
+func (a *Applier) setApplySetLabel(obj *unstructured.Unstructured) {
+	if len(a.applySet) == 0 {
+		return
+	}
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[LabelApplySet] = a.applySet
+	obj.SetLabels(labels)
+}
+
+// PruneList returns the live objects of the apply set which are not in the
+// manifest, within the prune namespaces and kinds. It's the dry-run listing
+// of Prune.
+func (a *Applier) PruneList(objects []*unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	if len(a.applySet) == 0 {
+		return nil, errors.New("prune requires an apply set")
+	}
+
+	var (
+		keep       = sets.NewString()
+		namespaces = sets.NewString(a.pruneNamespaces...)
+		kinds      = a.pruneKinds
+		seen       = make(map[schema.GroupKind]bool)
+	)
+	if len(kinds) == 0 {
+		kinds = append(kinds, DefaultPruneKinds...)
+	}
+	for _, kind := range kinds {
+		seen[kind] = true
+	}
+	for _, obj := range objects {
+		obj = obj.DeepCopy()
+		// resolve the default namespace of the namespaced objects.
+		_, mapping, err := a.ResourceFor(obj)
+		if err != nil {
+			continue
+		}
+		keep.Insert(pruneKey(obj))
+		if len(a.pruneNamespaces) == 0 && mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			namespaces.Insert(obj.GetNamespace())
+		}
+		if gk := obj.GroupVersionKind().GroupKind(); len(a.pruneKinds) == 0 && !seen[gk] {
+			kinds, seen[gk] = append(kinds, gk), true
+		}
+	}
+	if namespaces.Len() == 0 {
+		namespaces.Insert(a.namespace)
+	}
+
+	var pruned []*unstructured.Unstructured
+	listOptions := metav1.ListOptions{LabelSelector: LabelApplySet + "=" + a.applySet}
+	for _, kind := range kinds {
+		mapping, err := a.restMapper.RESTMapping(kind)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, err
+		}
+		var lists []*unstructured.UnstructuredList
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			for _, namespace := range namespaces.List() {
+				list, err := a.dynamicClient.Resource(mapping.Resource).Namespace(namespace).List(a.ctx, listOptions)
+				if err != nil {
+					return nil, fmt.Errorf("list %s in namespace %q: %w", mapping.Resource.Resource, namespace, err)
+				}
+				lists = append(lists, list)
+			}
+		} else {
+			list, err := a.dynamicClient.Resource(mapping.Resource).List(a.ctx, listOptions)
+			if err != nil {
+				return nil, fmt.Errorf("list %s: %w", mapping.Resource.Resource, err)
+			}
+			lists = append(lists, list)
+		}
+		for _, list := range lists {
+			for i := range list.Items {
+				obj := &list.Items[i]
+				// the list of a custom resource may not have apiVersion and kind.
+				obj.SetGroupVersionKind(mapping.GroupVersionKind)
+				if keep.Has(pruneKey(obj)) || obj.GetDeletionTimestamp() != nil {
+					continue
+				}
+				pruned = append(pruned, obj)
+			}
+		}
+	}
+	return pruned, nil
+}
+
+// Prune deletes the objects returned by PruneList, in the reverse order of
+// ApplyAll. The objects are not deleted by an Applier with dry run.
+func (a *Applier) Prune(objects []*unstructured.Unstructured) (*Report, error) {
+	pruned, err := a.PruneList(objects)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{}
+	for _, obj := range SortForDelete(pruned) {
+		result := a.Delete(obj)
+		if result.Action == ActionDeleted {
+			result.Action = ActionPruned
+		}
+		report.add(result)
+	}
+	return report, nil
+}
+
+func pruneKey(obj *unstructured.Unstructured) string {
+	gk := obj.GroupVersionKind().GroupKind()
+	return gk.String() + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
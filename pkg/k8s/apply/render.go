@@ -0,0 +1,155 @@
+package apply
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// RenderFunc renders a manifest before it's decoded, name is the file name of
+// the manifest, or "-" if it is read from stdin or bytes.
+type RenderFunc func(name string, data []byte) ([]byte, error)
+
+// envVariable matches ${VAR}, and the escaped $${VAR}.
+var envVariable = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Template returns a RenderFunc which executes the manifest as a go
+// text/template with the values, eg: "image: {{ .image }}". A key missing
+// from the values is an error.
+func Template(values map[string]interface{}) RenderFunc {
+	return func(name string, data []byte) ([]byte, error) {
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(string(data))
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, values); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+// Env returns a RenderFunc which substitutes ${VAR} with the value returned
+// by lookup, default to os.LookupEnv. A variable not found is an error, "$${VAR}"
+// is kept as "${VAR}", and $VAR without braces is not substituted, so the
+// shell scripts in the manifests are kept as is.
+func Env(lookup func(key string) (string, bool)) RenderFunc {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	return func(name string, data []byte) ([]byte, error) {
+		missing := make(map[string]bool)
+		rendered := envVariable.ReplaceAllFunc(data, func(match []byte) []byte {
+			if bytes.HasPrefix(match, []byte("$$")) {
+				return match[1:]
+			}
+			key := string(match[2 : len(match)-1])
+			value, ok := lookup(key)
+			if !ok {
+				missing[key] = true
+			}
+			return []byte(value)
+		})
+		if len(missing) != 0 {
+			keys := make([]string, 0, len(missing))
+			for key := range missing {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			return nil, fmt.Errorf("%s: variables not set: %s", name, strings.Join(keys, ", "))
+		}
+		return rendered, nil
+	}
+}
+
+// Loader decodes the manifests like DecodeFile, every manifest file is
+// rendered by Render before it's decoded, including the resources and patches
+// of a kustomization. The kustomization files are not rendered.
+type Loader struct {
+	// Render renders the manifests, nil means the manifests are decoded as is.
+	Render RenderFunc
+	// Output is where the rendered manifests are dumped to, like "helm template",
+	// nil means they are not dumped.
+	Output io.Writer
+	// RESTMapper resolves the scope of the kinds of a kustomization, so the
+	// namespace of the cluster scoped objects is not overridden. The built-in
+	// kinds are known without it.
+	RESTMapper meta.RESTMapper
+}
+
+// File decodes the manifest file, the directory or the kustomization, the
+// manifest is read from stdin if the path is "-".
+func (l *Loader) File(path string) ([]*unstructured.Unstructured, error) {
+	if path == "-" {
+		return l.Reader(os.Stdin)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case IsKustomizationFile(path), info.IsDir() && len(findKustomization(path)) != 0:
+		return l.Kustomize(path)
+	case info.IsDir():
+		return l.decodeDir(path)
+	}
+	data, err := l.readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// Reader decodes the manifest read from r, eg: os.Stdin.
+func (l *Loader) Reader(r io.Reader) ([]*unstructured.Unstructured, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = l.render("-", data); err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// Bytes renders the manifest, name is the name of the manifest in the errors
+// and the dump.
+func (l *Loader) Bytes(name string, data []byte) ([]byte, error) {
+	return l.render(name, data)
+}
+
+// readFile reads and renders the manifest file.
+func (l *Loader) readFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return l.render(path, data)
+}
+
+func (l *Loader) render(name string, data []byte) ([]byte, error) {
+	if l.Render == nil {
+		return data, nil
+	}
+	rendered, err := l.Render(name, data)
+	if err != nil {
+		return nil, fmt.Errorf("render %s: %w", name, err)
+	}
+	if l.Output != nil {
+		fmt.Fprintf(l.Output, "---\n# Source: %s\n%s", name, rendered)
+		if !bytes.HasSuffix(rendered, []byte("\n")) {
+			fmt.Fprintln(l.Output)
+		}
+	}
+	return rendered, nil
+}
@@ -0,0 +1,97 @@
+package apply
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testTemplate = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .name }}
+spec:
+  replicas: {{ .replicas }}
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:{{ .image.tag }}
+`
+
+func TestTemplate(t *testing.T) {
+	render := Template(map[string]interface{}{
+		"name":     "nginx",
+		"replicas": 3,
+		"image":    map[string]interface{}{"tag": "1.22"},
+	})
+	data, err := render("deployment.yaml", []byte(testTemplate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if objects[0].GetName() != "nginx" || !strings.Contains(string(data), "replicas: 3") || !strings.Contains(string(data), "image: nginx:1.22") {
+		t.Errorf("rendered =\n%s", data)
+	}
+
+	_, err = Template(map[string]interface{}{"name": "nginx", "replicas": 1})("deployment.yaml", []byte(testTemplate))
+	if err == nil || !strings.Contains(err.Error(), "deployment.yaml") || !strings.Contains(err.Error(), "image") {
+		t.Errorf("error = %v, want the missing key image", err)
+	}
+}
+
+func TestEnv(t *testing.T) {
+	env := map[string]string{"NAME": "nginx", "TAG": "1.22"}
+	lookup := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+	manifest := `name: ${NAME}
+image: nginx:${TAG}
+script: echo $HOME $${NAME}
+`
+	data, err := Env(lookup)("-", []byte(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "name: nginx\nimage: nginx:1.22\nscript: echo $HOME ${NAME}\n"; string(data) != want {
+		t.Errorf("rendered = %q, want %q", data, want)
+	}
+
+	_, err = Env(lookup)("-", []byte("${B} ${A} ${NAME} ${A}"))
+	if err == nil || !strings.HasSuffix(err.Error(), "variables not set: A, B") {
+		t.Errorf("error = %v, want the variables A and B not set", err)
+	}
+}
+
+func TestLoader(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"deployment.yaml":    testTemplate,
+		"kustomization.yaml": "resources:\n- deployment.yaml\nnamePrefix: dev-\n",
+	})
+	var output bytes.Buffer
+	loader := &Loader{
+		Render: Template(map[string]interface{}{"name": "nginx", "replicas": 1, "image": map[string]string{"tag": "1.22"}}),
+		Output: &output,
+	}
+	objects, err := loader.File(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].GetName() != "dev-nginx" {
+		t.Fatalf("objects = %v", objects)
+	}
+	source := "---\n# Source: " + filepath.Join(dir, "deployment.yaml") + "\n"
+	if !strings.HasPrefix(output.String(), source) || !strings.Contains(output.String(), "name: nginx\n") {
+		t.Errorf("output =\n%s", output.String())
+	}
+
+	loader.Render = Template(nil)
+	if _, err := loader.File(filepath.Join(dir, "deployment.yaml")); err == nil || !strings.HasPrefix(err.Error(), "render ") {
+		t.Errorf("error = %v, want a render error", err)
+	}
+}
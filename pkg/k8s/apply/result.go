@@ -0,0 +1,144 @@
+package apply
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// Action is what has been done to an object.
+type Action string
+
+const (
+	ActionCreated    Action = "created"
+	ActionConfigured Action = "configured"
+	ActionUnchanged  Action = "unchanged"
+	ActionDeleted    Action = "deleted"
+	// ActionPruned means the object of the apply set is deleted because
+	// it's not in the manifest any more.
+	ActionPruned Action = "pruned"
+	// ActionSkipped means nothing has been done, eg: delete an object which
+	// doesn't exist.
+	ActionSkipped Action = "skipped"
+	// ActionFailed means the request failed, Result.Err is the error.
+	ActionFailed Action = "failed"
+)
+
+// Result is the result of applying or deleting an object.
+type Result struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+	Action           Action
+	// Object is the object returned by the api server, nil if the object
+	// is deleted or the request failed.
+	Object *unstructured.Unstructured
+	Err    error
+	// DryRun means the request is sent with dryRun=All, nothing is persisted.
+	DryRun bool
+	// Diff is the unified diff returned by Applier.Diff, empty if nothing
+	// would change.
+	Diff string
+}
+
+func (a *Applier) newResult(obj *unstructured.Unstructured) Result {
+	return Result{
+		GroupVersionKind: obj.GroupVersionKind(),
+		Namespace:        obj.GetNamespace(),
+		Name:             obj.GetName(),
+		DryRun:           a.dryRun,
+	}
+}
+
+// done sets the action, or the error if the request failed.
+func (r Result) done(obj *unstructured.Unstructured, action Action, err error) Result {
+	if err != nil {
+		r.Action, r.Err = ActionFailed, err
+		return r
+	}
+	r.Action, r.Object = action, obj
+	if obj != nil {
+		r.Namespace = obj.GetNamespace()
+	}
+	return r
+}
+
+// Ref returns the object reference like kubectl prints, eg: deployment.apps/nginx.
+func (r Result) Ref() string {
+	kind := strings.ToLower(r.GroupVersionKind.Kind)
+	if len(r.GroupVersionKind.Group) != 0 {
+		kind += "." + r.GroupVersionKind.Group
+	}
+	return kind + "/" + r.Name
+}
+
+// String returns the result like kubectl prints, eg: deployment.apps/nginx created.
+func (r Result) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("%s %s: %v", r.Ref(), r.Action, r.Err)
+	}
+	if r.DryRun {
+		return fmt.Sprintf("%s %s (dry run)", r.Ref(), r.Action)
+	}
+	return fmt.Sprintf("%s %s", r.Ref(), r.Action)
+}
+
+// Report is the results of the objects in a manifest, in the order they are
+// applied or deleted.
+type Report struct {
+	Results []Result
+	// Errors is the errors which don't belong to an object, eg: failed to list
+	// the objects to prune.
+	Errors []error
+}
+
+func (r *Report) add(result Result) {
+	r.Results = append(r.Results, result)
+}
+
+// Err returns the aggregated errors of the failed objects, nil if no
+// object failed.
+func (r *Report) Err() error {
+	errs := append([]error(nil), r.Errors...)
+	for _, result := range r.Results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Ref(), result.Err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// Count returns how many objects the action has been done to.
+func (r *Report) Count(action Action) int {
+	count := 0
+	for _, result := range r.Results {
+		if result.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Diff returns the diffs of all the objects, like "kubectl diff" prints.
+func (r *Report) Diff() string {
+	var diff strings.Builder
+	for _, result := range r.Results {
+		diff.WriteString(result.Diff)
+	}
+	return diff.String()
+}
+
+// String returns a kubectl-style summary, one object per line.
+func (r *Report) String() string {
+	var lines []string
+	for _, result := range r.Results {
+		lines = append(lines, result.String())
+	}
+	for _, err := range r.Errors {
+		lines = append(lines, "error: "+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,297 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// IndexByNode indexes the pods by spec.nodeName.
+	IndexByNode = "node"
+	// IndexByOwner indexes the objects by the uid of their owners, eg: the
+	// pods of a replicaset, the replicasets of a deployment.
+	IndexByOwner = "owner"
+)
+
+// cacheInformers returns the shared informer of the resource kind, the
+// informer is registered in the informer factory when it's called.
+var cacheInformers = map[string]func(f informers.SharedInformerFactory) cache.SharedIndexInformer{
+	ResourceKindPod: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Core().V1().Pods().Informer()
+	},
+	ResourceKindDeployment: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Apps().V1().Deployments().Informer()
+	},
+	ResourceKindDaemonSet: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Apps().V1().DaemonSets().Informer()
+	},
+	ResourceKindStatefulSet: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Apps().V1().StatefulSets().Informer()
+	},
+	ResourceKindJob: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Batch().V1().Jobs().Informer()
+	},
+	ResourceKindCronJob: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Batch().V1().CronJobs().Informer()
+	},
+	ResourceKindReplicaSet: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Apps().V1().ReplicaSets().Informer()
+	},
+	ResourceKindReplicationController: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Core().V1().ReplicationControllers().Informer()
+	},
+	ResourceKindClusterRoleBinding: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Rbac().V1().ClusterRoleBindings().Informer()
+	},
+	ResourceKindClusterRole: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Rbac().V1().ClusterRoles().Informer()
+	},
+	ResourceKindConfigMap: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Core().V1().ConfigMaps().Informer()
+	},
+	ResourceKindIngress: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Networking().V1().Ingresses().Informer()
+	},
+	ResourceKindIngressClass: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Networking().V1().IngressClasses().Informer()
+	},
+	ResourceKindNamespace: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Core().V1().Namespaces().Informer()
+	},
+	ResourceKindNetworkPolicy: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Networking().V1().NetworkPolicies().Informer()
+	},
+	ResourceKindNode: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Core().V1().Nodes().Informer()
+	},
+	ResourceKindPersistentVolumeClaim: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Core().V1().PersistentVolumeClaims().Informer()
+	},
+	ResourceKindPersistentVolume: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Core().V1().PersistentVolumes().Informer()
+	},
+	ResourceKindRoleBinding: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Rbac().V1().RoleBindings().Informer()
+	},
+	ResourceKindRole: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Rbac().V1().Roles().Informer()
+	},
+	ResourceKindSecret: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Core().V1().Secrets().Informer()
+	},
+	ResourceKindServiceAccount: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Core().V1().ServiceAccounts().Informer()
+	},
+	ResourceKindService: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Core().V1().Services().Informer()
+	},
+	ResourceKindStorageClass: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Storage().V1().StorageClasses().Informer()
+	},
+}
+
+// StartCache starts the informers of the resource kinds, eg: "pod",
+// "deployment", and waits until their caches are synced or ctx is done.
+// The informers run until the context of the factory is done, the handlers
+// of the kinds then read from the cache by Lister, and the methods such as
+// Node.GetPods and Deployment.GetPods don't hit the api server anymore. eg:
+//
+//	if err := factory.StartCache(ctx, k8s.ResourceKindPod, k8s.ResourceKindDeployment); err != nil {
+//		return err
+//	}
+//	pods, err := factory.Pods("").WithNamespace(metav1.NamespaceAll).Lister().ByIndex(k8s.IndexByNode, "node1")
+//
+// The pods are indexed by IndexByNode and IndexByOwner, the other kinds by
+// IndexByOwner. The custom indexers must be added by Handler.AddIndexers
+// before StartCache.
+func (f *Factory) StartCache(ctx context.Context, kinds ...string) error {
+	var synced []cache.InformerSynced
+	for _, kind := range kinds {
+		kind = strings.ToLower(kind)
+		newInformer, ok := cacheInformers[kind]
+		if !ok {
+			return fmt.Errorf("cache of %s is not supported", kind)
+		}
+		informer := newInformer(f.informerFactory)
+		indexers := cache.Indexers{IndexByOwner: indexByOwner}
+		if kind == ResourceKindPod {
+			indexers[IndexByNode] = indexByNode
+		}
+		addIndexers(informer, indexers)
+		f.cacheMu.Lock()
+		if f.caches == nil {
+			f.caches = make(map[string]cache.SharedIndexInformer)
+		}
+		f.caches[kind] = informer
+		f.cacheMu.Unlock()
+		synced = append(synced, informer.HasSynced)
+	}
+	f.informerFactory.Start(f.ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return fmt.Errorf("wait for the cache of %s to sync: %w", strings.Join(kinds, ", "), ctx.Err())
+	}
+	return nil
+}
+
+// cachedInformer returns the informer of the kind if it's started by
+// StartCache and synced, otherwise nil.
+func (f *Factory) cachedInformer(kind string) cache.SharedIndexInformer {
+	f.cacheMu.Lock()
+	informer := f.caches[kind]
+	f.cacheMu.Unlock()
+	if informer == nil || !informer.HasSynced() {
+		return nil
+	}
+	return informer
+}
+
+// addIndexers adds the indexers which are not added yet. The indexers can't
+// be added after the informer started, eg: by RunInformer, it's logged only
+// and the index is not available.
+func addIndexers(informer cache.SharedIndexInformer, indexers cache.Indexers) {
+	existing := informer.GetIndexer().GetIndexers()
+	missing := cache.Indexers{}
+	for name, indexFunc := range indexers {
+		if _, ok := existing[name]; !ok {
+			missing[name] = indexFunc
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	if err := informer.AddIndexers(missing); err != nil {
+		log.Warnf("add indexers: %v", err)
+	}
+}
+
+// indexByNode is the index func of IndexByNode.
+func indexByNode(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || len(pod.Spec.NodeName) == 0 {
+		return nil, nil
+	}
+	return []string{pod.Spec.NodeName}, nil
+}
+
+// indexByOwner is the index func of IndexByOwner.
+func indexByOwner(obj interface{}) ([]string, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	var uids []string
+	for _, owner := range accessor.GetOwnerReferences() {
+		uids = append(uids, string(owner.UID))
+	}
+	return uids, nil
+}
+
+// Lister reads the objects of a kind from the informer cache. The objects
+// returned are shared with the cache, they must not be modified, DeepCopy
+// them first.
+type Lister[T any] struct {
+	indexer   cache.Indexer
+	kind      string
+	namespace string
+}
+
+// Get returns the object of the name in the namespace of the lister, it
+// returns a NotFound error if it's not in the cache.
+func (l *Lister[T]) Get(name string) (*T, error) {
+	key := name
+	if len(l.namespace) != 0 {
+		key = l.namespace + "/" + name
+	}
+	item, exists, err := l.indexer.GetByKey(key)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := item.(*T)
+	if !exists || !ok {
+		return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: l.kind}, name)
+	}
+	return obj, nil
+}
+
+// List returns the objects matching the label selector, eg: "app=nginx",
+// all objects if it's empty.
+func (l *Lister[T]) List(label string) ([]*T, error) {
+	selector, err := labels.Parse(label)
+	if err != nil {
+		return nil, err
+	}
+	var objects []*T
+	appendFunc := func(item interface{}) {
+		if obj, ok := item.(*T); ok {
+			objects = append(objects, obj)
+		}
+	}
+	if len(l.namespace) == 0 {
+		err = cache.ListAll(l.indexer, selector, appendFunc)
+	} else {
+		err = cache.ListAllByNamespace(l.indexer, l.namespace, selector, appendFunc)
+	}
+	return objects, err
+}
+
+// ByIndex returns the objects whose index value is value, eg:
+//
+//	pods, err := factory.Pods("").WithNamespace(metav1.NamespaceAll).Lister().ByIndex(k8s.IndexByNode, "node1")
+func (l *Lister[T]) ByIndex(indexName, value string) ([]*T, error) {
+	items, err := l.indexer.ByIndex(indexName, value)
+	if err != nil {
+		return nil, err
+	}
+	var objects []*T
+	for _, item := range items {
+		obj, ok := item.(*T)
+		if !ok {
+			continue
+		}
+		if len(l.namespace) != 0 {
+			if accessor, err := meta.Accessor(obj); err != nil || accessor.GetNamespace() != l.namespace {
+				continue
+			}
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// Lister returns the lister of the handler namespace, all namespaces if the
+// namespace is empty, eg: factory.Pods("").WithNamespace(metav1.NamespaceAll).
+// The cache must be started by Factory.StartCache.
+func (h *Handler[T, TList]) Lister() *Lister[T] {
+	return &Lister[T]{indexer: h.informer().GetIndexer(), kind: h.kind, namespace: h.namespace}
+}
+
+// AddIndexers adds the custom indexers to the informer of the kind, it must
+// be called before Factory.StartCache, eg:
+//
+//	p.AddIndexers(cache.Indexers{"ip": func(obj interface{}) ([]string, error) {
+//		return []string{obj.(*corev1.Pod).Status.PodIP}, nil
+//	}})
+func (h *Handler[T, TList]) AddIndexers(indexers cache.Indexers) error {
+	return h.informer().AddIndexers(indexers)
+}
+
+// cachedLister returns the lister if the cache of the kind is started by
+// Factory.StartCache and synced, otherwise nil, the caller should read from
+// the api server instead.
+func (h *Handler[T, TList]) cachedLister() *Lister[T] {
+	informer := h.factory.cachedInformer(h.kind)
+	if informer == nil {
+		return nil
+	}
+	return &Lister[T]{indexer: informer.GetIndexer(), kind: h.kind, namespace: h.namespace}
+}
@@ -0,0 +1,125 @@
+package k8s
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func podNames(pods []*corev1.Pod) []string {
+	var names []string
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestStartCache(t *testing.T) {
+	owner := metav1.OwnerReference{Kind: "ReplicaSet", Name: "nginx", UID: "rs-uid"}
+	other := newTestPod("other", true)
+	other.Namespace = "kube-system"
+	node2 := newTestPod("node2", true)
+	node2.Spec.NodeName = "node2"
+	f := newTestFactory(newTestPod("a", true, owner), newTestPod("b", false), node2, other,
+		newTestDeployment("nginx", true))
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	f.ctx = ctx
+
+	if err := f.StartCache(ctx, "unknown"); err == nil {
+		t.Error("StartCache of an unknown kind succeeded")
+	}
+	p := f.Pods(testNamespace)
+	if err := p.AddIndexers(cache.Indexers{"ip": func(obj interface{}) ([]string, error) {
+		return []string{obj.(*corev1.Pod).Status.PodIP}, nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.StartCache(ctx, ResourceKindPod, "Deployment"); err != nil {
+		t.Fatal(err)
+	}
+
+	lister := p.Lister()
+	if pod, err := lister.Get("a"); err != nil || pod.Name != "a" {
+		t.Errorf("Get(a) = %v, %v", pod, err)
+	}
+	if _, err := lister.Get("other"); !k8serrors.IsNotFound(err) {
+		t.Errorf("Get(other) error = %v, want NotFound", err)
+	}
+	if pods, err := lister.List("app=nginx"); err != nil || len(pods) != 3 {
+		t.Errorf("List(app=nginx) = %v, %v, want 3 pods", podNames(pods), err)
+	}
+	if pods, _ := f.Pods("").WithNamespace(metav1.NamespaceAll).Lister().List(""); len(pods) != 4 {
+		t.Errorf("List of all namespaces = %v, want 4 pods", podNames(pods))
+	}
+	if pods, err := lister.ByIndex(IndexByNode, "node1"); err != nil || len(pods) != 2 {
+		t.Errorf("ByIndex(node, node1) = %v, %v, want a and b", podNames(pods), err)
+	}
+	// the lister of all namespaces gets the pods on the node in every namespace.
+	all := f.Pods("").WithNamespace(metav1.NamespaceAll).Lister()
+	if pods, err := all.ByIndex(IndexByNode, "node1"); err != nil || strings.Join(podNames(pods), ",") != "a,b,other" {
+		t.Errorf("ByIndex(node, node1) of all namespaces = %v, %v, want a, b and other", podNames(pods), err)
+	}
+	if pods, err := lister.ByIndex(IndexByOwner, "rs-uid"); err != nil || len(pods) != 1 || pods[0].Name != "a" {
+		t.Errorf("ByIndex(owner, rs-uid) = %v, %v, want a", podNames(pods), err)
+	}
+	if pods, err := lister.ByIndex("ip", "10.244.0.10"); err != nil || len(pods) != 3 {
+		t.Errorf("ByIndex(ip) = %v, %v, want 3 pods", podNames(pods), err)
+	}
+	if _, err := f.Deployments(testNamespace).Lister().Get("nginx"); err != nil {
+		t.Errorf("Get deployment from the cache: %v", err)
+	}
+
+	// the cache is updated by the informer.
+	if _, err := f.clientset.CoreV1().Pods(testNamespace).Create(ctx, newTestPod("c", true), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := lister.Get("c"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("pod c is not added to the cache")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGetPodsFromCache(t *testing.T) {
+	node2 := newTestPod("node2", true)
+	node2.Spec.NodeName = "node2"
+	failed := newTestPod("failed", false)
+	failed.Status.Phase = corev1.PodFailed
+	f := newTestFactory(newTestPod("a", true), failed, node2, newTestDeployment("nginx", true))
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	f.ctx = ctx
+	if err := f.StartCache(ctx, ResourceKindPod); err != nil {
+		t.Fatal(err)
+	}
+
+	// the fake clientset ignores the field selector, only the cache is
+	// indexed by the node.
+	n := f.Nodes()
+	pods, err := n.GetPods("node1")
+	if err != nil || len(pods.Items) != 2 {
+		t.Fatalf("GetPods(node1) = %v, %v, want 2 pods", pods, err)
+	}
+	if pods, err := n.GetNonTerminatedPods("node1"); err != nil || len(pods.Items) != 1 || pods.Items[0].Name != "a" {
+		t.Errorf("GetNonTerminatedPods(node1) = %v, %v, want a", pods, err)
+	}
+	names, err := f.Deployments(testNamespace).GetPods("nginx")
+	sort.Strings(names)
+	if err != nil || len(names) != 3 {
+		t.Errorf("Deployment.GetPods = %v, %v, want 3 pods", names, err)
+	}
+}
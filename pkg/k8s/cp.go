@@ -0,0 +1,325 @@
+package k8s
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CopyOptions is the options of CopyFromPod and CopyToPod.
+type CopyOptions struct {
+	// Container is the container to copy from or to, it's the default
+	// container of the pod if it's empty, eg: the first container.
+	Container string
+	// Progress is called with the total bytes of the tar archive transferred
+	// so far, it can be nil.
+	Progress func(bytes int64)
+}
+
+// progressWriter and progressReader report the bytes transferred.
+type progressWriter struct {
+	w        io.Writer
+	n        int64
+	progress func(int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	if w.progress != nil && n > 0 {
+		w.progress(w.n)
+	}
+	return n, err
+}
+
+type progressReader struct {
+	r        io.Reader
+	n        int64
+	progress func(int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	if r.progress != nil && n > 0 {
+		r.progress(r.n)
+	}
+	return n, err
+}
+
+// CopyFromPod copies the file or directory src in the pod to the local dst,
+// like "kubectl cp pod:src dst". It streams a tar archive over exec, so the
+// tar binary must be available in the container. The modes and modification
+// times are preserved, the entries escaping dst and the links are skipped.
+// If dst is an existing directory, src is copied into it. eg:
+//
+//	err := p.CopyFromPod("java-app", "/tmp/heap.hprof", "./heap.hprof", k8s.CopyOptions{
+//		Progress: func(n int64) { log.Infof("%d bytes copied", n) },
+//	})
+func (p *Pod) CopyFromPod(name, src, dst string, opts ...CopyOptions) error {
+	var o CopyOptions
+	if len(opts) != 0 {
+		o = opts[0]
+	}
+	if len(src) == 0 || len(dst) == 0 {
+		return fmt.Errorf("the source and the destination must be set")
+	}
+	src = path.Clean(src)
+	srcDir, srcBase := path.Dir(src), path.Base(src)
+	if info, err := os.Stat(dst); err == nil && info.IsDir() {
+		dst = filepath.Join(dst, srcBase)
+	}
+
+	reader, writer := io.Pipe()
+	stderr := &bytes.Buffer{}
+	done := make(chan error, 1)
+	go func() {
+		exitCode, err := p.Exec(name, ExecOptions{
+			Container: o.Container,
+			Command:   []string{"tar", "cf", "-", "-C", srcDir, srcBase},
+			Stdout:    writer,
+			Stderr:    stderr,
+		})
+		if err == nil && exitCode != 0 {
+			err = fmt.Errorf("tar exited with code %d: %s", exitCode, strings.TrimSpace(stderr.String()))
+		}
+		writer.CloseWithError(err)
+		done <- err
+	}()
+
+	err := untar(&progressReader{r: reader, progress: o.Progress}, srcBase, dst)
+	if err == nil {
+		// read the padding after the end of the archive.
+		io.Copy(io.Discard, reader)
+	}
+	// stop the remote tar if the archive can't be extracted, the exec then
+	// fails with a stream error, which hides the cause.
+	reader.CloseWithError(err)
+	execErr := <-done
+	if err == nil {
+		err = execErr
+	}
+	if err != nil {
+		return fmt.Errorf("copy %s from pod %s: %w", src, name, err)
+	}
+	return nil
+}
+
+// CopyToPod copies the local file or directory src to dst in the pod, like
+// "kubectl cp src pod:dst". It streams a tar archive over exec, so the tar
+// binary must be available in the container. Same as kubectl, tar runs
+// without -p and with -m, so the modes are only kept when the container runs
+// as root, otherwise they are masked by the umask of the container, and the
+// modification times are set to the time extracted. If dst is an existing
+// directory in the pod, src is copied into it.
+func (p *Pod) CopyToPod(name, src, dst string, opts ...CopyOptions) error {
+	var o CopyOptions
+	if len(opts) != 0 {
+		o = opts[0]
+	}
+	if len(src) == 0 || len(dst) == 0 {
+		return fmt.Errorf("the source and the destination must be set")
+	}
+	if _, err := os.Stat(src); err != nil {
+		return err
+	}
+	dst = path.Clean(dst)
+	if _, _, exitCode, err := p.ExecCapture(name, o.Container, []string{"test", "-d", dst}); err != nil {
+		return err
+	} else if exitCode == 0 {
+		dst = path.Join(dst, filepath.Base(src))
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(tarFiles(&progressWriter{w: writer, progress: o.Progress}, src, path.Base(dst)))
+	}()
+	stderr := &bytes.Buffer{}
+	exitCode, err := p.Exec(name, ExecOptions{
+		Container: o.Container,
+		Command:   []string{"tar", "xmf", "-", "-C", path.Dir(dst)},
+		Stdin:     reader,
+		Stderr:    stderr,
+	})
+	// unblock the tar writer if the remote tar exited early.
+	reader.Close()
+	if err == nil && exitCode != 0 {
+		err = fmt.Errorf("tar exited with code %d: %s", exitCode, strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		return fmt.Errorf("copy %s to pod %s: %w", src, name, err)
+	}
+	return nil
+}
+
+// tarFiles writes the local file or directory src to the tar archive, src is
+// renamed to name in the archive.
+func tarFiles(w io.Writer, src, name string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(src, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		} else if !info.Mode().IsRegular() && !info.IsDir() {
+			log.Warnf("skipping %s: not a regular file or directory", file)
+			return nil
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(name, filepath.ToSlash(rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// untar extracts the tar archive to dst, the entry prefix of the archive
+// is replaced with dst. The entries escaping dst, eg: "../../etc/passwd", and
+// the links are skipped, and it fails if a path under dst is a symlink, so
+// nothing is written outside of dst through an existing symlink.
+func untar(r io.Reader, prefix, dst string) error {
+	// the modes of the directories are set at last, so the read-only
+	// directories can be written.
+	type dirMode struct {
+		dir  string
+		mode os.FileMode
+	}
+	var dirs []dirMode
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		target, ok := untarTarget(header.Name, prefix, dst)
+		if !ok {
+			log.Warnf("skipping %q: it's outside of %s", header.Name, dst)
+			continue
+		}
+		if err := checkSymlinks(dst, target); err != nil {
+			return err
+		}
+		mode := header.FileInfo().Mode()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirMode{dir: target, mode: mode.Perm()})
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := writeFile(target, tr, mode.Perm()); err != nil {
+				return err
+			}
+			if err := os.Chtimes(target, header.ModTime, header.ModTime); err != nil {
+				return err
+			}
+		default:
+			log.Warnf("skipping %q: links and special files are not supported", header.Name)
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].dir, dirs[i].mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// untarTarget maps the entry name of the archive to the local path, it
+// returns false if the entry is not under the prefix or escapes dst.
+func untarTarget(name, prefix, dst string) (string, bool) {
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	if name == prefix {
+		return dst, true
+	}
+	if !strings.HasPrefix(name, prefix+"/") {
+		return "", false
+	}
+	rel := strings.TrimPrefix(name, prefix+"/")
+	return filepath.Join(dst, filepath.FromSlash(rel)), true
+}
+
+// checkSymlinks returns an error if target or any of its parents under dst
+// is a symlink, dst itself is not checked.
+func checkSymlinks(dst, target string) error {
+	rel, err := filepath.Rel(dst, target)
+	if err != nil || rel == "." {
+		return err
+	}
+	path := dst
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		path = filepath.Join(path, name)
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("refuse to write %s: %s is a symlink", target, path)
+		}
+	}
+	return nil
+}
+
+func writeFile(file string, r io.Reader, perm os.FileMode) error {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// the mode of an existing file is not changed by OpenFile, and the new
+	// file is masked by umask.
+	return os.Chmod(file, perm)
+}
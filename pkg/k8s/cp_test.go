@@ -0,0 +1,214 @@
+package k8s
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+)
+
+// tarExecutor fakes the tar and test commands in the container.
+type tarExecutor struct {
+	command []string
+	// archive is written to the stdout of "tar cf".
+	archive []byte
+	// received is the stdin of "tar xmf".
+	received []byte
+	// dir is whether "test -d" succeeds.
+	dir bool
+}
+
+func (e *tarExecutor) setURL(url *url.URL) { e.command = url.Query()["command"] }
+
+func (e *tarExecutor) Stream(options remotecommand.StreamOptions) error {
+	switch strings.Join(e.command[:2], " ") {
+	case "test -d":
+		if !e.dir {
+			return exec.CodeExitError{Err: errors.New("command terminated with exit code 1"), Code: 1}
+		}
+	case "tar cf":
+		if _, err := options.Stdout.Write(e.archive); err != nil {
+			// the stream is reset when the local side stops reading.
+			return errors.New("stream reset")
+		}
+	case "tar xmf":
+		var err error
+		e.received, err = io.ReadAll(options.Stdin)
+		return err
+	}
+	return nil
+}
+
+func TestCopyFromPod(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, header := range []*tar.Header{
+		{Name: "conf/", Typeflag: tar.TypeDir, Mode: 0700},
+		{Name: "conf/nginx.conf", Typeflag: tar.TypeReg, Mode: 0640, Size: 5},
+		{Name: "conf/../../evil", Typeflag: tar.TypeReg, Mode: 0644, Size: 5},
+		{Name: "conf/link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+	} {
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Size != 0 {
+			tw.Write([]byte("hello"))
+		}
+	}
+	tw.Close()
+	executor := &tarExecutor{archive: buf.Bytes()}
+	p := newExecTestPod(t, executor)
+
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "out", "nginx")
+	if err := os.Mkdir(filepath.Join(dir, "out"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var progress int64
+	if err := p.CopyFromPod("nginx", "/etc/conf", dst, CopyOptions{Progress: func(n int64) { progress = n }}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(executor.command, " "); got != "tar cf - -C /etc conf" {
+		t.Errorf("command = %q", got)
+	}
+	data, err := os.ReadFile(filepath.Join(dst, "nginx.conf"))
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("nginx.conf = %q, %v", data, err)
+	}
+	if info, _ := os.Stat(filepath.Join(dst, "nginx.conf")); info.Mode().Perm() != 0640 {
+		t.Errorf("mode of nginx.conf = %v, want 0640", info.Mode().Perm())
+	}
+	if info, _ := os.Stat(dst); info.Mode().Perm() != 0700 {
+		t.Errorf("mode of the directory = %v, want 0700", info.Mode().Perm())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil")); !os.IsNotExist(err) {
+		t.Errorf("the entry escaping the destination is extracted: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dst, "link")); !os.IsNotExist(err) {
+		t.Errorf("the symlink is extracted: %v", err)
+	}
+	if progress != int64(len(executor.archive)) {
+		t.Errorf("progress = %d, want %d", progress, len(executor.archive))
+	}
+}
+
+func TestCopyToPod(t *testing.T) {
+	executor := &tarExecutor{dir: true}
+	p := newExecTestPod(t, executor)
+
+	src := filepath.Join(t.TempDir(), "conf")
+	if err := os.MkdirAll(filepath.Join(src, "conf.d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "conf.d", "default.conf"), []byte("server {}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var progress int64
+	if err := p.CopyToPod("nginx", src, "/etc/nginx", CopyOptions{Progress: func(n int64) { progress = n }}); err != nil {
+		t.Fatal(err)
+	}
+	// "/etc/nginx" is a directory, src is copied into it.
+	if got := strings.Join(executor.command, " "); got != "tar xmf - -C /etc/nginx" {
+		t.Errorf("command = %q", got)
+	}
+	if progress != int64(len(executor.received)) || progress == 0 {
+		t.Errorf("progress = %d, want %d", progress, len(executor.received))
+	}
+	modes := make(map[string]os.FileMode)
+	tr := tar.NewReader(bytes.NewReader(executor.received))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		modes[header.Name] = header.FileInfo().Mode().Perm()
+	}
+	if len(modes) != 3 || modes["conf/"] != 0755 || modes["conf/conf.d/default.conf"] != 0600 {
+		t.Errorf("archive entries = %v", modes)
+	}
+
+	if err := p.CopyToPod("nginx", filepath.Join(src, "notexist"), "/tmp"); err == nil {
+		t.Error("CopyToPod of a missing file succeeded")
+	}
+}
+
+func TestUntarTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"data", "/tmp/dst", true},
+		{"data/a/b.txt", "/tmp/dst/a/b.txt", true},
+		{"/data/a.txt", "/tmp/dst/a.txt", true},
+		{"data/../../etc/passwd", "", false},
+		{"../data/a.txt", "", false},
+		{"other/a.txt", "", false},
+		{"data2/a.txt", "", false},
+	}
+	for _, test := range tests {
+		got, ok := untarTarget(test.name, "data", "/tmp/dst")
+		if got != filepath.FromSlash(test.want) || ok != test.ok {
+			t.Errorf("untarTarget(%q) = %q, %v, want %q, %v", test.name, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestUntarSymlink(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	tw.WriteHeader(&tar.Header{Name: "data/", Typeflag: tar.TypeDir, Mode: 0755})
+	tw.WriteHeader(&tar.Header{Name: "data/sub/passwd", Typeflag: tar.TypeReg, Mode: 0644, Size: 5})
+	tw.Write([]byte("hello"))
+	tw.Close()
+
+	// data/sub is a symlink to a directory outside of the destination.
+	dir, outside := t.TempDir(), t.TempDir()
+	dst := filepath.Join(dir, "data")
+	if err := os.Mkdir(dst, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dst, "sub")); err != nil {
+		t.Skip(err)
+	}
+	if err := untar(buf, "data", dst); err == nil || !strings.Contains(err.Error(), "symlink") {
+		t.Errorf("untar error = %v, want the symlink refused", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "passwd")); !os.IsNotExist(err) {
+		t.Errorf("the file is written through the symlink: %v", err)
+	}
+}
+
+func TestCopyFromPodUntarError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	tw.WriteHeader(&tar.Header{Name: "data/", Typeflag: tar.TypeDir, Mode: 0755})
+	tw.WriteHeader(&tar.Header{Name: "data/sub/passwd", Typeflag: tar.TypeReg, Mode: 0644, Size: 5})
+	tw.Write([]byte("hello"))
+	tw.Close()
+	p := newExecTestPod(t, &tarExecutor{archive: buf.Bytes()})
+
+	// data/sub is a symlink to a directory outside of the destination.
+	dir, outside := t.TempDir(), t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "data", "sub")); err != nil {
+		t.Skip(err)
+	}
+	err := p.CopyFromPod("nginx", "/data", dir)
+	if err == nil || !strings.Contains(err.Error(), "symlink") {
+		t.Errorf("CopyFromPod error = %v, want the symlink refused", err)
+	}
+}
@@ -0,0 +1,89 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestDeployment(name string, available bool) *appsv1.Deployment {
+	status := corev1.ConditionFalse
+	if available {
+		status = corev1.ConditionTrue
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+		},
+		Status: appsv1.DeploymentStatus{
+			Conditions: []appsv1.DeploymentCondition{{Type: appsv1.DeploymentAvailable, Status: status}},
+		},
+	}
+}
+
+func TestDeploymentIsReady(t *testing.T) {
+	deploy := newTestFactory(newTestDeployment("ready", true), newTestDeployment("notready", false)).Deployments(testNamespace)
+	if !deploy.IsReady("ready") {
+		t.Errorf("IsReady(ready) = false, want true")
+	}
+	if deploy.IsReady("notready") {
+		t.Errorf("IsReady(notready) = true, want false")
+	}
+}
+
+func TestDeploymentWaitReady(t *testing.T) {
+	deploy := newTestFactory(newTestDeployment("nginx", false)).Deployments(testNamespace)
+
+	// WaitReady returns error if the deployment doesn't exist.
+	if err := deploy.WaitReady("notexist", true); err == nil {
+		t.Errorf("WaitReady(notexist) returns no error")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- deploy.WaitReady("nginx", true) }()
+
+	// the deployment becomes available, keep updating it until WaitReady
+	// observes the modified event.
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+			return
+		case <-ticker.C:
+			obj := newTestDeployment("nginx", true)
+			if _, err := deploy.factory.clientset.AppsV1().Deployments(testNamespace).UpdateStatus(deploy.ctx, obj, metav1.UpdateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		case <-timeout:
+			t.Fatal("WaitReady doesn't return after the deployment is available")
+		}
+	}
+}
+
+func TestDeploymentGetPods(t *testing.T) {
+	other := newTestPod("other", true)
+	other.Labels = map[string]string{"app": "other"}
+	deploy := newTestFactory(
+		newTestDeployment("nginx", true),
+		newTestPod("nginx-1", true),
+		newTestPod("nginx-2", true),
+		other,
+	).Deployments(testNamespace)
+
+	pods, err := deploy.GetPods("nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 2 {
+		t.Errorf("GetPods = %v, want [nginx-1 nginx-2]", pods)
+	}
+}
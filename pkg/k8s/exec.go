@@ -0,0 +1,140 @@
+package k8s
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+)
+
+// newExecutor creates the executor of the pods/exec url, it's replaced in
+// unit tests.
+var newExecutor = func(config *rest.Config, method string, url *url.URL) (remotecommand.Executor, error) {
+	return remotecommand.NewSPDYExecutor(config, method, url)
+}
+
+// ExecOptions is the command and the streams of Exec.
+type ExecOptions struct {
+	// Container is the container to execute the command in, it's the default
+	// container of the pod if it's empty, eg: the first container.
+	Container string
+	Command   []string
+
+	// Stdin, Stdout and Stderr are connected to the remote command if they
+	// are not nil, at least one of them must be set.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// TTY allocates a tty for the command, the stderr is merged into the
+	// stdout and Stderr is not used. The local terminal is not touched, put
+	// it into raw mode if needed, eg: Pod.Execute.
+	TTY bool
+	// Resize sends the terminal size to the tty when it changes, it's used
+	// only if TTY is true, eg: MonitorTerminalSize for the local terminal, or
+	// the resize events of the browser forwarded by a web terminal.
+	Resize <-chan remotecommand.TerminalSize
+}
+
+// Exec executes the command in the container of the pod, and returns the
+// exit code of the command. A non-zero exit code is not an error, the error
+// is returned only if the command can't be executed, eg:
+//
+//	exitCode, err := p.Exec("nginx", k8s.ExecOptions{
+//		Command: []string{"nginx", "-t"},
+//		Stdout:  os.Stdout,
+//		Stderr:  os.Stderr,
+//	})
+//
+// It works in the non-terminal process, eg: the CI jobs and the http services.
+func (p *Pod) Exec(name string, opts ExecOptions) (int, error) {
+	err := p.stream(name, opts)
+	var exitErr exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		return exitErr.ExitStatus(), nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return 0, nil
+}
+
+// ExecCapture executes the command in the container of the pod without stdin,
+// and returns the stdout, stderr and exit code of the command, eg:
+//
+//	stdout, stderr, exitCode, err := p.ExecCapture("nginx", "", []string{"cat", "/etc/nginx/nginx.conf"})
+func (p *Pod) ExecCapture(name, container string, command []string) ([]byte, []byte, int, error) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	exitCode, err := p.Exec(name, ExecOptions{
+		Container: container,
+		Command:   command,
+		Stdout:    stdout,
+		Stderr:    stderr,
+	})
+	return stdout.Bytes(), stderr.Bytes(), exitCode, err
+}
+
+// stream connects the streams to the command executed in the pod, it returns
+// the error of remotecommand, the exit code of the command is wrapped in
+// exec.ExitError.
+func (p *Pod) stream(name string, opts ExecOptions) error {
+	if p.factory.config == nil {
+		return fmt.Errorf("the factory has no rest config, can't execute command in pod")
+	}
+	if len(opts.Command) == 0 {
+		return fmt.Errorf("no command to execute in pod %s", name)
+	}
+	if opts.Stdin == nil && opts.Stdout == nil && opts.Stderr == nil {
+		return fmt.Errorf("at least one of Stdin, Stdout and Stderr must be set")
+	}
+	if opts.TTY {
+		opts.Stderr = nil
+	}
+	pod, err := p.Get(name)
+	if err != nil {
+		return err
+	}
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return fmt.Errorf("cannot exec into a container in a completed pod; current phase is %s", pod.Status.Phase)
+	}
+	container := opts.Container
+	if len(container) == 0 {
+		container = defaultContainer(pod)
+	}
+
+	req := p.factory.restClient.Post().
+		Namespace(pod.Namespace).
+		Resource("pods").
+		Name(name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   opts.Command,
+			Stdin:     opts.Stdin != nil,
+			Stdout:    opts.Stdout != nil,
+			Stderr:    opts.Stderr != nil,
+			TTY:       opts.TTY,
+		}, scheme.ParameterCodec)
+	executor, err := newExecutor(p.factory.config, "POST", req.URL())
+	if err != nil {
+		return err
+	}
+	streamOptions := remotecommand.StreamOptions{
+		Stdin:  opts.Stdin,
+		Stdout: opts.Stdout,
+		Stderr: opts.Stderr,
+		Tty:    opts.TTY,
+	}
+	if opts.TTY && opts.Resize != nil {
+		done := make(chan struct{})
+		defer close(done)
+		streamOptions.TerminalSizeQueue = sizeQueue{ch: opts.Resize, done: done}
+	}
+	return executor.Stream(streamOptions)
+}
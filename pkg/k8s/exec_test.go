@@ -0,0 +1,149 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+)
+
+// fakeExecutor echoes the stdin to the stdout, writes the command to the
+// stderr and exits with the code.
+type fakeExecutor struct {
+	url  *url.URL
+	code int
+	err  error
+	// sizes are the terminal sizes received before the stream ends.
+	sizes []remotecommand.TerminalSize
+}
+
+func (e *fakeExecutor) Stream(options remotecommand.StreamOptions) error {
+	if e.err != nil {
+		return e.err
+	}
+	if options.TerminalSizeQueue != nil {
+		for len(e.sizes) < 2 {
+			size := options.TerminalSizeQueue.Next()
+			if size == nil {
+				break
+			}
+			e.sizes = append(e.sizes, *size)
+		}
+	}
+	if options.Stdin != nil && options.Stdout != nil {
+		io.Copy(options.Stdout, options.Stdin)
+	}
+	if options.Stderr != nil {
+		io.WriteString(options.Stderr, e.url.Query().Get("command"))
+	}
+	if e.code != 0 {
+		return exec.CodeExitError{Err: errors.New("command terminated with non-zero exit code"), Code: e.code}
+	}
+	return nil
+}
+
+func (e *fakeExecutor) setURL(url *url.URL) { e.url = url }
+
+// testExecutor is the executor of newExecTestPod, it's given the url of
+// the pods/exec request.
+type testExecutor interface {
+	remotecommand.Executor
+	setURL(url *url.URL)
+}
+
+func newExecTestPod(t *testing.T, executor testExecutor) *Pod {
+	t.Helper()
+	f := newTestFactory(newTestPod("nginx", true))
+	f.config = &rest.Config{Host: "https://127.0.0.1:6443"}
+	restClient, err := rest.RESTClientFor(&rest.Config{
+		Host:    f.config.Host,
+		APIPath: "api",
+		ContentConfig: rest.ContentConfig{
+			GroupVersion:         &corev1.SchemeGroupVersion,
+			NegotiatedSerializer: scheme.Codecs,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.restClient = restClient
+
+	origin := newExecutor
+	t.Cleanup(func() { newExecutor = origin })
+	newExecutor = func(config *rest.Config, method string, url *url.URL) (remotecommand.Executor, error) {
+		executor.setURL(url)
+		return executor, nil
+	}
+	return f.Pods(testNamespace)
+}
+
+func TestExec(t *testing.T) {
+	executor := &fakeExecutor{}
+	p := newExecTestPod(t, executor)
+
+	stdout := &strings.Builder{}
+	exitCode, err := p.Exec("nginx", ExecOptions{Command: []string{"cat"}, Stdin: strings.NewReader("hello"), Stdout: stdout, TTY: true})
+	if err != nil || exitCode != 0 || stdout.String() != "hello" {
+		t.Fatalf("Exec = %d, %v, stdout %q", exitCode, err, stdout.String())
+	}
+	query := executor.url.Query()
+	if executor.url.Path != "/api/v1/namespaces/"+testNamespace+"/pods/nginx/exec" ||
+		query.Get("container") != "nginx" || query.Get("tty") != "true" || query.Get("stderr") != "" {
+		t.Errorf("exec url = %s", executor.url)
+	}
+
+	executor.code = 2
+	stdout2, stderr, exitCode, err := p.ExecCapture("nginx", "nginx", []string{"ls", "/notexist"})
+	if err != nil || exitCode != 2 || len(stdout2) != 0 || string(stderr) != "ls" {
+		t.Errorf("ExecCapture = %q, %q, %d, %v, want exit code 2", stdout2, stderr, exitCode, err)
+	}
+
+	executor.err = errors.New("connection refused")
+	if exitCode, err := p.Exec("nginx", ExecOptions{Command: []string{"ls"}, Stdout: io.Discard}); err == nil || exitCode != -1 {
+		t.Errorf("Exec = %d, %v, want the error of the executor", exitCode, err)
+	}
+	if _, err := p.Exec("nginx", ExecOptions{Command: []string{"ls"}}); err == nil {
+		t.Error("Exec without streams succeeded")
+	}
+	if _, err := p.Exec("notexist", ExecOptions{Command: []string{"ls"}, Stdout: io.Discard}); err == nil {
+		t.Error("Exec in an unknown pod succeeded")
+	}
+}
+
+func TestExecResize(t *testing.T) {
+	executor := &fakeExecutor{}
+	p := newExecTestPod(t, executor)
+
+	resize := make(chan remotecommand.TerminalSize, 2)
+	resize <- remotecommand.TerminalSize{Width: 80, Height: 24}
+	resize <- remotecommand.TerminalSize{Width: 120, Height: 40}
+	if _, err := p.Exec("nginx", ExecOptions{Command: []string{"top"}, Stdout: io.Discard, TTY: true, Resize: resize}); err != nil {
+		t.Fatal(err)
+	}
+	if len(executor.sizes) != 2 || executor.sizes[1].Width != 120 || executor.sizes[1].Height != 40 {
+		t.Errorf("terminal sizes = %v, want 80x24 and 120x40", executor.sizes)
+	}
+
+	// the size queue ends when the stream is done, even if the resize
+	// channel is not closed.
+	done := make(chan struct{})
+	close(done)
+	if size := (sizeQueue{ch: make(chan remotecommand.TerminalSize), done: done}).Next(); size != nil {
+		t.Errorf("Next = %v, want nil", size)
+	}
+
+	// the channel of MonitorTerminalSize is closed when ctx is done.
+	ctx, cancel := context.WithCancel(context.TODO())
+	sizes := MonitorTerminalSize(ctx, -1)
+	cancel()
+	for range sizes {
+	}
+}
@@ -0,0 +1,191 @@
+package k8s
+
+import (
+	"hybfkuf/pkg/k8s/apply"
+
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// Factory owns one rest.Config and one set of clients (RESTClient, Clientset,
+// dynamic client, discovery client, metrics clientset and SharedInformerFactory).
+// Every handler built from a Factory, eg: factory.Pods("default"), shares these
+// clients instead of opening its own connections and discovery caches.
+type Factory struct {
+	kubeconfig string
+
+	ctx              context.Context
+	config           *rest.Config
+	restClient       rest.Interface
+	clientset        kubernetes.Interface
+	dynamicClient    dynamic.Interface
+	discoveryClient  discovery.DiscoveryInterface
+	metricsClientset metricsv.Interface
+	informerFactory  informers.SharedInformerFactory
+
+	// restMapper is created on the first use, see RESTMapper.
+	restMapperOnce sync.Once
+	restMapper     *restmapper.DeferredDiscoveryRESTMapper
+
+	// caches are the informers started by StartCache, keyed by resource kind.
+	cacheMu sync.Mutex
+	caches  map[string]cache.SharedIndexInformer
+
+	render       RenderFunc
+	renderOutput io.Writer
+}
+
+// NewFactory new a Factory from kubeconfig or in-cluster config
+func NewFactory(ctx context.Context, kubeconfig string) (factory *Factory, err error) {
+	var (
+		config *rest.Config
+	)
+
+	// create rest config
+	if len(kubeconfig) != 0 {
+		// use the current context in kubeconfig
+		if config, err = clientcmd.BuildConfigFromFlags("", kubeconfig); err != nil {
+			return nil, err
+		}
+	} else {
+		// create the in-cluster config
+		if config, err = rest.InClusterConfig(); err != nil {
+			return nil, err
+		}
+	}
+	return NewFactoryForConfig(ctx, config, kubeconfig)
+}
+
+// NewFactoryForConfig new a Factory from the given rest config.
+// kubeconfig is only recorded, it may be empty.
+func NewFactoryForConfig(ctx context.Context, config *rest.Config, kubeconfig string) (factory *Factory, err error) {
+	var (
+		restClient       *rest.RESTClient
+		clientset        *kubernetes.Clientset
+		dynamicClient    dynamic.Interface
+		discoveryClient  *discovery.DiscoveryClient
+		metricsClientset *metricsv.Clientset
+	)
+
+	// the RESTClient is used for core/v1 subresources such as pods/exec,
+	// setup APIPath, GroupVersion and NegotiatedSerializer on a copy of the
+	// config so that the shared config is left untouched.
+	restConfig := rest.CopyConfig(config)
+	restConfig.APIPath = "api"
+	restConfig.GroupVersion = &corev1.SchemeGroupVersion
+	restConfig.NegotiatedSerializer = scheme.Codecs
+
+	// create a RESTClient for the given config
+	if restClient, err = rest.RESTClientFor(restConfig); err != nil {
+		return nil, err
+	}
+	// create a Clientset for the given config
+	if clientset, err = kubernetes.NewForConfig(config); err != nil {
+		return nil, err
+	}
+	// create a dynamic client for the given config
+	if dynamicClient, err = dynamic.NewForConfig(config); err != nil {
+		return nil, err
+	}
+	// create a DiscoveryClient for the given config
+	if discoveryClient, err = discovery.NewDiscoveryClientForConfig(config); err != nil {
+		return nil, err
+	}
+	// create a metrics clientset for the given config
+	if metricsClientset, err = metricsv.NewForConfig(config); err != nil {
+		return nil, err
+	}
+
+	factory = NewFactoryForClients(ctx, clientset, dynamicClient, metricsClientset)
+	factory.kubeconfig = kubeconfig
+	factory.config = config
+	factory.restClient = restClient
+	factory.discoveryClient = discoveryClient
+
+	return factory, nil
+}
+
+// NewFactoryForClients new a Factory from the given clients, it's mostly used
+// with the fake clientsets of client-go in unit tests:
+//
+//	factory := NewFactoryForClients(ctx,
+//		fake.NewSimpleClientset(objects...),
+//		dynamicfake.NewSimpleDynamicClient(scheme.Scheme),
+//		metricsfake.NewSimpleClientset())
+//
+// dynamicClient and metricsClientset may be nil if they are not used.
+// The factory has no rest config, so the methods which need one, eg:
+// Pod.Execute, return error.
+func NewFactoryForClients(ctx context.Context, clientset kubernetes.Interface,
+	dynamicClient dynamic.Interface, metricsClientset metricsv.Interface) *Factory {
+	factory := &Factory{}
+	factory.ctx = ctx
+	factory.clientset = clientset
+	factory.restClient = clientset.CoreV1().RESTClient()
+	factory.discoveryClient = clientset.Discovery()
+	factory.dynamicClient = dynamicClient
+	factory.metricsClientset = metricsClientset
+	// create a sharedInformerFactory for all namespaces.
+	factory.informerFactory = informers.NewSharedInformerFactory(clientset, time.Minute)
+
+	return factory
+}
+
+// Config returns the rest config shared by all handlers of the factory.
+func (f *Factory) Config() *rest.Config {
+	return f.config
+}
+
+// RESTClient returns the core/v1 RESTClient shared by all handlers of the factory.
+func (f *Factory) RESTClient() rest.Interface {
+	return f.restClient
+}
+
+// Clientset returns the Clientset shared by all handlers of the factory.
+func (f *Factory) Clientset() kubernetes.Interface {
+	return f.clientset
+}
+
+// DynamicClient returns the dynamic client shared by all handlers of the factory.
+func (f *Factory) DynamicClient() dynamic.Interface {
+	return f.dynamicClient
+}
+
+// DiscoveryClient returns the DiscoveryClient shared by all handlers of the factory.
+func (f *Factory) DiscoveryClient() discovery.DiscoveryInterface {
+	return f.discoveryClient
+}
+
+// RESTMapper returns the discovery RESTMapper shared by the Appliers and the
+// dynamic scale clients of the factory, the discovery information is fetched
+// once, and fetched again when a kind is not found.
+func (f *Factory) RESTMapper() *restmapper.DeferredDiscoveryRESTMapper {
+	f.restMapperOnce.Do(func() {
+		f.restMapper = apply.NewRESTMapper(f.discoveryClient)
+	})
+	return f.restMapper
+}
+
+// MetricsClientset returns the metrics clientset shared by all handlers of the factory.
+func (f *Factory) MetricsClientset() metricsv.Interface {
+	return f.metricsClientset
+}
+
+// InformerFactory returns the SharedInformerFactory shared by all handlers of the factory.
+func (f *Factory) InformerFactory() informers.SharedInformerFactory {
+	return f.informerFactory
+}
@@ -0,0 +1,807 @@
+package k8s
+
+import (
+	"hybfkuf/pkg/k8s/apply"
+
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resourceClient is the method set shared by every typed client of client-go,
+// eg: typedcorev1.PodInterface, typedappsv1.DeploymentInterface.
+type resourceClient[T, TList any] interface {
+	Create(ctx context.Context, obj *T, opts metav1.CreateOptions) (*T, error)
+	Update(ctx context.Context, obj *T, opts metav1.UpdateOptions) (*T, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*T, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*TList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*T, error)
+}
+
+// Handler is the generic engine behind every per-kind handler (Pod, Deployment,
+// Service...). T is the typed object, eg: corev1.Pod, and TList is its list
+// type, eg: corev1.PodList. The per-kind handlers embed *Handler, so that the
+// CRUD, list, watch and informer logic lives in one place.
+type Handler[T, TList any] struct {
+	kind       string
+	namespaced bool
+	namespace  string
+
+	factory  *Factory
+	ctx      context.Context
+	client   func(namespace string) resourceClient[T, TList]
+	informer func() cache.SharedIndexInformer
+
+	Options *HandlerOptions
+
+	sync.Mutex
+}
+
+// newHandler new a generic handler.
+// client returns the typed client for the given namespace, the namespace is
+// ignored by cluster scope resources. informer is called only when an
+// informer is needed, so the informer is not registered in the factory
+// until it's used.
+func newHandler[T, TList any](f *Factory, kind string, namespaced bool, namespace string,
+	client func(namespace string) resourceClient[T, TList],
+	informer func() cache.SharedIndexInformer) *Handler[T, TList] {
+	if !namespaced {
+		namespace = ""
+	} else if len(namespace) == 0 {
+		namespace = metav1.NamespaceDefault
+	}
+	return &Handler[T, TList]{
+		kind:       kind,
+		namespaced: namespaced,
+		namespace:  namespace,
+		factory:    f,
+		ctx:        f.ctx,
+		client:     client,
+		informer:   informer,
+		Options:    &HandlerOptions{Render: f.render, RenderOutput: f.renderOutput},
+	}
+}
+
+// Namespace returns the namespace the handler works in.
+// it's always empty for cluster scope resources.
+func (h *Handler[T, TList]) Namespace() string {
+	return h.namespace
+}
+
+// Kind returns the resource kind of the handler, eg: "pod".
+func (h *Handler[T, TList]) Kind() string {
+	return h.kind
+}
+
+// Factory returns the factory that the handler was built from.
+func (h *Handler[T, TList]) Factory() *Factory {
+	return h.factory
+}
+
+func (in *Handler[T, TList]) DeepCopy() *Handler[T, TList] {
+	out := new(Handler[T, TList])
+
+	out.kind = in.kind
+	out.namespaced = in.namespaced
+	out.namespace = in.namespace
+
+	out.factory = in.factory
+	out.ctx = in.ctx
+	out.client = in.client
+	out.informer = in.informer
+
+	out.Options = &HandlerOptions{}
+	out.Options.ListOptions = *in.Options.ListOptions.DeepCopy()
+	out.Options.GetOptions = *in.Options.GetOptions.DeepCopy()
+	out.Options.CreateOptions = *in.Options.CreateOptions.DeepCopy()
+	out.Options.DeleteOptions = *in.Options.DeleteOptions.DeepCopy()
+	out.Options.UpdateOptions = *in.Options.UpdateOptions.DeepCopy()
+	out.Options.PatchOptions = *in.Options.PatchOptions.DeepCopy()
+	out.Options.ApplyOptions = *in.Options.ApplyOptions.DeepCopy()
+	out.Options.ApplyMode = in.Options.ApplyMode
+	out.Options.Render = in.Options.Render
+	out.Options.RenderOutput = in.Options.RenderOutput
+
+	return out
+}
+
+func (h *Handler[T, TList]) setNamespace(namespace string) {
+	h.Lock()
+	defer h.Unlock()
+	if h.namespaced {
+		h.namespace = namespace
+	}
+}
+
+// WithNamespace returns a copy of the handler working in the given namespace.
+// cluster scope resources ignore the namespace.
+func (h *Handler[T, TList]) WithNamespace(namespace string) *Handler[T, TList] {
+	handler := h.DeepCopy()
+	handler.setNamespace(namespace)
+	return handler
+}
+
+// WithDryRun returns a copy of the handler whose write requests are dry-run.
+func (h *Handler[T, TList]) WithDryRun() *Handler[T, TList] {
+	handler := h.DeepCopy()
+	handler.Options.CreateOptions.DryRun = []string{metav1.DryRunAll}
+	handler.Options.UpdateOptions.DryRun = []string{metav1.DryRunAll}
+	handler.Options.DeleteOptions.DryRun = []string{metav1.DryRunAll}
+	handler.Options.PatchOptions.DryRun = []string{metav1.DryRunAll}
+	handler.Options.ApplyOptions.DryRun = []string{metav1.DryRunAll}
+	return handler
+}
+func (h *Handler[T, TList]) SetLimit(limit int64) {
+	h.Lock()
+	defer h.Unlock()
+	h.Options.ListOptions.Limit = limit
+}
+func (h *Handler[T, TList]) SetTimeout(timeout int64) {
+	h.Lock()
+	defer h.Unlock()
+	h.Options.ListOptions.TimeoutSeconds = &timeout
+}
+func (h *Handler[T, TList]) SetForceDelete(force bool) {
+	h.Lock()
+	defer h.Unlock()
+	if force {
+		gracePeriodSeconds := int64(0)
+		h.Options.DeleteOptions.GracePeriodSeconds = &gracePeriodSeconds
+	} else {
+		h.Options.DeleteOptions.GracePeriodSeconds = nil
+	}
+}
+
+// SetPropagationPolicy set the propagation policy used by delete,
+// the policy is one of "background", "foreground" and "orphan".
+func (h *Handler[T, TList]) SetPropagationPolicy(policy string) {
+	h.Lock()
+	defer h.Unlock()
+	switch strings.ToLower(policy) {
+	case strings.ToLower(string(metav1.DeletePropagationBackground)):
+		propagationPolicy := metav1.DeletePropagationBackground
+		h.Options.DeleteOptions.PropagationPolicy = &propagationPolicy
+	case strings.ToLower(string(metav1.DeletePropagationForeground)):
+		propagationPolicy := metav1.DeletePropagationForeground
+		h.Options.DeleteOptions.PropagationPolicy = &propagationPolicy
+	case strings.ToLower(string(metav1.DeletePropagationOrphan)):
+		propagationPolicy := metav1.DeletePropagationOrphan
+		h.Options.DeleteOptions.PropagationPolicy = &propagationPolicy
+	default:
+		propagationPolicy := metav1.DeletePropagationBackground
+		h.Options.DeleteOptions.PropagationPolicy = &propagationPolicy
+	}
+}
+
+// SetApplyMode set the strategy used by the Apply methods.
+func (h *Handler[T, TList]) SetApplyMode(mode ApplyMode) {
+	h.Lock()
+	defer h.Unlock()
+	h.Options.ApplyMode = mode
+}
+
+// SetFieldManager set the field manager used by server-side apply,
+// default to "client-go".
+func (h *Handler[T, TList]) SetFieldManager(name string) {
+	h.Lock()
+	defer h.Unlock()
+	h.Options.ApplyOptions.FieldManager = name
+}
+
+// SetForceConflicts set whether server-side apply takes the ownership of
+// the fields owned by other field managers, instead of returning a conflict error.
+func (h *Handler[T, TList]) SetForceConflicts(force bool) {
+	h.Lock()
+	defer h.Unlock()
+	h.Options.ApplyOptions.Force = force
+}
+
+// SetRender set how to render the manifests of the *FromBytes and *FromFile
+// methods before they are decoded, eg: apply.Template(values) renders the
+// manifests as go templates, apply.Env(nil) substitutes ${VAR} with the
+// environment variables.
+func (h *Handler[T, TList]) SetRender(render RenderFunc) {
+	h.Lock()
+	defer h.Unlock()
+	h.Options.Render = render
+}
+
+// SetRenderOutput set where the rendered manifests are dumped to.
+func (h *Handler[T, TList]) SetRenderOutput(w io.Writer) {
+	h.Lock()
+	defer h.Unlock()
+	h.Options.RenderOutput = w
+}
+
+// namespaceOf returns the namespace of the object, if the object doesn't
+// set the namespace, the namespace of the handler is used.
+func (h *Handler[T, TList]) namespaceOf(obj *T) string {
+	if !h.namespaced {
+		return ""
+	}
+	if accessor, err := meta.Accessor(obj); err == nil && len(accessor.GetNamespace()) != 0 {
+		return accessor.GetNamespace()
+	}
+	return h.namespace
+}
+
+// nameOf returns the name of the object.
+func (h *Handler[T, TList]) nameOf(obj *T) string {
+	if accessor, err := meta.Accessor(obj); err == nil {
+		return accessor.GetName()
+	}
+	return ""
+}
+
+// fromRaw converts map[string]interface{} to the typed object.
+func (h *Handler[T, TList]) fromRaw(raw map[string]interface{}) (*T, error) {
+	obj := new(T)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// decode renders the yaml or json bytes with the render of the handler, and
+// converts them to the typed object, objJson is the json of the manifest.
+func (h *Handler[T, TList]) decode(name string, data []byte) (obj *T, objJson []byte, err error) {
+	if data, err = h.loader().Bytes(name, data); err != nil {
+		return nil, nil, err
+	}
+	if objJson, err = yaml.ToJSON(data); err != nil {
+		return nil, nil, err
+	}
+	obj = new(T)
+	if err = json.Unmarshal(objJson, obj); err != nil {
+		return nil, nil, err
+	}
+	return obj, objJson, nil
+}
+
+// decodeFile decodes the manifest of the object from path, path is a yaml or
+// json file, or a directory or kustomization which has only one object of the kind.
+func (h *Handler[T, TList]) decodeFile(path string) (*T, []byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !info.IsDir() && !apply.IsKustomizationFile(path) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return h.decode(path, data)
+	}
+	objects, err := h.loader().File(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var found []*unstructured.Unstructured
+	for _, obj := range objects {
+		if strings.EqualFold(obj.GetKind(), h.kind) {
+			found = append(found, obj)
+		}
+	}
+	if len(found) != 1 {
+		return nil, nil, fmt.Errorf("%s has %d %s, want 1", path, len(found), h.kind)
+	}
+	objJson, err := found[0].MarshalJSON()
+	if err != nil {
+		return nil, nil, err
+	}
+	obj := new(T)
+	if err = json.Unmarshal(objJson, obj); err != nil {
+		return nil, nil, err
+	}
+	return obj, objJson, nil
+}
+
+// loader returns the loader rendering the manifests with the render of the handler.
+func (h *Handler[T, TList]) loader() *apply.Loader {
+	return &apply.Loader{Render: h.Options.Render, Output: h.Options.RenderOutput}
+}
+
+func (h *Handler[T, TList]) create(obj *T) (*T, error) {
+	return h.client(h.namespaceOf(obj)).Create(h.ctx, obj, h.Options.CreateOptions)
+}
+func (h *Handler[T, TList]) update(obj *T) (*T, error) {
+	return h.client(h.namespaceOf(obj)).Update(h.ctx, obj, h.Options.UpdateOptions)
+}
+
+// apply applies the object with the ApplyMode of the handler,
+// objJson is the json of the object read from the manifest.
+func (h *Handler[T, TList]) apply(obj *T, objJson []byte) (*T, error) {
+	switch h.Options.ApplyMode {
+	case ApplyModeCreateOrUpdate:
+		return h.createOrUpdate(obj)
+	case ApplyModeClientSide:
+		return h.clientSideApply(obj, objJson)
+	default:
+		return h.serverSideApply(obj, objJson)
+	}
+}
+func (h *Handler[T, TList]) createOrUpdate(obj *T) (*T, error) {
+	created, err := h.create(obj)
+	if k8serrors.IsAlreadyExists(err) {
+		log.Debug(err)
+		return h.update(obj)
+	}
+	return created, err
+}
+
+// clientSideApply works like "kubectl apply" without --server-side, the
+// manifest is saved in the last-applied-configuration annotation, and the
+// live object is patched with a three-way strategic merge patch computed
+// from the last applied, the live and the desired object.
+func (h *Handler[T, TList]) clientSideApply(obj *T, objJson []byte) (*T, error) {
+	modified, err := apply.GetModifiedConfiguration(objJson)
+	if err != nil {
+		return nil, err
+	}
+	namespace, name := h.namespaceOf(obj), h.nameOf(obj)
+	live, err := h.client(namespace).Get(h.ctx, name, h.Options.GetOptions)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return nil, err
+		}
+		desired := new(T)
+		if err := json.Unmarshal(modified, desired); err != nil {
+			return nil, err
+		}
+		return h.create(desired)
+	}
+
+	accessor, err := meta.Accessor(live)
+	if err != nil {
+		return nil, err
+	}
+	current, err := json.Marshal(live)
+	if err != nil {
+		return nil, err
+	}
+	patch, patchType, err := apply.CreateThreeWayPatch(apply.GetOriginalConfiguration(accessor), modified, current, new(T))
+	if err != nil {
+		return nil, err
+	}
+	if apply.IsEmptyPatch(patch) {
+		log.Debugf("%s %s unchanged", h.kind, name)
+		return live, nil
+	}
+	return h.client(namespace).Patch(h.ctx, name, patchType, patch, h.Options.PatchOptions)
+}
+
+// serverSideApply sends the manifest as is instead of the marshaled typed
+// object, so the field manager only owns the fields set in the manifest.
+func (h *Handler[T, TList]) serverSideApply(obj *T, objJson []byte) (*T, error) {
+	return h.client(h.namespaceOf(obj)).Patch(h.ctx, h.nameOf(obj), types.ApplyPatchType, objJson, h.applyPatchOptions())
+}
+
+// applyPatchOptions converts ApplyOptions to the PatchOptions of server-side apply.
+func (h *Handler[T, TList]) applyPatchOptions() metav1.PatchOptions {
+	patchOptions := h.Options.ApplyOptions.ToPatchOptions()
+	if len(patchOptions.FieldManager) == 0 {
+		patchOptions.FieldManager = FieldManager
+	}
+	return patchOptions
+}
+
+// CreateFromRaw create object from map[string]interface{}
+func (h *Handler[T, TList]) CreateFromRaw(raw map[string]interface{}) (*T, error) {
+	obj, err := h.fromRaw(raw)
+	if err != nil {
+		return nil, err
+	}
+	return h.create(obj)
+}
+
+// CreateFromBytes create object from bytes
+func (h *Handler[T, TList]) CreateFromBytes(data []byte) (*T, error) {
+	obj, _, err := h.decode("-", data)
+	if err != nil {
+		return nil, err
+	}
+	return h.create(obj)
+}
+
+// CreateFromFile create object from yaml file
+func (h *Handler[T, TList]) CreateFromFile(path string) (*T, error) {
+	obj, _, err := h.decodeFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return h.create(obj)
+}
+
+// Create create object from file, alias to "CreateFromFile"
+func (h *Handler[T, TList]) Create(path string) (*T, error) {
+	return h.CreateFromFile(path)
+}
+
+// UpdateFromRaw update object from map[string]interface{}
+func (h *Handler[T, TList]) UpdateFromRaw(raw map[string]interface{}) (*T, error) {
+	obj, err := h.fromRaw(raw)
+	if err != nil {
+		return nil, err
+	}
+	return h.update(obj)
+}
+
+// UpdateFromBytes update object from bytes
+func (h *Handler[T, TList]) UpdateFromBytes(data []byte) (*T, error) {
+	obj, _, err := h.decode("-", data)
+	if err != nil {
+		return nil, err
+	}
+	return h.update(obj)
+}
+
+// UpdateFromFile update object from yaml file
+func (h *Handler[T, TList]) UpdateFromFile(path string) (*T, error) {
+	obj, _, err := h.decodeFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return h.update(obj)
+}
+
+// Update update object from file, alias to "UpdateFromFile"
+func (h *Handler[T, TList]) Update(path string) (*T, error) {
+	return h.UpdateFromFile(path)
+}
+
+// ApplyFromRaw apply object from map[string]interface{}
+func (h *Handler[T, TList]) ApplyFromRaw(raw map[string]interface{}) (*T, error) {
+	obj, err := h.fromRaw(raw)
+	if err != nil {
+		return nil, err
+	}
+	objJson, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	return h.apply(obj, objJson)
+}
+
+// ApplyFromBytes apply object from bytes
+func (h *Handler[T, TList]) ApplyFromBytes(data []byte) (*T, error) {
+	obj, objJson, err := h.decode("-", data)
+	if err != nil {
+		return nil, err
+	}
+	return h.apply(obj, objJson)
+}
+
+// ApplyFromFile apply object from yaml file
+func (h *Handler[T, TList]) ApplyFromFile(path string) (*T, error) {
+	obj, objJson, err := h.decodeFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return h.apply(obj, objJson)
+}
+
+// Apply apply object from file, alias to "ApplyFromFile"
+func (h *Handler[T, TList]) Apply(path string) (*T, error) {
+	return h.ApplyFromFile(path)
+}
+
+// diff returns the unified diff from the live object to the object returned
+// by a dry-run apply, empty if applying the object changes nothing.
+func (h *Handler[T, TList]) diff(obj *T, objJson []byte) (string, error) {
+	var live map[string]interface{}
+	current, err := h.client(h.namespaceOf(obj)).Get(h.ctx, h.nameOf(obj), h.Options.GetOptions)
+	switch {
+	case err == nil:
+		if live, err = runtime.DefaultUnstructuredConverter.ToUnstructured(current); err != nil {
+			return "", err
+		}
+	case !k8serrors.IsNotFound(err):
+		return "", err
+	}
+	applied, err := h.WithDryRun().apply(obj, objJson)
+	if err != nil {
+		return "", err
+	}
+	merged, err := runtime.DefaultUnstructuredConverter.ToUnstructured(applied)
+	if err != nil {
+		return "", err
+	}
+
+	// the objects returned by the typed client have no apiVersion and kind.
+	var gvk schema.GroupVersionKind
+	if object, ok := any(obj).(runtime.Object); ok {
+		gvk = object.GetObjectKind().GroupVersionKind()
+	}
+	for _, raw := range []map[string]interface{}{live, merged} {
+		if raw != nil && !gvk.Empty() {
+			(&unstructured.Unstructured{Object: raw}).SetGroupVersionKind(gvk)
+		}
+	}
+	if len(gvk.Kind) == 0 {
+		gvk.Kind = h.kind
+	}
+	ref := apply.Result{GroupVersionKind: gvk, Name: h.nameOf(obj)}.Ref()
+	return apply.DiffObjects(ref, live, merged)
+}
+
+// DiffFromBytes returns what applying the object from bytes would change,
+// as a unified diff from the live object, like "kubectl diff".
+func (h *Handler[T, TList]) DiffFromBytes(data []byte) (string, error) {
+	obj, objJson, err := h.decode("-", data)
+	if err != nil {
+		return "", err
+	}
+	return h.diff(obj, objJson)
+}
+
+// DiffFromFile returns what applying the object from yaml file would change
+func (h *Handler[T, TList]) DiffFromFile(path string) (string, error) {
+	obj, objJson, err := h.decodeFile(path)
+	if err != nil {
+		return "", err
+	}
+	return h.diff(obj, objJson)
+}
+
+// Diff returns what applying the object from file would change, alias to "DiffFromFile"
+func (h *Handler[T, TList]) Diff(path string) (string, error) {
+	return h.DiffFromFile(path)
+}
+
+// DeleteFromBytes delete object from bytes
+func (h *Handler[T, TList]) DeleteFromBytes(data []byte) error {
+	obj, _, err := h.decode("-", data)
+	if err != nil {
+		return err
+	}
+	return h.WithNamespace(h.namespaceOf(obj)).DeleteByName(h.nameOf(obj))
+}
+
+// DeleteFromFile delete object from yaml file
+func (h *Handler[T, TList]) DeleteFromFile(path string) error {
+	obj, _, err := h.decodeFile(path)
+	if err != nil {
+		return err
+	}
+	return h.WithNamespace(h.namespaceOf(obj)).DeleteByName(h.nameOf(obj))
+}
+
+// DeleteByName delete object by name
+func (h *Handler[T, TList]) DeleteByName(name string) error {
+	return h.client(h.namespace).Delete(h.ctx, name, h.Options.DeleteOptions)
+}
+
+// Delete delete object by name, alias to "DeleteByName"
+func (h *Handler[T, TList]) Delete(name string) error {
+	return h.DeleteByName(name)
+}
+
+// GetFromBytes get object from bytes
+func (h *Handler[T, TList]) GetFromBytes(data []byte) (*T, error) {
+	obj, _, err := h.decode("-", data)
+	if err != nil {
+		return nil, err
+	}
+	return h.WithNamespace(h.namespaceOf(obj)).GetByName(h.nameOf(obj))
+}
+
+// GetFromFile get object from yaml file
+func (h *Handler[T, TList]) GetFromFile(path string) (*T, error) {
+	obj, _, err := h.decodeFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return h.WithNamespace(h.namespaceOf(obj)).GetByName(h.nameOf(obj))
+}
+
+// GetByName get object by name
+func (h *Handler[T, TList]) GetByName(name string) (*T, error) {
+	return h.client(h.namespace).Get(h.ctx, name, h.Options.GetOptions)
+}
+
+// Get get object by name, alias to "GetByName"
+func (h *Handler[T, TList]) Get(name string) (*T, error) {
+	return h.GetByName(name)
+}
+
+// ListByLabel list objects by labels
+func (h *Handler[T, TList]) ListByLabel(labels string) (*TList, error) {
+	listOptions := h.Options.ListOptions.DeepCopy()
+	listOptions.LabelSelector = labels
+	return h.client(h.namespace).List(h.ctx, *listOptions)
+}
+
+// List list objects by labels, alias to "ListByLabel"
+func (h *Handler[T, TList]) List(labels string) (*TList, error) {
+	return h.ListByLabel(labels)
+}
+
+// ListByNamespace list all objects in the specified namespace
+func (h *Handler[T, TList]) ListByNamespace(namespace string) (*TList, error) {
+	return h.WithNamespace(namespace).ListByLabel("")
+}
+
+// ListAll list all objects in the k8s cluster
+func (h *Handler[T, TList]) ListAll() (*TList, error) {
+	return h.WithNamespace(metav1.NamespaceAll).ListByLabel("")
+}
+
+// WatchByName watch object by name, the callbacks are called with x when the
+// object is added, modified or deleted, addFunc is called for the existing
+// object too. It returns when the handler context is done. Use WatchEvents
+// to get the changed objects.
+func (h *Handler[T, TList]) WatchByName(name string,
+	addFunc, modifyFunc, deleteFunc func(x interface{}), x interface{}) (err error) {
+	return h.watchFuncs(WatchOptions{Name: name}, addFunc, modifyFunc, deleteFunc, x)
+}
+
+// WatchByLabel watch objects by labelSelector, see WatchByName.
+func (h *Handler[T, TList]) WatchByLabel(labelSelector string,
+	addFunc, modifyFunc, deleteFunc func(x interface{}), x interface{}) (err error) {
+	return h.watchFuncs(WatchOptions{LabelSelector: labelSelector}, addFunc, modifyFunc, deleteFunc, x)
+}
+
+// watchFuncs calls the callbacks on the events of WatchEvents, the existing
+// objects are sent as Added, same as a watch without resourceVersion.
+func (h *Handler[T, TList]) watchFuncs(opts WatchOptions,
+	addFunc, modifyFunc, deleteFunc func(x interface{}), x interface{}) error {
+	opts.SendInitialEvents = true
+	events, err := h.WatchEvents(h.ctx, opts)
+	if err != nil {
+		return err
+	}
+	for event := range events {
+		switch event.Type {
+		case watch.Added:
+			addFunc(x)
+		case watch.Modified:
+			modifyFunc(x)
+		case watch.Deleted:
+			deleteFunc(x)
+		}
+	}
+	return h.ctx.Err()
+}
+
+// Watch watch object by name, alias to "WatchByName"
+func (h *Handler[T, TList]) Watch(name string,
+	addFunc, modifyFunc, deleteFunc func(x interface{}), x interface{}) (err error) {
+	return h.WatchByName(name, addFunc, modifyFunc, deleteFunc, x)
+}
+
+// watchUntil gets the object and calls check with it, then watches the object
+// and calls check on every change, until check returns true or an error, or
+// ctx is done. obj is nil if the object doesn't exist or is deleted. When the
+// watch is closed by the server, it's resumed from the last resourceVersion,
+// the object is got again if the resourceVersion is expired.
+func (h *Handler[T, TList]) watchUntil(ctx context.Context, name string, check func(obj *T) (bool, error)) error {
+	resourceVersion := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(resourceVersion) == 0 {
+			obj, err := h.client(h.namespace).Get(ctx, name, h.Options.GetOptions)
+			switch {
+			case k8serrors.IsNotFound(err):
+				obj = nil
+			case err != nil:
+				return err
+			}
+			if done, err := check(obj); err != nil || done {
+				return err
+			}
+			if obj != nil {
+				if accessor, err := meta.Accessor(obj); err == nil {
+					resourceVersion = accessor.GetResourceVersion()
+				}
+			}
+		}
+		listOptions := metav1.SingleObject(metav1.ObjectMeta{Name: name, Namespace: h.namespace, ResourceVersion: resourceVersion})
+		listOptions.AllowWatchBookmarks = true
+		watcher, err := h.client(h.namespace).Watch(ctx, listOptions)
+		switch {
+		case k8serrors.IsResourceExpired(err), k8serrors.IsGone(err):
+			resourceVersion = ""
+			continue
+		case err != nil:
+			return err
+		}
+		var done bool
+		resourceVersion, done, err = h.watchEvents(ctx, watcher, name, resourceVersion, check)
+		watcher.Stop()
+		if err != nil || done {
+			return err
+		}
+		log.Debugf("watch %s %s: reconnect to kubernetes from resourceVersion %q", h.kind, name, resourceVersion)
+	}
+}
+
+// watchEvents calls check with the objects of the watch events, until the
+// watch is closed. It returns the last resourceVersion it has seen, or empty
+// if the resourceVersion is expired. It waits watchRetryInterval before
+// returning on the other error events.
+func (h *Handler[T, TList]) watchEvents(ctx context.Context, watcher watch.Interface, name, resourceVersion string,
+	check func(obj *T) (bool, error)) (string, bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return resourceVersion, false, ctx.Err()
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return resourceVersion, false, nil
+			}
+			if event.Type == watch.Error {
+				err := k8serrors.FromObject(event.Object)
+				log.Debugf("watch %s %s: %v", h.kind, name, err)
+				if k8serrors.IsResourceExpired(err) || k8serrors.IsGone(err) {
+					return "", false, nil
+				}
+				// back off, the server may keep failing the watch.
+				sleepRetry(ctx)
+				return resourceVersion, false, ctx.Err()
+			}
+			obj, ok := any(event.Object).(*T)
+			if !ok {
+				continue
+			}
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				continue
+			}
+			resourceVersion = accessor.GetResourceVersion()
+			if event.Type == watch.Bookmark || accessor.GetName() != name {
+				continue
+			}
+			if event.Type == watch.Deleted {
+				obj = nil
+			}
+			if done, err := check(obj); err != nil || done {
+				return resourceVersion, done, err
+			}
+		}
+	}
+}
+
+// Informer returns the shared informer of the resource kind.
+func (h *Handler[T, TList]) Informer() cache.SharedIndexInformer {
+	return h.informer()
+}
+
+// RunInformer
+// informer 的三个回调函数 addFunc, updateFunc, deleteFunc
+// the informer is shared with the cache of Factory.StartCache, it's started
+// by the informer factory, RunInformer blocks until stopCh is closed.
+func (h *Handler[T, TList]) RunInformer(
+	addFunc func(obj interface{}),
+	updateFunc func(oldObj, newObj interface{}),
+	deleteFunc func(obj interface{}),
+	stopCh chan struct{}) {
+	informer := h.informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    addFunc,
+		UpdateFunc: updateFunc,
+		DeleteFunc: deleteFunc,
+	})
+	h.factory.informerFactory.Start(stopCh)
+	<-stopCh
+}
@@ -0,0 +1,752 @@
+package k8s
+
+import (
+	"hybfkuf/pkg/k8s/apply"
+
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8stesting "k8s.io/client-go/testing"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+	"sigs.k8s.io/yaml"
+)
+
+const testNamespace = "test"
+
+// newTestFactory new a Factory backed by the fake clientsets of client-go,
+// the objects are preloaded into the fake kubernetes clientset.
+func newTestFactory(objects ...runtime.Object) *Factory {
+	clientset := fake.NewSimpleClientset(objects...)
+	fakeServerSideApply(clientset)
+	fakeScale(clientset.Tracker(), clientset.PrependReactor)
+	clientset.Resources = testAPIResources
+	// the fake dynamic client lists typed objects if the kind is in the
+	// scheme, which can't be converted from the unstructured objects.
+	listKinds := make(map[schema.GroupVersionResource]string)
+	for _, list := range testAPIResources {
+		gv, _ := schema.ParseGroupVersion(list.GroupVersion)
+		for _, resource := range list.APIResources {
+			listKinds[gv.WithResource(resource.Name)] = resource.Kind + "List"
+		}
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+	fakeDynamicPatch(dynamicClient)
+	fakeScale(dynamicClient.Tracker(), dynamicClient.PrependReactor)
+	return NewFactoryForClients(context.TODO(),
+		clientset,
+		dynamicClient,
+		metricsfake.NewSimpleClientset())
+}
+
+// testAPIResources is the discovery information of the fake clientset.
+var testAPIResources = []*metav1.APIResourceList{
+	{GroupVersion: "v1", APIResources: []metav1.APIResource{
+		{Name: "namespaces", Kind: "Namespace"},
+		{Name: "nodes", Kind: "Node"},
+		{Name: "persistentvolumes", Kind: "PersistentVolume"},
+		{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+		{Name: "secrets", Kind: "Secret", Namespaced: true},
+		{Name: "services", Kind: "Service", Namespaced: true},
+		{Name: "serviceaccounts", Kind: "ServiceAccount", Namespaced: true},
+		{Name: "pods", Kind: "Pod", Namespaced: true},
+		{Name: "persistentvolumeclaims", Kind: "PersistentVolumeClaim", Namespaced: true},
+	}},
+	{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{
+		{Name: "deployments", Kind: "Deployment", Namespaced: true},
+		{Name: "statefulsets", Kind: "StatefulSet", Namespaced: true},
+		{Name: "daemonsets", Kind: "DaemonSet", Namespaced: true},
+		{Name: "replicasets", Kind: "ReplicaSet", Namespaced: true},
+	}},
+	{GroupVersion: "batch/v1", APIResources: []metav1.APIResource{
+		{Name: "jobs", Kind: "Job", Namespaced: true},
+		{Name: "cronjobs", Kind: "CronJob", Namespaced: true},
+	}},
+	{GroupVersion: "rbac.authorization.k8s.io/v1", APIResources: []metav1.APIResource{
+		{Name: "clusterroles", Kind: "ClusterRole"},
+		{Name: "clusterrolebindings", Kind: "ClusterRoleBinding"},
+		{Name: "roles", Kind: "Role", Namespaced: true},
+		{Name: "rolebindings", Kind: "RoleBinding", Namespaced: true},
+	}},
+	{GroupVersion: "autoscaling/v2", APIResources: []metav1.APIResource{
+		{Name: "horizontalpodautoscalers", Kind: "HorizontalPodAutoscaler", Namespaced: true},
+	}},
+	{GroupVersion: "policy/v1", APIResources: []metav1.APIResource{
+		{Name: "poddisruptionbudgets", Kind: "PodDisruptionBudget", Namespaced: true},
+	}},
+	{GroupVersion: "apiextensions.k8s.io/v1", APIResources: []metav1.APIResource{
+		{Name: "customresourcedefinitions", Kind: "CustomResourceDefinition"},
+	}},
+	{GroupVersion: "example.com/v1", APIResources: []metav1.APIResource{
+		{Name: "foos", Kind: "Foo", Namespaced: true},
+	}},
+}
+
+// fakeDynamicPatch makes the fake dynamic client handle ApplyPatchType and
+// StrategicMergePatchType patches of unstructured objects, same as
+// fakeServerSideApply does for the fake clientset.
+func fakeDynamicPatch(dynamicClient *dynamicfake.FakeDynamicClient) {
+	dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		var (
+			tracker   = dynamicClient.Tracker()
+			gvr       = action.GetResource()
+			namespace = action.GetNamespace()
+			merged    []byte
+		)
+		live, err := tracker.Get(gvr, namespace, patch.GetName())
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return true, nil, err
+		}
+		switch patch.GetPatchType() {
+		case types.ApplyPatchType:
+			if err != nil {
+				obj := &unstructured.Unstructured{}
+				if err := obj.UnmarshalJSON(patch.GetPatch()); err != nil {
+					return true, nil, err
+				}
+				obj.SetNamespace(namespace)
+				return true, obj, tracker.Create(gvr, obj, namespace)
+			}
+			liveJson, _ := json.Marshal(live)
+			if merged, err = jsonpatch.MergePatch(liveJson, patch.GetPatch()); err != nil {
+				return true, nil, err
+			}
+		case types.StrategicMergePatchType:
+			if err != nil {
+				return true, nil, err
+			}
+			typed, err := scheme.Scheme.New(live.GetObjectKind().GroupVersionKind())
+			if err != nil {
+				return true, nil, err
+			}
+			liveJson, _ := json.Marshal(live)
+			if merged, err = strategicpatch.StrategicMergePatch(liveJson, patch.GetPatch(), typed); err != nil {
+				return true, nil, err
+			}
+		default:
+			return false, nil, nil
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(merged); err != nil {
+			return true, nil, err
+		}
+		return true, obj, tracker.Update(gvr, obj, namespace)
+	})
+}
+
+// fakeServerSideApply makes the fake clientset handle ApplyPatchType patches,
+// which the object tracker of client-go doesn't support. The patch is merged
+// into the live object as a json merge patch, the object is created if it
+// doesn't exist. There is no field ownership.
+func fakeServerSideApply(clientset *fake.Clientset) {
+	clientset.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		var (
+			tracker   = clientset.Tracker()
+			gvr       = action.GetResource()
+			namespace = action.GetNamespace()
+			decode    = scheme.Codecs.UniversalDeserializer().Decode
+		)
+		live, err := tracker.Get(gvr, namespace, patch.GetName())
+		if k8serrors.IsNotFound(err) {
+			obj, _, err := decode(patch.GetPatch(), nil, nil)
+			if err != nil {
+				return true, nil, err
+			}
+			accessor, _ := meta.Accessor(obj)
+			accessor.SetNamespace(namespace)
+			return true, obj, tracker.Create(gvr, obj, namespace)
+		}
+		if err != nil {
+			return true, nil, err
+		}
+		liveJson, err := json.Marshal(live)
+		if err != nil {
+			return true, nil, err
+		}
+		mergedJson, err := jsonpatch.MergePatch(liveJson, patch.GetPatch())
+		if err != nil {
+			return true, nil, err
+		}
+		obj, _, err := decode(mergedJson, nil, nil)
+		if err != nil {
+			return true, nil, err
+		}
+		return true, obj, tracker.Update(gvr, obj, namespace)
+	})
+}
+
+// fakeScale makes the fake clients handle the scale subresource, the object
+// tracker returns and updates the object itself instead of the scale. The
+// scale is read from and written to spec.replicas and status.replicas.
+func fakeScale(tracker k8stesting.ObjectTracker, prependReactor func(verb, resource string, reaction k8stesting.ReactionFunc)) {
+	scaleOf := func(obj runtime.Object) (*autoscalingv1.Scale, map[string]interface{}, error) {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, nil, err
+		}
+		u := &unstructured.Unstructured{Object: content}
+		replicas, _, _ := unstructured.NestedInt64(content, "spec", "replicas")
+		statusReplicas, _, _ := unstructured.NestedInt64(content, "status", "replicas")
+		return &autoscalingv1.Scale{
+			ObjectMeta: metav1.ObjectMeta{Name: u.GetName(), Namespace: u.GetNamespace(), ResourceVersion: u.GetResourceVersion()},
+			Spec:       autoscalingv1.ScaleSpec{Replicas: int32(replicas)},
+			Status:     autoscalingv1.ScaleStatus{Replicas: int32(statusReplicas)},
+		}, content, nil
+	}
+	prependReactor("get", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		obj, err := tracker.Get(action.GetResource(), action.GetNamespace(), action.(k8stesting.GetAction).GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		scale, _, err := scaleOf(obj)
+		if _, ok := obj.(*unstructured.Unstructured); !ok || err != nil {
+			return true, scale, err
+		}
+		// the fake dynamic client returns unstructured objects.
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(scale)
+		u := &unstructured.Unstructured{Object: content}
+		u.SetGroupVersionKind(autoscalingv1.SchemeGroupVersion.WithKind("Scale"))
+		return true, u, err
+	})
+	prependReactor("update", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		scale := &autoscalingv1.Scale{}
+		update := action.(k8stesting.UpdateAction).GetObject()
+		if u, ok := update.(*unstructured.Unstructured); ok {
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, scale); err != nil {
+				return true, nil, err
+			}
+		} else {
+			scale = update.(*autoscalingv1.Scale)
+		}
+		obj, err := tracker.Get(action.GetResource(), action.GetNamespace(), scale.Name)
+		if err != nil {
+			return true, nil, err
+		}
+		_, content, err := scaleOf(obj)
+		if err != nil {
+			return true, nil, err
+		}
+		if err := unstructured.SetNestedField(content, int64(scale.Spec.Replicas), "spec", "replicas"); err != nil {
+			return true, nil, err
+		}
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			u.SetUnstructuredContent(content)
+		} else if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj); err != nil {
+			return true, nil, err
+		}
+		if err := tracker.Update(action.GetResource(), obj, action.GetNamespace()); err != nil {
+			return true, nil, err
+		}
+		return true, update, nil
+	})
+}
+
+// testManifest returns a minimal yaml manifest labeled with "app=test".
+func testManifest(apiVersion, kind, name string) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: %s
+kind: %s
+metadata:
+  name: %s
+  labels:
+    app: test
+`, apiVersion, kind, name))
+}
+
+func listLen(t *testing.T, list any) int {
+	t.Helper()
+	items, err := meta.ExtractList(list.(runtime.Object))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(items)
+}
+
+// testHandler runs the create, get, list, update, apply and delete methods of
+// the handler against the manifest.
+func testHandler[T, TList any](t *testing.T, h HandlerInterface[T, TList], manifest []byte) {
+	var (
+		obj  *T
+		list *TList
+		err  error
+	)
+	accessor := func(obj *T) metav1.Object {
+		a, err := meta.Accessor(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+
+	// create
+	if obj, err = h.CreateFromBytes(manifest); err != nil {
+		t.Fatalf("CreateFromBytes: %v", err)
+	}
+	name := accessor(obj).GetName()
+	if accessor(obj).GetNamespace() != h.Namespace() {
+		t.Errorf("namespace = %q, want %q", accessor(obj).GetNamespace(), h.Namespace())
+	}
+	if _, err = h.CreateFromBytes(manifest); !k8serrors.IsAlreadyExists(err) {
+		t.Errorf("CreateFromBytes twice: got %v, want AlreadyExists", err)
+	}
+
+	// get
+	if obj, err = h.Get(name); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if accessor(obj).GetName() != name {
+		t.Errorf("Get name = %q, want %q", accessor(obj).GetName(), name)
+	}
+	if _, err = h.GetFromBytes(manifest); err != nil {
+		t.Errorf("GetFromBytes: %v", err)
+	}
+
+	// list
+	if list, err = h.List("app=test"); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if n := listLen(t, list); n != 1 {
+		t.Errorf("List(app=test) returns %d items, want 1", n)
+	}
+	if list, err = h.ListByLabel("app=other"); err != nil {
+		t.Fatalf("ListByLabel: %v", err)
+	}
+	if n := listLen(t, list); n != 0 {
+		t.Errorf("ListByLabel(app=other) returns %d items, want 0", n)
+	}
+	if list, err = h.ListAll(); err != nil {
+		t.Fatalf("ListAll: %v", err)
+	}
+	if n := listLen(t, list); n != 1 {
+		t.Errorf("ListAll returns %d items, want 1", n)
+	}
+
+	// update and apply
+	raw := map[string]interface{}{}
+	if err = yaml.Unmarshal(manifest, &raw); err != nil {
+		t.Fatal(err)
+	}
+	raw["metadata"].(map[string]interface{})["labels"] = map[string]interface{}{"app": "test", "version": "v2"}
+	if obj, err = h.UpdateFromRaw(raw); err != nil {
+		t.Fatalf("UpdateFromRaw: %v", err)
+	}
+	if accessor(obj).GetLabels()["version"] != "v2" {
+		t.Errorf("UpdateFromRaw labels = %v", accessor(obj).GetLabels())
+	}
+	// server-side apply keeps the fields not in the manifest
+	if obj, err = h.ApplyFromBytes(manifest); err != nil {
+		t.Fatalf("ApplyFromBytes: %v", err)
+	}
+	if accessor(obj).GetLabels()["version"] != "v2" {
+		t.Errorf("ApplyFromBytes labels = %v", accessor(obj).GetLabels())
+	}
+	// create-or-update replaces the whole object
+	h.SetApplyMode(ApplyModeCreateOrUpdate)
+	if obj, err = h.ApplyFromBytes(manifest); err != nil {
+		t.Fatalf("ApplyFromBytes: %v", err)
+	}
+	if _, ok := accessor(obj).GetLabels()["version"]; ok {
+		t.Errorf("ApplyFromBytes labels = %v", accessor(obj).GetLabels())
+	}
+	h.SetApplyMode(ApplyModeServerSide)
+
+	// delete
+	if err = h.DeleteFromBytes(manifest); err != nil {
+		t.Fatalf("DeleteFromBytes: %v", err)
+	}
+	if _, err = h.Get(name); !k8serrors.IsNotFound(err) {
+		t.Errorf("Get after delete: got %v, want NotFound", err)
+	}
+
+	// apply creates the object if it doesn't exist
+	if _, err = h.ApplyFromRaw(raw); err != nil {
+		t.Fatalf("ApplyFromRaw: %v", err)
+	}
+	if err = h.Delete(name); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	// file
+	path := filepath.Join(t.TempDir(), name+".yaml")
+	if err = os.WriteFile(path, manifest, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = h.Create(path); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err = h.GetFromFile(path); err != nil {
+		t.Errorf("GetFromFile: %v", err)
+	}
+	if _, err = h.Apply(path); err != nil {
+		t.Errorf("Apply: %v", err)
+	}
+	if err = h.DeleteFromFile(path); err != nil {
+		t.Errorf("DeleteFromFile: %v", err)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	f := newTestFactory()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{"pod", func(t *testing.T) {
+			testHandler[corev1.Pod, corev1.PodList](t, f.Pods(testNamespace), testManifest("v1", "Pod", "nginx"))
+		}},
+		{"deployment", func(t *testing.T) {
+			testHandler[appsv1.Deployment, appsv1.DeploymentList](t, f.Deployments(testNamespace), testManifest("apps/v1", "Deployment", "nginx"))
+		}},
+		{"daemonset", func(t *testing.T) {
+			testHandler[appsv1.DaemonSet, appsv1.DaemonSetList](t, f.DaemonSets(testNamespace), testManifest("apps/v1", "DaemonSet", "nginx"))
+		}},
+		{"statefulset", func(t *testing.T) {
+			testHandler[appsv1.StatefulSet, appsv1.StatefulSetList](t, f.StatefulSets(testNamespace), testManifest("apps/v1", "StatefulSet", "nginx"))
+		}},
+		{"replicaset", func(t *testing.T) {
+			testHandler[appsv1.ReplicaSet, appsv1.ReplicaSetList](t, f.ReplicaSets(testNamespace), testManifest("apps/v1", "ReplicaSet", "nginx"))
+		}},
+		{"replicationcontroller", func(t *testing.T) {
+			testHandler[corev1.ReplicationController, corev1.ReplicationControllerList](t, f.ReplicationControllers(testNamespace), testManifest("v1", "ReplicationController", "nginx"))
+		}},
+		{"job", func(t *testing.T) {
+			testHandler[batchv1.Job, batchv1.JobList](t, f.Jobs(testNamespace), testManifest("batch/v1", "Job", "pi"))
+		}},
+		{"cronjob", func(t *testing.T) {
+			testHandler[batchv1.CronJob, batchv1.CronJobList](t, f.CronJobs(testNamespace), testManifest("batch/v1", "CronJob", "pi"))
+		}},
+		{"service", func(t *testing.T) {
+			testHandler[corev1.Service, corev1.ServiceList](t, f.Services(testNamespace), testManifest("v1", "Service", "nginx"))
+		}},
+		{"configmap", func(t *testing.T) {
+			testHandler[corev1.ConfigMap, corev1.ConfigMapList](t, f.ConfigMaps(testNamespace), testManifest("v1", "ConfigMap", "nginx"))
+		}},
+		{"secret", func(t *testing.T) {
+			testHandler[corev1.Secret, corev1.SecretList](t, f.Secrets(testNamespace), testManifest("v1", "Secret", "nginx"))
+		}},
+		{"serviceaccount", func(t *testing.T) {
+			testHandler[corev1.ServiceAccount, corev1.ServiceAccountList](t, f.ServiceAccounts(testNamespace), testManifest("v1", "ServiceAccount", "nginx"))
+		}},
+		{"persistentvolumeclaim", func(t *testing.T) {
+			testHandler[corev1.PersistentVolumeClaim, corev1.PersistentVolumeClaimList](t, f.PersistentVolumeClaims(testNamespace), testManifest("v1", "PersistentVolumeClaim", "data"))
+		}},
+		{"ingress", func(t *testing.T) {
+			testHandler[networkingv1.Ingress, networkingv1.IngressList](t, f.Ingresses(testNamespace), testManifest("networking.k8s.io/v1", "Ingress", "nginx"))
+		}},
+		{"networkpolicy", func(t *testing.T) {
+			testHandler[networkingv1.NetworkPolicy, networkingv1.NetworkPolicyList](t, f.NetworkPolicies(testNamespace), testManifest("networking.k8s.io/v1", "NetworkPolicy", "deny-all"))
+		}},
+		{"role", func(t *testing.T) {
+			testHandler[rbacv1.Role, rbacv1.RoleList](t, f.Roles(testNamespace), testManifest("rbac.authorization.k8s.io/v1", "Role", "reader"))
+		}},
+		{"rolebinding", func(t *testing.T) {
+			testHandler[rbacv1.RoleBinding, rbacv1.RoleBindingList](t, f.RoleBindings(testNamespace), testManifest("rbac.authorization.k8s.io/v1", "RoleBinding", "reader"))
+		}},
+		{"namespace", func(t *testing.T) {
+			testHandler[corev1.Namespace, corev1.NamespaceList](t, f.Namespaces(), testManifest("v1", "Namespace", "dev"))
+		}},
+		{"node", func(t *testing.T) {
+			testHandler[corev1.Node, corev1.NodeList](t, f.Nodes(), testManifest("v1", "Node", "node1"))
+		}},
+		{"persistentvolume", func(t *testing.T) {
+			testHandler[corev1.PersistentVolume, corev1.PersistentVolumeList](t, f.PersistentVolumes(), testManifest("v1", "PersistentVolume", "pv1"))
+		}},
+		{"ingressclass", func(t *testing.T) {
+			testHandler[networkingv1.IngressClass, networkingv1.IngressClassList](t, f.IngressClasses(), testManifest("networking.k8s.io/v1", "IngressClass", "nginx"))
+		}},
+		{"clusterrole", func(t *testing.T) {
+			testHandler[rbacv1.ClusterRole, rbacv1.ClusterRoleList](t, f.ClusterRoles(), testManifest("rbac.authorization.k8s.io/v1", "ClusterRole", "reader"))
+		}},
+		{"clusterrolebinding", func(t *testing.T) {
+			testHandler[rbacv1.ClusterRoleBinding, rbacv1.ClusterRoleBindingList](t, f.ClusterRoleBindings(), testManifest("rbac.authorization.k8s.io/v1", "ClusterRoleBinding", "reader"))
+		}},
+		{"storageclass", func(t *testing.T) {
+			testHandler[storagev1.StorageClass, storagev1.StorageClassList](t, f.StorageClasses(), testManifest("storage.k8s.io/v1", "StorageClass", "local"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, tt.run)
+	}
+}
+
+func TestHandlerWithNamespace(t *testing.T) {
+	f := newTestFactory()
+	pod := f.Pods("")
+	if pod.Namespace() != "default" {
+		t.Errorf("Namespace() = %q, want %q", pod.Namespace(), "default")
+	}
+	if _, err := pod.WithNamespace(testNamespace).CreateFromBytes(testManifest("v1", "Pod", "nginx")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pod.Get("nginx"); !k8serrors.IsNotFound(err) {
+		t.Errorf("Get in default namespace: got %v, want NotFound", err)
+	}
+	list, err := pod.ListByNamespace(testNamespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 {
+		t.Errorf("ListByNamespace returns %d items, want 1", len(list.Items))
+	}
+
+	// cluster scope handlers ignore the namespace
+	if ns := f.Nodes().WithDryRun().Namespace(); ns != "" {
+		t.Errorf("node Namespace() = %q, want empty", ns)
+	}
+}
+
+func TestHandlerOptions(t *testing.T) {
+	f := newTestFactory()
+	job := f.Jobs(testNamespace)
+	if p := job.Options.DeleteOptions.PropagationPolicy; p == nil || *p != "Background" {
+		t.Fatalf("job propagation policy = %v, want Background", p)
+	}
+	job.SetForceDelete(true)
+	// the options are kept by the copies of the handler
+	copied := job.WithNamespace("default")
+	if g := copied.Options.DeleteOptions.GracePeriodSeconds; g == nil || *g != 0 {
+		t.Errorf("GracePeriodSeconds = %v, want 0", g)
+	}
+	if p := copied.Options.DeleteOptions.PropagationPolicy; p == nil || *p != "Background" {
+		t.Errorf("propagation policy = %v, want Background", p)
+	}
+	copied.SetForceDelete(false)
+	if job.Options.DeleteOptions.GracePeriodSeconds == nil {
+		t.Errorf("options of the origin handler changed by the copy")
+	}
+	if dryRun := copied.WithDryRun().Options.CreateOptions.DryRun; len(dryRun) != 1 {
+		t.Errorf("DryRun = %v", dryRun)
+	}
+}
+
+func TestHandlerServerSideApply(t *testing.T) {
+	f := newTestFactory()
+	cm := f.ConfigMaps(testNamespace)
+	if _, err := cm.ApplyFromBytes(testManifest("v1", "ConfigMap", "nginx")); err != nil {
+		t.Fatal(err)
+	}
+	var patches []k8stesting.PatchAction
+	for _, action := range f.clientset.(*fake.Clientset).Actions() {
+		if patch, ok := action.(k8stesting.PatchAction); ok {
+			patches = append(patches, patch)
+		}
+	}
+	if len(patches) != 1 || patches[0].GetPatchType() != types.ApplyPatchType {
+		t.Fatalf("apply sends %v, want one ApplyPatchType patch", patches)
+	}
+	// the manifest is sent as is, no empty fields of the typed object
+	if strings.Contains(string(patches[0].GetPatch()), "creationTimestamp") {
+		t.Errorf("apply patch = %s", patches[0].GetPatch())
+	}
+
+	opts := cm.applyPatchOptions()
+	if opts.FieldManager != FieldManager || opts.Force == nil || *opts.Force {
+		t.Errorf("default apply options = %+v", opts)
+	}
+	cm.SetFieldManager("my-controller")
+	cm.SetForceConflicts(true)
+	opts = cm.WithDryRun().applyPatchOptions()
+	if opts.FieldManager != "my-controller" || opts.Force == nil || !*opts.Force || len(opts.DryRun) != 1 {
+		t.Errorf("apply options = %+v", opts)
+	}
+}
+
+func TestHandlerClientSideApply(t *testing.T) {
+	f := newTestFactory()
+	deploy := f.Deployments(testNamespace)
+	deploy.SetApplyMode(ApplyModeClientSide)
+	manifest := func(labels, containers string) []byte {
+		return []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  labels:` + labels + `
+spec:
+  selector:
+    matchLabels:
+      app: nginx
+  template:
+    metadata:
+      labels:
+        app: nginx
+    spec:
+      containers:` + containers)
+	}
+
+	applied, err := deploy.ApplyFromBytes(manifest(`
+    app: nginx
+    tier: web`, `
+      - name: nginx
+        image: nginx:1.21
+      - name: sidecar
+        image: busybox`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := applied.Annotations[apply.LastAppliedConfigAnnotation]; !ok {
+		t.Fatalf("annotations = %v, want the last-applied-configuration", applied.Annotations)
+	}
+
+	// fields set by others are kept
+	applied.Labels["owner"] = "ops"
+	if _, err := f.clientset.AppsV1().Deployments(testNamespace).Update(context.TODO(), applied, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	v2 := manifest(`
+    app: nginx`, `
+      - name: nginx
+        image: nginx:1.22`)
+	applied, err = deploy.ApplyFromBytes(v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := applied.Labels["tier"]; ok || applied.Labels["owner"] != "ops" {
+		t.Errorf("labels = %v, want tier removed and owner kept", applied.Labels)
+	}
+	containers := applied.Spec.Template.Spec.Containers
+	if len(containers) != 1 || containers[0].Image != "nginx:1.22" {
+		t.Errorf("containers = %v, want only nginx:1.22", containers)
+	}
+
+	// nothing changed, no patch is sent
+	clientset := f.clientset.(*fake.Clientset)
+	clientset.ClearActions()
+	if _, err := deploy.ApplyFromBytes(v2); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() != "get" {
+			t.Errorf("unexpected action %v", action)
+		}
+	}
+}
+
+func TestHandlerDiff(t *testing.T) {
+	f := newTestFactory()
+	cm := f.ConfigMaps(testNamespace)
+	manifest := func(value string) []byte {
+		return []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: nginx
+data:
+  key: ` + value)
+	}
+
+	diff, err := cm.DiffFromBytes(manifest("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(diff, "--- live/configmap/nginx\n+++ merged/configmap/nginx\n@@ -0,0 +1,") {
+		t.Errorf("diff of a new object =\n%s", diff)
+	}
+
+	if _, err := cm.ApplyFromBytes(manifest("v1")); err != nil {
+		t.Fatal(err)
+	}
+	if diff, err = cm.DiffFromBytes(manifest("v1")); err != nil || len(diff) != 0 {
+		t.Errorf("diff of the applied object = %q, %v", diff, err)
+	}
+	if diff, err = cm.DiffFromBytes(manifest("v2")); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "\n-  key: v1\n+  key: v2\n") {
+		t.Errorf("diff =\n%s", diff)
+	}
+}
+
+func TestHandlerKustomization(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"kustomization.yaml": "resources:\n- deployment.yaml\n- configmap.yaml\nnamePrefix: dev-\n",
+		"deployment.yaml":    string(testManifest("apps/v1", "Deployment", "nginx")),
+		"configmap.yaml":     string(testManifest("v1", "ConfigMap", "nginx")),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := newTestFactory()
+	for _, path := range []string{dir, filepath.Join(dir, "kustomization.yaml")} {
+		deploy, err := f.Deployments(testNamespace).Apply(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deploy.Name != "dev-nginx" {
+			t.Errorf("name = %s, want dev-nginx", deploy.Name)
+		}
+	}
+	if _, err := f.Services(testNamespace).Apply(dir); err == nil {
+		t.Error("apply a service from a kustomization without service succeeded")
+	}
+}
+
+func TestHandlerRender(t *testing.T) {
+	f := newTestFactory()
+	f.SetRender(apply.Env(func(key string) (string, bool) {
+		return map[string]string{"NAME": "nginx", "VALUE": "v1"}[key], key != "MISSING"
+	}))
+	var output strings.Builder
+	cm := f.ConfigMaps(testNamespace)
+	cm.SetRenderOutput(&output)
+
+	applied, err := cm.ApplyFromBytes([]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: ${NAME}\ndata:\n  key: ${VALUE}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied.Name != "nginx" || applied.Data["key"] != "v1" {
+		t.Errorf("applied %s with data %v", applied.Name, applied.Data)
+	}
+	if !strings.Contains(output.String(), "name: nginx\n") {
+		t.Errorf("output =\n%s", output.String())
+	}
+	if _, err := cm.CreateFromBytes([]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: ${MISSING}\n")); err == nil {
+		t.Error("create with a missing variable succeeded")
+	}
+
+	// ApplyF renders with the render of the factory too.
+	path := writeTestManifest(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: ${NAME}-f\n  namespace: test\n")
+	if _, err := f.ApplyF(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getTestObject(f, testConfigMapGVR, testNamespace, "nginx-f"); err != nil {
+		t.Error(err)
+	}
+}
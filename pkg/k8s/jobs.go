@@ -2,30 +2,12 @@ package k8s
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"strings"
-	"sync"
-	"time"
 
-	log "github.com/sirupsen/logrus"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
-	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/util/yaml"
-	"k8s.io/apimachinery/pkg/watch"
-	"k8s.io/client-go/discovery"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/informers"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 type JobController struct {
@@ -37,450 +19,41 @@ type JobController struct {
 	metav1.OwnerReference `json:"ownerReference"`
 }
 type Job struct {
-	kubeconfig string
-	namespace  string
-
-	ctx             context.Context
-	config          *rest.Config
-	restClient      *rest.RESTClient
-	clientset       *kubernetes.Clientset
-	dynamicClient   dynamic.Interface
-	discoveryClient *discovery.DiscoveryClient
-	informerFactory informers.SharedInformerFactory
-	informer        cache.SharedIndexInformer
+	*Handler[batchv1.Job, batchv1.JobList]
+}
 
-	Options *HandlerOptions
+var _ HandlerInterface[batchv1.Job, batchv1.JobList] = (*Job)(nil)
 
-	sync.Mutex
+// Jobs returns a Job handler working in the given namespace, the handler
+// shares the clients of the factory.
+func (f *Factory) Jobs(namespace string) *Job {
+	j := &Job{newHandler(f, ResourceKindJob, true, namespace,
+		func(namespace string) resourceClient[batchv1.Job, batchv1.JobList] {
+			return f.clientset.BatchV1().Jobs(namespace)
+		},
+		f.informerFactory.Batch().V1().Jobs().Informer)}
+	// the api server orphans the dependents of job by default,
+	// delete them in the background.
+	j.SetPropagationPolicy("background")
+	return j
 }
 
 // new a job handler from kubeconfig or in-cluster config
-func NewJob(ctx context.Context, namespace, kubeconfig string) (job *Job, err error) {
-	var (
-		config          *rest.Config
-		restClient      *rest.RESTClient
-		clientset       *kubernetes.Clientset
-		dynamicClient   dynamic.Interface
-		discoveryClient *discovery.DiscoveryClient
-		informerFactory informers.SharedInformerFactory
-	)
-	job = &Job{}
-
-	// create rest config
-	if len(kubeconfig) != 0 {
-		// use the current context in kubeconfig
-		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
-		if err != nil {
-			return
-		}
-	} else {
-		// create the in-cluster config
-		config, err = rest.InClusterConfig()
-		if err != nil {
-			return
-		}
-	}
-
-	// setup APIPath, GroupVersion and NegotiatedSerializer before initializing a RESTClient
-	config.APIPath = "api"
-	config.GroupVersion = &batchv1.SchemeGroupVersion
-	config.NegotiatedSerializer = scheme.Codecs
-
-	// create a RESTClient for the given config
-	restClient, err = rest.RESTClientFor(config)
-	if err != nil {
-		return
-	}
-	// create a Clientset for the given config
-	clientset, err = kubernetes.NewForConfig(config)
-	if err != nil {
-		return
-	}
-	// create a dynamic client for the given config
-	dynamicClient, err = dynamic.NewForConfig(config)
+func NewJob(ctx context.Context, namespace, kubeconfig string) (*Job, error) {
+	factory, err := NewFactory(ctx, kubeconfig)
 	if err != nil {
-		return
-	}
-	// create a DiscoveryClient for the given config
-	discoveryClient, err = discovery.NewDiscoveryClientForConfig(config)
-	if err != nil {
-		return
-	}
-	// create a sharedInformerFactory for all namespaces.
-	informerFactory = informers.NewSharedInformerFactory(clientset, time.Minute)
-
-	if len(namespace) == 0 {
-		namespace = metav1.NamespaceDefault
+		return nil, err
 	}
-	job.kubeconfig = kubeconfig
-	job.namespace = namespace
-	job.ctx = ctx
-	job.config = config
-	job.restClient = restClient
-	job.clientset = clientset
-	job.dynamicClient = dynamicClient
-	job.discoveryClient = discoveryClient
-	job.informerFactory = informerFactory
-	job.informer = informerFactory.Batch().V1().Jobs().Informer()
-	job.Options = &HandlerOptions{}
-
-	return
-}
-func (j *Job) Namespace() string {
-	return j.namespace
+	return factory.Jobs(namespace), nil
 }
 func (in *Job) DeepCopy() *Job {
-	out := new(Job)
-
-	out.kubeconfig = in.kubeconfig
-	out.namespace = in.namespace
-
-	out.ctx = in.ctx
-	out.config = in.config
-	out.restClient = in.restClient
-	out.clientset = in.clientset
-	out.dynamicClient = in.dynamicClient
-	out.discoveryClient = in.discoveryClient
-	out.informerFactory = in.informerFactory
-	out.informer = in.informer
-
-	out.Options = &HandlerOptions{}
-	out.Options.ListOptions = *in.Options.ListOptions.DeepCopy()
-	out.Options.GetOptions = *in.Options.GetOptions.DeepCopy()
-	out.Options.CreateOptions = *in.Options.CreateOptions.DeepCopy()
-	out.Options.UpdateOptions = *in.Options.UpdateOptions.DeepCopy()
-	out.Options.PatchOptions = *in.Options.PatchOptions.DeepCopy()
-	out.Options.ApplyOptions = *in.Options.ApplyOptions.DeepCopy()
-	out.SetPropagationPolicy("background")
-
-	return out
-}
-func (j *Job) setNamespace(namespace string) {
-	j.Lock()
-	defer j.Unlock()
-	j.namespace = namespace
+	return &Job{in.Handler.DeepCopy()}
 }
 func (j *Job) WithNamespace(namespace string) *Job {
-	job := j.DeepCopy()
-	job.setNamespace(namespace)
-	return job
+	return &Job{j.Handler.WithNamespace(namespace)}
 }
 func (j *Job) WithDryRun() *Job {
-	job := j.DeepCopy()
-	job.Options.CreateOptions.DryRun = []string{metav1.DryRunAll}
-	job.Options.UpdateOptions.DryRun = []string{metav1.DryRunAll}
-	job.Options.DeleteOptions.DryRun = []string{metav1.DryRunAll}
-	job.Options.PatchOptions.DryRun = []string{metav1.DryRunAll}
-	job.Options.ApplyOptions.DryRun = []string{metav1.DryRunAll}
-	job.SetPropagationPolicy("background")
-	return job
-}
-func (j *Job) SetTimeout(timeout int64) {
-	j.Lock()
-	defer j.Unlock()
-	j.Options.ListOptions.TimeoutSeconds = &timeout
-}
-func (j *Job) SetLimit(limit int64) {
-	j.Lock()
-	defer j.Unlock()
-	j.Options.ListOptions.Limit = limit
-}
-func (j *Job) SetForceDelete(force bool) {
-	j.Lock()
-	defer j.Unlock()
-	if force {
-		gracePeriodSeconds := int64(0)
-		j.Options.DeleteOptions.GracePeriodSeconds = &gracePeriodSeconds
-		propagationPolicy := metav1.DeletePropagationBackground
-		j.Options.DeleteOptions.PropagationPolicy = &propagationPolicy
-	} else {
-		j.Options.DeleteOptions = metav1.DeleteOptions{}
-		propagationPolicy := metav1.DeletePropagationBackground
-		j.Options.DeleteOptions.PropagationPolicy = &propagationPolicy
-	}
-}
-
-// Whether and how garbage collection will be performed.
-// support value are "Background", "Orphan", "Foreground",
-// default value is "Background"
-func (j *Job) SetPropagationPolicy(policy string) {
-	j.Lock()
-	defer j.Unlock()
-	switch strings.ToLower(policy) {
-	case strings.ToLower(string(metav1.DeletePropagationBackground)):
-		propagationPolicy := metav1.DeletePropagationBackground
-		j.Options.DeleteOptions.PropagationPolicy = &propagationPolicy
-	case strings.ToLower(string(metav1.DeletePropagationForeground)):
-		propagationPolicy := metav1.DeletePropagationForeground
-		j.Options.DeleteOptions.PropagationPolicy = &propagationPolicy
-	case strings.ToLower(string(metav1.DeletePropagationOrphan)):
-		propagationPolicy := metav1.DeletePropagationOrphan
-		j.Options.DeleteOptions.PropagationPolicy = &propagationPolicy
-	default:
-		propagationPolicy := metav1.DeletePropagationBackground
-		j.Options.DeleteOptions.PropagationPolicy = &propagationPolicy
-	}
-}
-
-// CreateFromRaw create job from map[string]interface{}
-func (j *Job) CreateFromRaw(raw map[string]interface{}) (*batchv1.Job, error) {
-	job := &batchv1.Job{}
-	err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, job)
-	if err != nil {
-		return nil, err
-	}
-
-	var namespace string
-	if len(job.Namespace) != 0 {
-		namespace = job.Namespace
-	} else {
-		namespace = j.namespace
-	}
-
-	return j.clientset.BatchV1().Jobs(namespace).Create(j.ctx, job, j.Options.CreateOptions)
-}
-
-// CreateFromBytes create job from bytes
-func (j *Job) CreateFromBytes(data []byte) (*batchv1.Job, error) {
-	jobJson, err := yaml.ToJSON(data)
-	if err != nil {
-		return nil, err
-	}
-
-	job := &batchv1.Job{}
-	err = json.Unmarshal(jobJson, job)
-	if err != nil {
-		return nil, err
-	}
-
-	var namespace string
-	if len(job.Namespace) != 0 {
-		namespace = job.Namespace
-	} else {
-		namespace = j.namespace
-	}
-
-	return j.clientset.BatchV1().Jobs(namespace).Create(j.ctx, job, j.Options.CreateOptions)
-}
-
-// CreateFromFile create job from yaml file
-func (j *Job) CreateFromFile(path string) (*batchv1.Job, error) {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	return j.CreateFromBytes(data)
-}
-
-// Create create job from yaml file, alias to "CreateFromFile"
-func (j *Job) Create(path string) (*batchv1.Job, error) {
-	return j.CreateFromFile(path)
-}
-
-// UpdateFromRaw update job from map[string]interface{}
-func (j *Job) UpdateFromRaw(raw map[string]interface{}) (*batchv1.Job, error) {
-	job := &batchv1.Job{}
-	err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, job)
-	if err != nil {
-		return nil, err
-	}
-
-	var namespace string
-	if len(job.Namespace) != 0 {
-		namespace = job.Namespace
-	} else {
-		namespace = j.namespace
-	}
-
-	return j.clientset.BatchV1().Jobs(namespace).Update(j.ctx, job, j.Options.UpdateOptions)
-}
-
-// UpdateFromBytes update job from bytes
-func (j *Job) UpdateFromBytes(data []byte) (*batchv1.Job, error) {
-	jobJson, err := yaml.ToJSON(data)
-	if err != nil {
-		return nil, err
-	}
-
-	job := &batchv1.Job{}
-	err = json.Unmarshal(jobJson, job)
-	if err != nil {
-		return nil, err
-	}
-
-	var namespace string
-	if len(job.Namespace) != 0 {
-		namespace = job.Namespace
-	} else {
-		namespace = j.namespace
-	}
-
-	return j.clientset.BatchV1().Jobs(namespace).Update(j.ctx, job, j.Options.UpdateOptions)
-}
-
-// UpdateFromFile update job from yaml file
-func (j *Job) UpdateFromFile(path string) (*batchv1.Job, error) {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	return j.UpdateFromBytes(data)
-}
-
-// Update update job from yaml file, alias to "UpdateFromFile"
-func (j *Job) Update(path string) (*batchv1.Job, error) {
-	return j.UpdateFromFile(path)
-}
-
-// ApplyFromRaw apply job from map[string]interface{}
-func (j *Job) ApplyFromRaw(raw map[string]interface{}) (*batchv1.Job, error) {
-	job := &batchv1.Job{}
-	err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, job)
-	if err != nil {
-		return nil, err
-	}
-
-	var namespace string
-	if len(job.Namespace) != 0 {
-		namespace = job.Namespace
-	} else {
-		namespace = j.namespace
-	}
-
-	job, err = j.clientset.BatchV1().Jobs(namespace).Create(j.ctx, job, j.Options.CreateOptions)
-	if k8serrors.IsAlreadyExists(err) {
-		job, err = j.clientset.BatchV1().Jobs(namespace).Update(j.ctx, job, j.Options.UpdateOptions)
-	}
-	return job, err
-}
-
-// ApplyFromBytes apply job from bytes
-func (j *Job) ApplyFromBytes(data []byte) (job *batchv1.Job, err error) {
-	job, err = j.CreateFromBytes(data)
-	if errors.IsAlreadyExists(err) {
-		job, err = j.UpdateFromBytes(data)
-	}
-	return
-}
-
-// ApplyFromFile apply job from yaml file
-func (j *Job) ApplyFromFile(path string) (job *batchv1.Job, err error) {
-	job, err = j.CreateFromFile(path)
-	if errors.IsAlreadyExists(err) {
-		job, err = j.UpdateFromFile(path)
-	}
-	return
-}
-
-// Apply apply job from yaml file, alias to "ApplyFromFile"
-func (j *Job) Apply(path string) (*batchv1.Job, error) {
-	return j.ApplyFromFile(path)
-}
-
-// DeleteFromBytes delete job from bytes
-func (j *Job) DeleteFromBytes(data []byte) error {
-	jobJson, err := yaml.ToJSON(data)
-	if err != nil {
-		return err
-	}
-
-	job := &batchv1.Job{}
-	err = json.Unmarshal(jobJson, job)
-	if err != nil {
-		return err
-	}
-
-	var namespace string
-	if len(job.Namespace) != 0 {
-		namespace = job.Namespace
-	} else {
-		namespace = j.namespace
-	}
-
-	return j.WithNamespace(namespace).DeleteByName(job.Name)
-}
-
-// DeleteFromFile delete job from yaml file
-func (j *Job) DeleteFromFile(path string) error {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return err
-	}
-	return j.DeleteFromBytes(data)
-}
-
-// DeleteByName delete job by name
-func (j *Job) DeleteByName(name string) error {
-	return j.clientset.BatchV1().Jobs(j.namespace).Delete(j.ctx, name, j.Options.DeleteOptions)
-}
-
-// Delete delete job by name,alias to "DeleteByName"
-func (j *Job) Delete(name string) error {
-	return j.DeleteByName(name)
-}
-
-// GetFromBytes get job from bytes
-func (j *Job) GetFromBytes(data []byte) (*batchv1.Job, error) {
-	jobJson, err := yaml.ToJSON(data)
-	if err != nil {
-		return nil, err
-	}
-
-	job := &batchv1.Job{}
-	err = json.Unmarshal(jobJson, job)
-	if err != nil {
-		return nil, err
-	}
-
-	var namespace string
-	if len(job.Namespace) != 0 {
-		namespace = job.Namespace
-	} else {
-		namespace = j.namespace
-	}
-
-	return j.WithNamespace(namespace).GetByName(job.Name)
-}
-
-// GetFromFile get job from yaml file
-func (j *Job) GetFromFile(path string) (*batchv1.Job, error) {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	return j.GetFromBytes(data)
-}
-
-// GetByName get job by name
-func (j *Job) GetByName(name string) (*batchv1.Job, error) {
-	return j.clientset.BatchV1().Jobs(j.namespace).Get(j.ctx, name, j.Options.GetOptions)
-}
-
-// Get get job by name, alias to "GetByName"
-func (j *Job) Get(name string) (*batchv1.Job, error) {
-	return j.GetByName(name)
-}
-
-// ListByLabel list jobs by labels
-func (j *Job) ListByLabel(labels string) (*batchv1.JobList, error) {
-	listOptions := j.Options.ListOptions.DeepCopy()
-	listOptions.LabelSelector = labels
-	return j.clientset.BatchV1().Jobs(j.namespace).List(j.ctx, *listOptions)
-}
-
-// List list jobs by labels, alias to "ListByLabel"
-func (j *Job) List(labels string) (*batchv1.JobList, error) {
-	return j.ListByLabel(labels)
-}
-
-// ListByNamespace list jobs by namespace
-func (j *Job) ListByNamespace(namespace string) (*batchv1.JobList, error) {
-	return j.WithNamespace(namespace).ListByLabel("")
-}
-
-// ListAll list all jobs in the k8s cluster
-func (j *Job) ListAll(namespace string) (*batchv1.JobList, error) {
-	return j.WithNamespace(metav1.NamespaceAll).ListByLabel("")
+	return &Job{j.Handler.WithDryRun()}
 }
 
 // GetController returns a JobController object by job name if the controllee(job) has a controller.
@@ -499,10 +72,7 @@ func (j *Job) GetController(name string) (*JobController, error) {
 	oc := JobController{OwnerReference: *ownerRef}
 
 	// new a cronjob handler
-	cronjobHandler, err := NewCronJob(j.ctx, j.namespace, j.kubeconfig)
-	if err != nil {
-		return nil, err
-	}
+	cronjobHandler := j.factory.CronJobs(j.namespace)
 	cronjob, err := cronjobHandler.Get(ownerRef.Name)
 	if err != nil {
 		return nil, err
@@ -554,176 +124,24 @@ func (j *Job) IsFinish(name string) bool {
 	return false
 }
 
-// WaitFinish wait job status to be "true"
-func (j *Job) WaitFinish(name string) (err error) {
-	var (
-		watcher watch.Interface
-		timeout = int64(0)
-	)
-	if j.IsFinish(name) {
-		return
-	}
-
-	for {
-		listOptions := metav1.SingleObject(metav1.ObjectMeta{Name: name, Namespace: j.namespace})
-		listOptions.TimeoutSeconds = &timeout
-		watcher, err = j.clientset.BatchV1().Jobs(j.namespace).Watch(j.ctx, listOptions)
-		if err != nil {
-			return
-		}
-		for event := range watcher.ResultChan() {
-			switch event.Type {
-			case watch.Modified:
-				if j.IsFinish(name) {
-					watcher.Stop()
-					return
-				}
-			case watch.Deleted:
-				watcher.Stop()
-				return fmt.Errorf("%s deleted", name)
+// WaitFinish wait job status to be "true", it times out after DefaultWaitTimeout.
+func (j *Job) WaitFinish(name string) error {
+	existed := false
+	return j.WaitFor(name, func(obj *unstructured.Unstructured) (bool, error) {
+		// job not exist means it's finished, same as IsFinish.
+		if obj == nil {
+			if existed {
+				return false, fmt.Errorf("%s deleted", name)
 			}
+			return true, nil
 		}
-	}
-}
-
-// WaitNotExist wait job not exist
-func (j *Job) WaitNotExist(name string) (err error) {
-	var (
-		watcher watch.Interface
-		timeout = int64(0)
-	)
-	_, err = j.Get(name)
-	if err != nil { // job not exist
-		return nil
-	}
-	for {
-		listOptions := metav1.SingleObject(metav1.ObjectMeta{Name: name, Namespace: j.namespace})
-		listOptions.TimeoutSeconds = &timeout
-		watcher, err = j.clientset.BatchV1().Jobs(j.namespace).Watch(j.ctx, listOptions)
-		if err != nil {
-			return
-		}
-		for event := range watcher.ResultChan() {
-			switch event.Type {
-			case watch.Deleted:
-				for {
-					if _, err := j.Get(name); err != nil { // job not exist
-						break
-					}
-					time.Sleep(time.Millisecond * 500)
-				}
-				watcher.Stop()
-				return
-			}
-		}
-	}
-}
-
-// WatchByName watch jobs by name
-func (j *Job) WatchByName(name string,
-	addFunc, modifyFunc, deleteFunc func(x interface{}), x interface{}) (err error) {
-	var (
-		watcher watch.Interface
-		timeout = int64(0)
-		isExist bool
-	)
-	for {
-		listOptions := metav1.SingleObject(metav1.ObjectMeta{Name: name, Namespace: j.namespace})
-		listOptions.TimeoutSeconds = &timeout
-		if watcher, err = j.clientset.BatchV1().Jobs(j.namespace).Watch(j.ctx, listOptions); err != nil {
-			return
-		}
-		if _, err = j.Get(name); err != nil {
-			isExist = false // job not exist
-		} else {
-			isExist = true // job exist
-		}
-		for event := range watcher.ResultChan() {
-			switch event.Type {
-			case watch.Added:
-				if !isExist {
-					addFunc(x)
-				}
-				isExist = true
-			case watch.Modified:
-				modifyFunc(x)
-				isExist = true
-			case watch.Deleted:
-				deleteFunc(x)
-				isExist = false
-			case watch.Bookmark:
-				log.Debug("watch job: bookmark.")
-			case watch.Error:
-				log.Debug("watch job: error")
-			}
-		}
-		// If event channel is closed, it means the server has closed the connection
-		log.Debug("watch job: reconnect to kubernetes")
-	}
-}
-
-// WatchByLabel watch jobs by labelSelector
-func (j *Job) WatchByLabel(labelSelector string,
-	addFunc, modifyFunc, deleteFunc func(x interface{}), x interface{}) (err error) {
-	var (
-		watcher watch.Interface
-		jobList *batchv1.JobList
-		timeout = int64(0)
-		isExist bool
-	)
-	for {
-		if watcher, err = j.clientset.BatchV1().Jobs(j.namespace).Watch(j.ctx,
-			metav1.ListOptions{LabelSelector: labelSelector, TimeoutSeconds: &timeout}); err != nil {
-			return
-		}
-		if jobList, err = j.List(labelSelector); err != nil {
-			return
-		}
-		if len(jobList.Items) == 0 {
-			isExist = false // job not exist
-		} else {
-			isExist = true // job exist
-		}
-		for event := range watcher.ResultChan() {
-			switch event.Type {
-			case watch.Added:
-				if !isExist {
-					addFunc(x)
-				}
-				isExist = true
-			case watch.Modified:
-				modifyFunc(x)
-				isExist = true
-			case watch.Deleted:
-				deleteFunc(x)
-				isExist = false
-			case watch.Bookmark:
-				log.Debug("watch job: bookmark.")
-			case watch.Error:
-				log.Debug("watch job: error")
-			}
-		}
-		// If event channel is closed, it means the server has closed the connection
-		log.Debug("watch job: reconnect to kubernetes")
-	}
-}
-
-// Watch watch jobs by name, alias to "WatchByName"
-func (j *Job) Watch(name string,
-	addFunc, modifyFunc, deleteFunc func(x interface{}), x interface{}) (err error) {
-	return j.WatchByName(name, addFunc, modifyFunc, deleteFunc, x)
+		existed = true
+		return hasCondition(obj, string(batchv1.JobComplete), string(corev1.ConditionTrue)) ||
+			hasCondition(obj, string(batchv1.JobFailed), string(corev1.ConditionTrue)), nil
+	}, DefaultWaitTimeout)
 }
 
-// RunInformer
-func (j *Job) RunInformer(
-	addFunc func(obj interface{}),
-	updateFunc func(oldObj, newObj interface{}),
-	deleteFunc func(obj interface{}),
-	stopCh chan struct{}) {
-	j.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    addFunc,
-		UpdateFunc: updateFunc,
-		DeleteFunc: deleteFunc,
-	})
-	j.informer.Run(stopCh)
+// WaitNotExist wait job not exist, it times out after DefaultWaitTimeout.
+func (j *Job) WaitNotExist(name string) error {
+	return j.WaitFor(name, ConditionDeleted, DefaultWaitTimeout)
 }
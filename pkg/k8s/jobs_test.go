@@ -0,0 +1,73 @@
+package k8s
+
+import (
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestJob(name string, cond batchv1.JobConditionType, owners ...metav1.OwnerReference) *batchv1.Job {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, OwnerReferences: owners},
+	}
+	if len(cond) != 0 {
+		job.Status.Conditions = []batchv1.JobCondition{{Type: cond, Status: corev1.ConditionTrue}}
+	}
+	return job
+}
+
+func TestJobStatus(t *testing.T) {
+	job := newTestFactory(
+		newTestJob("complete", batchv1.JobComplete),
+		newTestJob("failed", batchv1.JobFailed),
+		newTestJob("running", ""),
+	).Jobs(testNamespace)
+
+	tests := []struct {
+		name     string
+		complete bool
+		finish   bool
+	}{
+		{"complete", true, true},
+		{"failed", false, true},
+		{"running", false, false},
+		{"notexist", false, true},
+	}
+	for _, tt := range tests {
+		if got := job.IsComplete(tt.name); got != tt.complete {
+			t.Errorf("IsComplete(%s) = %v, want %v", tt.name, got, tt.complete)
+		}
+		if got := job.IsFinish(tt.name); got != tt.finish {
+			t.Errorf("IsFinish(%s) = %v, want %v", tt.name, got, tt.finish)
+		}
+	}
+}
+
+func TestJobGetController(t *testing.T) {
+	controller := true
+	now := metav1.Now()
+	cronjob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "pi", Namespace: testNamespace, Labels: map[string]string{"app": "pi"}},
+		Status:     batchv1.CronJobStatus{LastScheduleTime: &now, LastSuccessfulTime: &now},
+	}
+	owner := metav1.OwnerReference{APIVersion: "batch/v1", Kind: "CronJob", Name: "pi", Controller: &controller}
+	f := newTestFactory(cronjob, newTestJob("pi-27500000", batchv1.JobComplete, owner), newTestJob("other", ""))
+
+	jc, err := f.Jobs(testNamespace).GetController("pi-27500000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jc.Name != "pi" || jc.Labels["app"] != "pi" {
+		t.Errorf("controller = %s %v", jc.Name, jc.Labels)
+	}
+
+	jobs, err := f.CronJobs(testNamespace).GetJobs("pi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].Name != "pi-27500000" {
+		t.Errorf("GetJobs returns %d jobs, want [pi-27500000]", len(jobs))
+	}
+}
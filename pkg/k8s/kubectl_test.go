@@ -0,0 +1,624 @@
+package k8s
+
+import (
+	"hybfkuf/pkg/k8s/apply"
+
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testKubectlManifest = `# comment
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: test
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: nginx
+  namespace: test
+data:
+  key: "value # not a comment"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  namespace: test
+spec:
+  selector:
+    matchLabels:
+      app: nginx
+  template:
+    metadata:
+      labels:
+        app: nginx
+    spec:
+      containers:
+      - name: nginx
+        image: nginx
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: nginx
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: nginx
+  minReplicas: 1
+  maxReplicas: 3
+---
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: nginx
+  namespace: test
+spec:
+  minAvailable: 1
+---
+apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: bar
+  namespace: test
+spec:
+  size: 1
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+  namespace: ignored
+`
+
+var (
+	testNamespaceGVR   = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	testConfigMapGVR   = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	testDeploymentGVR  = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	testHPAGVR         = schema.GroupVersionResource{Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"}
+	testPDBGVR         = schema.GroupVersionResource{Group: "policy", Version: "v1", Resource: "poddisruptionbudgets"}
+	testFooGVR         = schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "foos"}
+	testClusterRoleGVR = schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}
+)
+
+func writeTestManifest(t *testing.T, manifest string) string {
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	if err := os.WriteFile(path, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// getTestObject gets the object applied by the dynamic client.
+func getTestObject(f *Factory, gvr schema.GroupVersionResource, namespace, name string) (*unstructured.Unstructured, error) {
+	if len(namespace) == 0 {
+		return f.dynamicClient.Resource(gvr).Get(context.TODO(), name, metav1.GetOptions{})
+	}
+	return f.dynamicClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+var testKubectlObjects = []struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+}{
+	{testNamespaceGVR, "", "test"},
+	{testConfigMapGVR, testNamespace, "nginx"},
+	{testDeploymentGVR, testNamespace, "nginx"},
+	{testHPAGVR, metav1.NamespaceDefault, "nginx"},
+	{testPDBGVR, testNamespace, "nginx"},
+	{testFooGVR, testNamespace, "bar"},
+	{testClusterRoleGVR, "", "reader"},
+}
+
+func TestApplyFDeleteF(t *testing.T) {
+	f := newTestFactory()
+	path := writeTestManifest(t, testKubectlManifest)
+
+	if _, err := f.ApplyF(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range testKubectlObjects {
+		if _, err := getTestObject(f, o.gvr, o.namespace, o.name); err != nil {
+			t.Errorf("%s %s/%s not applied: %v", o.gvr.Resource, o.namespace, o.name, err)
+		}
+	}
+	cm, _ := getTestObject(f, testConfigMapGVR, testNamespace, "nginx")
+	if value, _, _ := unstructured.NestedString(cm.Object, "data", "key"); value != "value # not a comment" {
+		t.Errorf("configmap data = %q", value)
+	}
+	// apply again updates the existing objects
+	if _, err := f.ApplyF(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.DeleteF(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range testKubectlObjects {
+		if _, err := getTestObject(f, o.gvr, o.namespace, o.name); !k8serrors.IsNotFound(err) {
+			t.Errorf("%s %s/%s not deleted: %v", o.gvr.Resource, o.namespace, o.name, err)
+		}
+	}
+}
+
+func TestApplyFModes(t *testing.T) {
+	for _, mode := range []ApplyMode{ApplyModeClientSide, ApplyModeCreateOrUpdate} {
+		f := newTestFactory()
+		path := writeTestManifest(t, testKubectlManifest)
+		for i := 0; i < 2; i++ {
+			if _, err := f.ApplyF(path, ApplyOptions{Mode: mode}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, o := range testKubectlObjects {
+			obj, err := getTestObject(f, o.gvr, o.namespace, o.name)
+			if err != nil {
+				t.Errorf("%s: %s %s/%s not applied: %v", mode, o.gvr.Resource, o.namespace, o.name, err)
+				continue
+			}
+			_, ok := obj.GetAnnotations()[apply.LastAppliedConfigAnnotation]
+			if ok != (mode == ApplyModeClientSide) {
+				t.Errorf("%s: annotations of %s = %v", mode, o.gvr.Resource, obj.GetAnnotations())
+			}
+		}
+	}
+}
+
+func TestApplierClientSide(t *testing.T) {
+	f := newTestFactory()
+	applier := f.Applier()
+	applier.SetMode(apply.ModeClientSide)
+	applyFoo := func(manifest string) *unstructured.Unstructured {
+		objects, err := apply.Decode([]byte(manifest))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := applier.Apply(objects[0])
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		return result.Object
+	}
+
+	applyFoo(`{"apiVersion":"example.com/v1","kind":"Foo","metadata":{"name":"bar"},"spec":{"size":1,"debug":true}}`)
+	// custom resources are patched with json merge patch, fields removed
+	// from the manifest are removed.
+	obj := applyFoo(`{"apiVersion":"example.com/v1","kind":"Foo","metadata":{"name":"bar"},"spec":{"size":2}}`)
+	if _, found, _ := unstructured.NestedBool(obj.Object, "spec", "debug"); found {
+		t.Errorf("spec = %v, want debug removed", obj.Object["spec"])
+	}
+	if size, _, _ := unstructured.NestedInt64(obj.Object, "spec", "size"); size != 2 {
+		t.Errorf("spec.size = %d, want 2", size)
+	}
+	if obj.GetNamespace() != metav1.NamespaceDefault {
+		t.Errorf("namespace = %q, want %q", obj.GetNamespace(), metav1.NamespaceDefault)
+	}
+
+	// unknown kinds are reported
+	objects, _ := apply.Decode([]byte(`{"apiVersion":"example.com/v1","kind":"Unknown","metadata":{"name":"bar"}}`))
+	if result := applier.Apply(objects[0]); result.Err == nil || result.Action != apply.ActionFailed {
+		t.Error("apply unknown kind should fail")
+	}
+}
+
+func TestApplyReaderDeleteReader(t *testing.T) {
+	f := newTestFactory()
+	manifest := `{"apiVersion":"v1","kind":"List","items":[
+{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"nginx","namespace":"test"}},
+{"apiVersion":"example.com/v1","kind":"Foo","metadata":{"name":"bar","namespace":"test"}}]}`
+
+	if _, err := f.ApplyReader(strings.NewReader(manifest)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getTestObject(f, testConfigMapGVR, testNamespace, "nginx"); err != nil {
+		t.Errorf("configmap not applied: %v", err)
+	}
+	if _, err := getTestObject(f, testFooGVR, testNamespace, "bar"); err != nil {
+		t.Errorf("foo not applied: %v", err)
+	}
+	if _, err := f.DeleteReader(strings.NewReader(manifest)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getTestObject(f, testFooGVR, testNamespace, "bar"); !k8serrors.IsNotFound(err) {
+		t.Errorf("foo not deleted: %v", err)
+	}
+
+	// the manifest is not applied at all if any document is broken
+	_, err := f.ApplyReader(strings.NewReader(manifest + "\n{"))
+	var decodeErr *apply.DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Index != 2 {
+		t.Errorf("error = %v, want the DecodeError of document 2", err)
+	}
+	if _, err := getTestObject(f, testConfigMapGVR, testNamespace, "nginx"); !k8serrors.IsNotFound(err) {
+		t.Errorf("configmap applied: %v", err)
+	}
+}
+
+func findResult(report *apply.Report, ref string) apply.Result {
+	for _, result := range report.Results {
+		if result.Ref() == ref {
+			return result
+		}
+	}
+	return apply.Result{}
+}
+
+func TestApplyFReport(t *testing.T) {
+	f := newTestFactory()
+	manifest := testKubectlManifest + `---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: bar
+`
+	path := writeTestManifest(t, manifest)
+	count := len(testKubectlObjects)
+
+	report, err := f.ApplyF(path)
+	if err == nil || !strings.Contains(err.Error(), "unknown.example.com/bar") {
+		t.Errorf("error = %v, want the error of unknown.example.com/bar", err)
+	}
+	if report.Count(apply.ActionCreated) != count || report.Count(apply.ActionFailed) != 1 {
+		t.Errorf("report:\n%s", report)
+	}
+	if result := findResult(report, "deployment.apps/nginx"); result.Namespace != testNamespace || result.Object == nil {
+		t.Errorf("result = %+v", result)
+	}
+	if result := findResult(report, "horizontalpodautoscaler.autoscaling/nginx"); result.String() != "horizontalpodautoscaler.autoscaling/nginx created" || result.Namespace != metav1.NamespaceDefault {
+		t.Errorf("result = %+v", result)
+	}
+
+	path = writeTestManifest(t, strings.Replace(testKubectlManifest, "value # not a comment", "changed", 1))
+	if report, err = f.ApplyF(path); err != nil {
+		t.Fatal(err)
+	}
+	if findResult(report, "configmap/nginx").Action != apply.ActionConfigured || report.Count(apply.ActionUnchanged) != count-1 {
+		t.Errorf("report:\n%s", report)
+	}
+
+	if report, err = f.DeleteF(path); err != nil || report.Count(apply.ActionDeleted) != count {
+		t.Errorf("report:\n%s\nerror: %v", report, err)
+	}
+	if report, err = f.DeleteF(path); err != nil || report.Count(apply.ActionSkipped) != count {
+		t.Errorf("report:\n%s\nerror: %v", report, err)
+	}
+}
+
+// fakeCRDEstablished makes the fake CRDs established when they are got, and
+// adds their resources to the discovery information.
+func fakeCRDEstablished(f *Factory) {
+	clientset := f.clientset.(*fake.Clientset)
+	dynamicClient := f.dynamicClient.(*dynamicfake.FakeDynamicClient)
+	dynamicClient.PrependReactor("get", "customresourcedefinitions", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		name := action.(k8stesting.GetAction).GetName()
+		obj, err := dynamicClient.Tracker().Get(action.GetResource(), "", name)
+		if err != nil {
+			return true, nil, err
+		}
+		crd := obj.(*unstructured.Unstructured).DeepCopy()
+		unstructured.SetNestedSlice(crd.Object, []interface{}{
+			map[string]interface{}{"type": "Established", "status": "True"},
+		}, "status", "conditions")
+
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+		plural, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "plural")
+		clientset.Resources = append(clientset.Resources, &metav1.APIResourceList{
+			GroupVersion: group + "/v1",
+			APIResources: []metav1.APIResource{{Name: plural, Kind: kind, Namespaced: true}},
+		})
+		return true, crd, nil
+	})
+}
+
+func TestApplyFOrder(t *testing.T) {
+	f := newTestFactory()
+	fakeCRDEstablished(f)
+	path := writeTestManifest(t, `
+apiVersion: stable.example.com/v1
+kind: CronTab
+metadata:
+  name: backup
+  namespace: ops
+spec:
+  cronSpec: "* * * * */5"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  namespace: ops
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: crontabs.stable.example.com
+spec:
+  group: stable.example.com
+  scope: Namespaced
+  names:
+    kind: CronTab
+    plural: crontabs
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: ops
+`)
+
+	report, err := f.ApplyF(path)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, report)
+	}
+	want := `namespace/ops created
+customresourcedefinition.apiextensions.k8s.io/crontabs.stable.example.com created
+deployment.apps/nginx created
+crontab.stable.example.com/backup created`
+	if report.String() != want {
+		t.Errorf("report:\n%s\nwant:\n%s", report, want)
+	}
+
+	if report, err = f.DeleteF(path); err != nil {
+		t.Fatal(err)
+	}
+	want = `crontab.stable.example.com/backup deleted
+deployment.apps/nginx deleted
+customresourcedefinition.apiextensions.k8s.io/crontabs.stable.example.com deleted
+namespace/ops deleted`
+	if report.String() != want {
+		t.Errorf("report:\n%s\nwant:\n%s", report, want)
+	}
+}
+
+func TestApplierPrune(t *testing.T) {
+	f := newTestFactory()
+	newApplier := func() *apply.Applier {
+		applier := f.Applier()
+		applier.SetApplySet("web")
+		applier.SetPrune(true)
+		return applier
+	}
+	// an object of another apply set is never pruned
+	other := f.Applier()
+	other.SetApplySet("other")
+	if _, err := other.ApplyReader(strings.NewReader(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"other","namespace":"test"}}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newApplier().ApplyFile(writeTestManifest(t, testKubectlManifest)); err != nil {
+		t.Fatal(err)
+	}
+	deploy, _ := getTestObject(f, testDeploymentGVR, testNamespace, "nginx")
+	if deploy.GetLabels()[apply.LabelApplySet] != "web" {
+		t.Errorf("labels = %v", deploy.GetLabels())
+	}
+
+	// the configmap and foo are removed from the manifest
+	manifest := writeTestManifest(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  namespace: test
+---
+apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: baz
+  namespace: test
+`)
+	// the fake dynamic client doesn't support dry run
+	dryRun := true
+	f.dynamicClient.(*dynamicfake.FakeDynamicClient).PrependReactor("delete", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return dryRun, nil, nil
+	})
+	report, err := newApplier().WithDryRun().ApplyFile(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"foo.example.com/bar pruned (dry run)",
+		"configmap/nginx pruned (dry run)",
+		"namespace/test pruned (dry run)",
+	}
+	if got := strings.Join(pruneResults(report), "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("dry run prune:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+	pruned, err := newApplier().PruneList(mustDecode(t, manifest))
+	if err != nil || len(pruned) != 3 {
+		t.Errorf("prune list = %v, %v", pruned, err)
+	}
+	dryRun = false
+
+	if report, err = newApplier().ApplyFile(manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(pruneResults(report)) != 3 {
+		t.Errorf("report:\n%s", report)
+	}
+	for _, o := range []struct {
+		gvr      schema.GroupVersionResource
+		name     string
+		notFound bool
+	}{
+		{testConfigMapGVR, "nginx", true},
+		{testFooGVR, "bar", true},
+		{testConfigMapGVR, "other", false},
+		{testDeploymentGVR, "nginx", false},
+		{testFooGVR, "baz", false},
+	} {
+		if _, err := getTestObject(f, o.gvr, testNamespace, o.name); k8serrors.IsNotFound(err) != o.notFound {
+			t.Errorf("%s %s: %v", o.gvr.Resource, o.name, err)
+		}
+	}
+
+	// the cluster scoped objects in the default prune kinds are pruned too
+	if _, err := getTestObject(f, testNamespaceGVR, "", "test"); !k8serrors.IsNotFound(err) {
+		t.Errorf("namespace not pruned: %v", err)
+	}
+}
+
+func TestApplyFPrune(t *testing.T) {
+	f := newTestFactory()
+	opts := ApplyOptions{ApplySet: "web", Prune: true}
+	configMap := func(name string) string {
+		return `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + name + `","namespace":"test"}}`
+	}
+	if _, err := f.ApplyF(writeTestManifest(t, configMap("a")+"\n"+configMap("b")), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// b is removed from the manifest
+	manifest := writeTestManifest(t, configMap("a"))
+	pruned, err := f.PruneListF(manifest, opts)
+	if err != nil || len(pruned) != 1 || pruned[0].GetName() != "b" {
+		t.Errorf("PruneListF = %v, %v, want b", pruned, err)
+	}
+	report, err := f.ApplyF(manifest, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pruneResults(report); len(got) != 1 || got[0] != "configmap/b pruned" {
+		t.Errorf("pruned = %v, want configmap/b", got)
+	}
+	if _, err := getTestObject(f, testConfigMapGVR, testNamespace, "b"); !k8serrors.IsNotFound(err) {
+		t.Errorf("configmap b not pruned: %v", err)
+	}
+	if _, err := getTestObject(f, testConfigMapGVR, testNamespace, "a"); err != nil {
+		t.Errorf("configmap a: %v", err)
+	}
+}
+
+func pruneResults(report *apply.Report) []string {
+	var results []string
+	for _, result := range report.Results {
+		if result.Action == apply.ActionPruned {
+			results = append(results, result.String())
+		}
+	}
+	return results
+}
+
+func mustDecode(t *testing.T, path string) []*unstructured.Unstructured {
+	objects, err := apply.DecodeFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return objects
+}
+
+func TestDiffF(t *testing.T) {
+	f := newTestFactory()
+	path := writeTestManifest(t, testKubectlManifest)
+	if _, err := f.ApplyF(path); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := f.DiffF(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := report.Diff(); len(diff) != 0 {
+		t.Errorf("diff of the applied manifest =\n%s", diff)
+	}
+	for _, result := range report.Results {
+		if result.Action != apply.ActionUnchanged || !result.DryRun {
+			t.Errorf("%s, want unchanged (dry run)", result)
+		}
+	}
+
+	path = writeTestManifest(t, strings.Replace(testKubectlManifest, "image: nginx", "image: nginx:1.22", 1))
+	if report, err = f.DiffF(path); err != nil {
+		t.Fatal(err)
+	}
+	result := findResult(report, "deployment.apps/nginx")
+	if result.Action != apply.ActionConfigured {
+		t.Errorf("%s, want configured", result)
+	}
+	want := `--- live/deployment.apps/nginx
++++ merged/deployment.apps/nginx
+@@ -13,5 +13,5 @@
+         app: nginx
+     spec:
+       containers:
+-      - image: nginx
++      - image: nginx:1.22
+         name: nginx
+`
+	if result.Diff != want || report.Diff() != want {
+		t.Errorf("diff =\n%s\nwant\n%s", report.Diff(), want)
+	}
+}
+
+func TestFactoryRESTMapper(t *testing.T) {
+	f := newTestFactory()
+	clientset := f.clientset.(*fake.Clientset)
+	discoveries := func() int {
+		n := 0
+		for _, action := range clientset.Actions() {
+			if action.GetResource().Resource == "group" {
+				n++
+			}
+		}
+		return n
+	}
+	path := writeTestManifest(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"a","namespace":"test"}}`)
+	for i := 0; i < 3; i++ {
+		if _, err := f.ApplyF(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := f.DiffF(path); err != nil {
+		t.Fatal(err)
+	}
+	if n := discoveries(); n != 1 {
+		t.Errorf("%d discoveries, want 1", n)
+	}
+
+	// the kind not found is discovered again.
+	if _, err := f.ApplyReader(strings.NewReader(`{"apiVersion":"example.com/v1","kind":"Unknown","metadata":{"name":"a"}}`)); err == nil {
+		t.Error("apply of an unknown kind succeeded")
+	}
+	if n := discoveries(); n != 2 {
+		t.Errorf("%d discoveries, want 2", n)
+	}
+}
+
+func TestApplyFRenderOptions(t *testing.T) {
+	f := newTestFactory()
+	path := writeTestManifest(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"{{ .name }}","namespace":"test"}}`)
+	var output strings.Builder
+	opts := ApplyOptions{Render: apply.Template(map[string]interface{}{"name": "web"}), RenderOutput: &output}
+
+	if _, err := f.ApplyF(path, opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getTestObject(f, testConfigMapGVR, testNamespace, "web"); err != nil {
+		t.Errorf("rendered configmap not applied: %v", err)
+	}
+	if !strings.Contains(output.String(), `"name":"web"`) {
+		t.Errorf("render output = %q", output.String())
+	}
+	if _, err := f.DeleteF(path, opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getTestObject(f, testConfigMapGVR, testNamespace, "web"); !k8serrors.IsNotFound(err) {
+		t.Errorf("rendered configmap not deleted: %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+package k8s
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultContainerAnnotation is the annotation of the default container of
+// the pod, same as "kubectl logs" and "kubectl exec".
+const defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+
+// LogOptions selects the logs of GetLogs and StreamLogs, like the flags of
+// "kubectl logs".
+type LogOptions struct {
+	// Container is the container to read, it's the default container of the
+	// pod if it's empty, eg: the first container.
+	Container string
+	// AllContainers reads the logs of the init containers, containers and
+	// ephemeral containers, Container is ignored.
+	AllContainers bool
+	// Previous reads the logs of the previous terminated instance, eg: the
+	// container crashed.
+	Previous bool
+	// Follow streams the logs until the container exits or the context is done.
+	Follow bool
+	// SinceTime and SinceSeconds read the logs newer than a time or a relative
+	// duration, only one of them can be set.
+	SinceTime    *metav1.Time
+	SinceSeconds *int64
+	// TailLines reads the last lines of the logs.
+	TailLines *int64
+	// Timestamps adds an RFC3339 timestamp at the beginning of every line.
+	Timestamps bool
+	// LimitBytes is the max bytes of the logs read from the server.
+	LimitBytes *int64
+}
+
+// LogLine is a line of the logs sent by StreamLogLines, the trailing newline
+// is removed.
+type LogLine struct {
+	Container string
+	Line      string
+}
+
+// logStream is the log stream of a container.
+type logStream struct {
+	container string
+	stream    io.ReadCloser
+}
+
+func (o LogOptions) podLogOptions(container string) *corev1.PodLogOptions {
+	return &corev1.PodLogOptions{
+		Container:    container,
+		Follow:       o.Follow,
+		Previous:     o.Previous,
+		SinceSeconds: o.SinceSeconds,
+		SinceTime:    o.SinceTime,
+		Timestamps:   o.Timestamps,
+		TailLines:    o.TailLines,
+		LimitBytes:   o.LimitBytes,
+	}
+}
+
+// GetLogs returns the logs of the pod, eg:
+//
+//	tail := int64(100)
+//	logs, err := p.GetLogs("nginx", k8s.LogOptions{Container: "nginx", TailLines: &tail})
+//
+// It blocks until the container exits if LogOptions.Follow is true, use
+// StreamLogs or StreamLogLines instead.
+func (p *Pod) GetLogs(name string, opts ...LogOptions) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := p.StreamLogs(p.ctx, name, buf, opts...); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// StreamLogs writes the logs of the pod to w, until the logs end or ctx is
+// done, no error is returned if ctx is done. With LogOptions.AllContainers,
+// the logs of the containers are written one by one, or line by line
+// concurrently if LogOptions.Follow is true.
+func (p *Pod) StreamLogs(ctx context.Context, name string, w io.Writer, opts ...LogOptions) error {
+	streams, follow, err := p.openLogs(ctx, name, opts)
+	if err != nil {
+		return err
+	}
+	var mu sync.Mutex
+	return copyLogs(ctx, streams, follow, func(_ string, line []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		_, err := w.Write(line)
+		return err
+	})
+}
+
+// StreamLogLines sends the log lines of the pod to the returned channel, the
+// channel is closed when the logs end or ctx is done. eg:
+//
+//	lines, err := p.StreamLogLines(ctx, "nginx", k8s.LogOptions{AllContainers: true, Follow: true})
+//	for line := range lines {
+//		fmt.Printf("[%s] %s\n", line.Container, line.Line)
+//	}
+//
+// The error is returned if the logs can't be opened, eg: the pod or the
+// container doesn't exist, the errors when reading the logs are logged.
+func (p *Pod) StreamLogLines(ctx context.Context, name string, opts ...LogOptions) (<-chan LogLine, error) {
+	streams, follow, err := p.openLogs(ctx, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan LogLine)
+	go func() {
+		defer close(ch)
+		err := copyLogs(ctx, streams, follow, func(container string, line []byte) error {
+			select {
+			case ch <- LogLine{Container: container, Line: strings.TrimSuffix(string(line), "\n")}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			log.Debugf("stream logs of pod %s: %v", name, err)
+		}
+	}()
+	return ch, nil
+}
+
+// openLogs opens the log streams of the containers selected by opts.
+func (p *Pod) openLogs(ctx context.Context, name string, opts []LogOptions) ([]logStream, bool, error) {
+	var o LogOptions
+	if len(opts) != 0 {
+		o = opts[0]
+	}
+	if o.SinceTime != nil && o.SinceSeconds != nil {
+		return nil, false, fmt.Errorf("only one of SinceTime and SinceSeconds can be set")
+	}
+	pod, err := p.Get(name)
+	if err != nil {
+		return nil, false, err
+	}
+	containers := podContainerNames(pod)
+	var selected []string
+	switch {
+	case o.AllContainers:
+		selected = containers
+	case len(o.Container) != 0:
+		found := false
+		for _, container := range containers {
+			if container == o.Container {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false, fmt.Errorf("container %s is not valid for pod %s", o.Container, name)
+		}
+		selected = []string{o.Container}
+	default:
+		selected = []string{defaultContainer(pod)}
+	}
+
+	var streams []logStream
+	for _, container := range selected {
+		stream, err := p.factory.clientset.CoreV1().Pods(p.namespace).
+			GetLogs(name, o.podLogOptions(container)).Stream(ctx)
+		if err != nil {
+			closeLogs(streams)
+			return nil, false, fmt.Errorf("get logs of container %s in pod %s: %w", container, name, err)
+		}
+		streams = append(streams, logStream{container: container, stream: stream})
+	}
+	return streams, o.Follow, nil
+}
+
+// copyLogs calls sink for every line of the streams and closes them, the
+// streams are read concurrently if follow is true, otherwise one by one.
+// It returns nil if ctx is done.
+func copyLogs(ctx context.Context, streams []logStream, follow bool, sink func(container string, line []byte) error) error {
+	defer closeLogs(streams)
+	var err error
+	if follow && len(streams) > 1 {
+		var (
+			wg   sync.WaitGroup
+			once sync.Once
+		)
+		for _, s := range streams {
+			wg.Add(1)
+			go func(s logStream) {
+				defer wg.Done()
+				if e := readLines(s, sink); e != nil {
+					once.Do(func() { err = e })
+				}
+			}(s)
+		}
+		wg.Wait()
+	} else {
+		for _, s := range streams {
+			if err = readLines(s, sink); err != nil {
+				break
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func readLines(s logStream, sink func(container string, line []byte) error) error {
+	reader := bufio.NewReader(s.stream)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) != 0 {
+			if err := sink(s.container, line); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func closeLogs(streams []logStream) {
+	for _, s := range streams {
+		s.stream.Close()
+	}
+}
+
+// podContainerNames returns the names of the init containers, containers and
+// ephemeral containers of the pod.
+func podContainerNames(pod *corev1.Pod) []string {
+	var names []string
+	for _, container := range pod.Spec.InitContainers {
+		names = append(names, container.Name)
+	}
+	for _, container := range pod.Spec.Containers {
+		names = append(names, container.Name)
+	}
+	for _, container := range pod.Spec.EphemeralContainers {
+		names = append(names, container.Name)
+	}
+	return names
+}
+
+// defaultContainer returns the container of the annotation
+// "kubectl.kubernetes.io/default-container" if it's set, otherwise the
+// first container of the pod.
+func defaultContainer(pod *corev1.Pod) string {
+	if name := pod.Annotations[defaultContainerAnnotation]; len(name) != 0 {
+		for _, container := range pod.Spec.Containers {
+			if container.Name == name {
+				return name
+			}
+		}
+	}
+	if len(pod.Spec.Containers) == 0 {
+		return ""
+	}
+	return pod.Spec.Containers[0].Name
+}
@@ -0,0 +1,75 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestGetLogs(t *testing.T) {
+	pod := newTestPod("nginx", true)
+	pod.Spec.InitContainers = []corev1.Container{{Name: "init"}}
+	pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: "sidecar"})
+	pod.Annotations = map[string]string{defaultContainerAnnotation: "sidecar"}
+	f := newTestFactory(pod)
+	p := f.Pods(testNamespace)
+	clientset := f.clientset.(*fake.Clientset)
+
+	tail := int64(10)
+	logs, err := p.GetLogs("nginx", LogOptions{Previous: true, TailLines: &tail, Timestamps: true})
+	if err != nil || logs != "fake logs" {
+		t.Fatalf("GetLogs = %q, %v", logs, err)
+	}
+	actions := clientset.Actions()
+	opts := actions[len(actions)-1].(k8stesting.GenericAction).GetValue().(*corev1.PodLogOptions)
+	if opts.Container != "sidecar" || !opts.Previous || *opts.TailLines != 10 || !opts.Timestamps {
+		t.Errorf("log options = %+v, want the default container sidecar", opts)
+	}
+
+	if _, err := p.GetLogs("nginx", LogOptions{Container: "notexist"}); err == nil {
+		t.Error("GetLogs of an unknown container succeeded")
+	}
+	if _, err := p.GetLogs("notexist"); err == nil {
+		t.Error("GetLogs of an unknown pod succeeded")
+	}
+
+	buf := &bytes.Buffer{}
+	if err := p.StreamLogs(context.TODO(), "nginx", buf, LogOptions{AllContainers: true}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "fake logsfake logsfake logs" {
+		t.Errorf("logs of all containers = %q", buf.String())
+	}
+}
+
+func TestStreamLogLines(t *testing.T) {
+	pod := newTestPod("nginx", true)
+	pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: "sidecar"})
+	p := newTestFactory(pod).Pods(testNamespace)
+
+	lines, err := p.StreamLogLines(context.TODO(), "nginx", LogOptions{AllContainers: true, Follow: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for line := range lines {
+		got[line.Container] = line.Line
+	}
+	if len(got) != 2 || got["nginx"] != "fake logs" || got["sidecar"] != "fake logs" {
+		t.Errorf("lines = %v", got)
+	}
+
+	// the channel is closed if ctx is done.
+	ctx, cancel := context.WithCancel(context.TODO())
+	lines, err = p.StreamLogLines(ctx, "nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	for range lines {
+	}
+}
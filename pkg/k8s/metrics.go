@@ -8,7 +8,6 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 	v1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
 	//metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
@@ -63,7 +62,7 @@ type MetricsHandler struct {
 
 	ctx       context.Context
 	config    *rest.Config
-	clientset *metricsv.Clientset
+	clientset metricsv.Interface
 }
 
 // Namespace return the the MetricsHandler working namespace.
@@ -90,38 +89,25 @@ func (m *MetricsHandler) WithNamespace(namespace string) *MetricsHandler {
 	return metricsHandler
 }
 
-// NewMetrics new a metrics handler from kubeconfig or in-cluster config
-func NewMetrics(ctx context.Context, namespace, kubeconfig string) (metrics *MetricsHandler, err error) {
-	var (
-		config    *rest.Config
-		clientset *metricsv.Clientset
-	)
-
-	if len(kubeconfig) != 0 {
-		// create a rest config from kubeconfig
-		if config, err = clientcmd.BuildConfigFromFlags("", kubeconfig); err != nil {
-			return
-		}
-	} else {
-		// create a rest config in-cluster config
-		if config, err = rest.InClusterConfig(); err != nil {
-			return
-		}
-	}
+// Metrics returns a MetricsHandler working in the given namespace, the handler
+// shares the clients of the factory.
+func (f *Factory) Metrics(namespace string) *MetricsHandler {
+	metrics := &MetricsHandler{}
+	metrics.kubeconfig = f.kubeconfig
+	metrics.namespace = namespace
+	metrics.ctx = f.ctx
+	metrics.config = f.config
+	metrics.clientset = f.metricsClientset
+	return metrics
+}
 
-	// create a metrics clientset from rest config
-	clientset, err = metricsv.NewForConfig(config)
+// NewMetrics new a metrics handler from kubeconfig or in-cluster config
+func NewMetrics(ctx context.Context, namespace, kubeconfig string) (*MetricsHandler, error) {
+	factory, err := NewFactory(ctx, kubeconfig)
 	if err != nil {
-		return
+		return nil, err
 	}
-
-	metrics = &MetricsHandler{}
-	metrics.kubeconfig = kubeconfig
-	metrics.namespace = namespace
-	metrics.ctx = ctx
-	metrics.config = config
-	metrics.clientset = clientset
-	return
+	return factory.Metrics(namespace), nil
 }
 
 // Pod query pod metrics by name
@@ -160,7 +146,7 @@ func (m *MetricsHandler) PodRaw(name string) (*PodMetrics, error) {
 		return nil, fmt.Errorf("pod name is empty")
 	}
 	apiPath = apiPath + "/namespaces/" + m.namespace + "/pods/" + name
-	data, err := m.clientset.RESTClient().Get().AbsPath(apiPath).DoRaw(m.ctx)
+	data, err := m.clientset.Discovery().RESTClient().Get().AbsPath(apiPath).DoRaw(m.ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -181,7 +167,7 @@ func (m *MetricsHandler) PodsRaw() ([]PodMetrics, error) {
 		return nil, fmt.Errorf("not set the namespace")
 	}
 	apiPath = apiPath + "/namespaces/" + m.namespace + "/pods"
-	data, err := m.clientset.RESTClient().Get().AbsPath(apiPath).DoRaw(m.ctx)
+	data, err := m.clientset.Discovery().RESTClient().Get().AbsPath(apiPath).DoRaw(m.ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -203,7 +189,7 @@ func (m *MetricsHandler) PodsRaw() ([]PodMetrics, error) {
 // PodAllRaw query the metrics of all pods in the k8s cluster where the pod running, using REST API
 func (m *MetricsHandler) PodAllRaw() ([]PodMetrics, error) {
 	apiPath := "apis/metrics.k8s.io/v1beta1/pods"
-	data, err := m.clientset.RESTClient().Get().AbsPath(apiPath).DoRaw(m.ctx)
+	data, err := m.clientset.Discovery().RESTClient().Get().AbsPath(apiPath).DoRaw(m.ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -254,7 +240,7 @@ func (m *MetricsHandler) NodeRaw(name string) (*NodeMetrics, error) {
 		return nil, fmt.Errorf("k8s node hostname is empty")
 	}
 	apiPath = apiPath + "/nodes/" + name
-	data, err := m.clientset.RESTClient().Get().AbsPath(apiPath).DoRaw(m.ctx)
+	data, err := m.clientset.Discovery().RESTClient().Get().AbsPath(apiPath).DoRaw(m.ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -271,7 +257,7 @@ func (m *MetricsHandler) NodeRaw(name string) (*NodeMetrics, error) {
 // NodeAllRaw query all k8s node metrics using REST API
 func (m *MetricsHandler) NodeAllRaw() ([]NodeMetrics, error) {
 	apiPath := "apis/metrics.k8s.io/v1beta1/nodes"
-	data, err := m.clientset.RESTClient().Get().AbsPath(apiPath).DoRaw(m.ctx)
+	data, err := m.clientset.Discovery().RESTClient().Get().AbsPath(apiPath).DoRaw(m.ctx)
 	if err != nil {
 		return nil, err
 	}
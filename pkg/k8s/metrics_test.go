@@ -0,0 +1,61 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+func TestMetrics(t *testing.T) {
+	usage := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("250m"),
+		corev1.ResourceMemory: resource.MustParse("64Mi"),
+	}
+	podMetrics := &v1beta1.PodMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: testNamespace, Labels: map[string]string{"app": "nginx"}},
+		Containers: []v1beta1.ContainerMetrics{{Name: "nginx", Usage: usage}},
+	}
+	nodeMetrics := &v1beta1.NodeMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Usage:      usage,
+	}
+	// the fake tracker guesses the resource "podmetricses" from the kind, but
+	// the metrics api serves "pods" and "nodes", so add the objects by hand.
+	metricsClientset := metricsfake.NewSimpleClientset()
+	if err := metricsClientset.Tracker().Create(v1beta1.SchemeGroupVersion.WithResource("pods"), podMetrics, testNamespace); err != nil {
+		t.Fatal(err)
+	}
+	if err := metricsClientset.Tracker().Create(v1beta1.SchemeGroupVersion.WithResource("nodes"), nodeMetrics, ""); err != nil {
+		t.Fatal(err)
+	}
+	f := NewFactoryForClients(context.TODO(), fake.NewSimpleClientset(), nil, metricsClientset)
+	metrics := f.Metrics(testNamespace)
+
+	pm, err := metrics.Pod("nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pm.Containers) != 1 || pm.Containers[0].Usage[corev1.ResourceCPU] != 250 {
+		t.Errorf("pod metrics = %+v", pm.Containers)
+	}
+	if pms, err := metrics.Pods("app=nginx"); err != nil || len(pms) != 1 {
+		t.Errorf("Pods(app=nginx) = %d, %v", len(pms), err)
+	}
+
+	nm, err := metrics.Node("node1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nm.Usage[corev1.ResourceMemory] != 64*1024*1024 {
+		t.Errorf("node memory = %d", nm.Usage[corev1.ResourceMemory])
+	}
+	if nms, err := metrics.Nodes(""); err != nil || len(nms) != 1 {
+		t.Errorf("Nodes() = %d, %v", len(nms), err)
+	}
+}
@@ -8,177 +8,43 @@ import (
 	"context"
 	"fmt"
 	"strings"
-	"sync"
-	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/util/sets"
-	"k8s.io/client-go/discovery"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/informers"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/tools/clientcmd"
 	_ "k8s.io/metrics/pkg/apis/metrics"
 	_ "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
 type Node struct {
-	kubeconfig string
-
-	ctx             context.Context
-	config          *rest.Config
-	restClient      *rest.RESTClient
-	clientset       *kubernetes.Clientset
-	dynamicClient   dynamic.Interface
-	discoveryClient *discovery.DiscoveryClient
-	informerFactory informers.SharedInformerFactory
-	informer        cache.SharedIndexInformer
+	*Handler[corev1.Node, corev1.NodeList]
+}
 
-	Options *HandlerOptions
+var _ HandlerInterface[corev1.Node, corev1.NodeList] = (*Node)(nil)
 
-	sync.Mutex
+// Nodes returns a Node handler, the handler shares the clients of the factory.
+func (f *Factory) Nodes() *Node {
+	return &Node{newHandler(f, ResourceKindNode, false, "",
+		func(string) resourceClient[corev1.Node, corev1.NodeList] {
+			return f.clientset.CoreV1().Nodes()
+		},
+		f.informerFactory.Core().V1().Nodes().Informer)}
 }
 
 // new a node handler from kubeconfig or in-cluster config
-func NewNode(ctx context.Context, kubeconfig string) (node *Node, err error) {
-	var (
-		config          *rest.Config
-		restClient      *rest.RESTClient
-		clientset       *kubernetes.Clientset
-		dynamicClient   dynamic.Interface
-		discoveryClient *discovery.DiscoveryClient
-		informerFactory informers.SharedInformerFactory
-	)
-	node = &Node{}
-
-	// create rest config
-	if len(kubeconfig) != 0 {
-		// use the current context in kubeconfig
-		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
-		if err != nil {
-			return
-		}
-	} else {
-		// create the in-cluster config
-		config, err = rest.InClusterConfig()
-		if err != nil {
-			return
-		}
-	}
-
-	// setup APIPath, GroupVersion and NegotiatedSerializer before initializing a RESTClient
-	config.APIPath = "api"
-	config.GroupVersion = &corev1.SchemeGroupVersion
-	config.NegotiatedSerializer = scheme.Codecs
-
-	// create a RESTClient for the given config
-	restClient, err = rest.RESTClientFor(config)
-	if err != nil {
-		return
-	}
-	// create a Clientset for the given config
-	clientset, err = kubernetes.NewForConfig(config)
-	if err != nil {
-		return
-	}
-	// create a dynamic client for the given config
-	dynamicClient, err = dynamic.NewForConfig(config)
-	if err != nil {
-		return
-	}
-	// create a DiscoveryClient for the given config
-	discoveryClient, err = discovery.NewDiscoveryClientForConfig(config)
+func NewNode(ctx context.Context, kubeconfig string) (*Node, error) {
+	factory, err := NewFactory(ctx, kubeconfig)
 	if err != nil {
-		return
+		return nil, err
 	}
-	// create a sharedInformerFactory for all namespaces.
-	informerFactory = informers.NewSharedInformerFactory(clientset, time.Minute)
-
-	node.kubeconfig = kubeconfig
-	node.ctx = ctx
-	node.config = config
-	node.restClient = restClient
-	node.clientset = clientset
-	node.dynamicClient = dynamicClient
-	node.discoveryClient = discoveryClient
-	node.informerFactory = informerFactory
-	node.informer = informerFactory.Core().V1().Nodes().Informer()
-	node.Options = &HandlerOptions{}
-
-	return
+	return factory.Nodes(), nil
 }
 func (in *Node) DeepCopy() *Node {
-	out := new(Node)
-
-	out.kubeconfig = in.kubeconfig
-
-	out.ctx = in.ctx
-	out.config = in.config
-	out.restClient = in.restClient
-	out.clientset = in.clientset
-	out.dynamicClient = in.dynamicClient
-	out.discoveryClient = in.discoveryClient
-	out.informerFactory = in.informerFactory
-	out.informer = in.informer
-
-	out.Options = &HandlerOptions{}
-	out.Options.ListOptions = *in.Options.ListOptions.DeepCopy()
-	out.Options.GetOptions = *in.Options.GetOptions.DeepCopy()
-	out.Options.CreateOptions = *in.Options.CreateOptions.DeepCopy()
-	out.Options.UpdateOptions = *in.Options.UpdateOptions.DeepCopy()
-	out.Options.PatchOptions = *in.Options.PatchOptions.DeepCopy()
-	out.Options.ApplyOptions = *in.Options.ApplyOptions.DeepCopy()
-
-	return out
+	return &Node{in.Handler.DeepCopy()}
 }
 func (n *Node) WithDryRun() *Node {
-	node := n.DeepCopy()
-	node.Options.CreateOptions.DryRun = []string{metav1.DryRunAll}
-	node.Options.UpdateOptions.DryRun = []string{metav1.DryRunAll}
-	node.Options.DeleteOptions.DryRun = []string{metav1.DryRunAll}
-	node.Options.PatchOptions.DryRun = []string{metav1.DryRunAll}
-	node.Options.ApplyOptions.DryRun = []string{metav1.DryRunAll}
-	return node
-}
-func (n *Node) SetTimeout(timeout int64) {
-	n.Lock()
-	defer n.Unlock()
-	n.Options.ListOptions.TimeoutSeconds = &timeout
-}
-func (n *Node) SetLimit(limit int64) {
-	n.Lock()
-	defer n.Unlock()
-	n.Options.ListOptions.Limit = limit
-}
-
-func (n *Node) GetByName(name string) (*corev1.Node, error) {
-	return n.clientset.CoreV1().Nodes().Get(n.ctx, name, n.Options.GetOptions)
-}
-
-func (n *Node) Get(name string) (*corev1.Node, error) {
-	return n.GetByName(name)
-}
-
-// ListByLabel list nodes by labels
-func (n *Node) ListByLabel(labels string) (*corev1.NodeList, error) {
-	listOptions := n.Options.ListOptions.DeepCopy()
-	listOptions.LabelSelector = labels
-	return n.clientset.CoreV1().Nodes().List(n.ctx, *listOptions)
-}
-
-// List list nodes by labels, alias to "ListByLabel"
-func (n *Node) List(labels string) (*corev1.NodeList, error) {
-	return n.ListByLabel(labels)
-}
-
-// ListAll list all nodes
-func (n *Node) ListAll() (*corev1.NodeList, error) {
-	return n.ListByLabel("")
+	return &Node{n.Handler.WithDryRun()}
 }
 
 // check if the node status is ready
@@ -247,8 +113,9 @@ func (n *Node) GetStatus(name string) *NodeStatus {
 
 // GetRoles returns the roles of a given node.
 // The roles are determined by looking for:
-//   node-role.kubernetes.io/<role>=""
-//   kubernetes.io/role="<role>"
+//
+//	node-role.kubernetes.io/<role>=""
+//	kubernetes.io/role="<role>"
 func (n *Node) GetRoles(name string) []string {
 	roles := sets.NewString()
 
@@ -281,10 +148,12 @@ func (n *Node) GetPods(name string) (*corev1.PodList, error) {
 		return nil, err
 	}
 
-	podHandler, err := NewPod(n.ctx, "", n.kubeconfig)
-	if err != nil {
-		return nil, err
+	// read from the cache if the pods cache is started by Factory.StartCache.
+	if pods, ok := n.cachedPods(name); ok {
+		return pods, nil
 	}
+
+	podHandler := n.factory.Pods("")
 	podHandler.Options.ListOptions = metav1.ListOptions{FieldSelector: fieldSelector.String()}
 	//podHandler.SetNamespace(metav1.NamespaceAll)
 	//return podHandler.List("")
@@ -304,14 +173,38 @@ func (n *Node) GetNonTerminatedPods(name string) (*corev1.PodList, error) {
 	if err != nil {
 		return nil, err
 	}
-	podHandler, err := NewPod(n.ctx, "", n.kubeconfig)
-	if err != nil {
-		return nil, err
+	if pods, ok := n.cachedPods(name); ok {
+		podList := &corev1.PodList{}
+		for _, pod := range pods.Items {
+			if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+				podList.Items = append(podList.Items, pod)
+			}
+		}
+		return podList, nil
 	}
+	podHandler := n.factory.Pods("")
 	podHandler.Options.ListOptions = metav1.ListOptions{FieldSelector: fieldSelector.String()}
 	return podHandler.WithNamespace(metav1.NamespaceAll).List("")
 }
 
+// cachedPods returns the pods in the node from the pods cache, it returns
+// false if the cache is not started or synced.
+func (n *Node) cachedPods(name string) (*corev1.PodList, bool) {
+	lister := n.factory.Pods("").WithNamespace(metav1.NamespaceAll).cachedLister()
+	if lister == nil {
+		return nil, false
+	}
+	pods, err := lister.ByIndex(IndexByNode, name)
+	if err != nil {
+		return nil, false
+	}
+	podList := &corev1.PodList{}
+	for _, pod := range pods {
+		podList.Items = append(podList.Items, *pod.DeepCopy())
+	}
+	return podList, true
+}
+
 // get the node ip
 func (n *Node) GetIP(name string) (ip string, err error) {
 	node, err := n.Get(name)
@@ -448,17 +341,3 @@ func (n *Node) GetAllInfo() ([]NodeInfo, error) {
 
 	return nodeInfoList, nil
 }
-
-// RunInformer
-func (n *Node) RunInformer(
-	addFunc func(obj interface{}),
-	updateFunc func(oldObj, newObj interface{}),
-	deleteFunc func(obj interface{}),
-	stopCh chan struct{}) {
-	n.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    addFunc,
-		UpdateFunc: updateFunc,
-		DeleteFunc: deleteFunc,
-	})
-	n.informer.Run(stopCh)
-}
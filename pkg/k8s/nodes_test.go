@@ -0,0 +1,55 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestNode(name string, labels map[string]string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Spec:       corev1.NodeSpec{PodCIDR: "10.244.0.0/24", PodCIDRs: []string{"10.244.0.0/24"}},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "192.168.1.10"},
+				{Type: corev1.NodeHostName, Address: name},
+			},
+		},
+	}
+}
+
+func TestNode(t *testing.T) {
+	node := newTestFactory(
+		newTestNode("master1", map[string]string{
+			LabelNodeRolePrefix + "master":        "",
+			LabelNodeRolePrefix + "control-plane": "",
+		}),
+		newTestNode("worker1", nil),
+	).Nodes()
+
+	if !node.IsReady("master1") {
+		t.Errorf("IsReady(master1) = false, want true")
+	}
+	if roles := node.GetRoles("master1"); !reflect.DeepEqual(roles, []string{"control-plane", "master"}) {
+		t.Errorf("GetRoles(master1) = %v", roles)
+	}
+	if !node.IsMaster("master1") || !node.IsControlPlane("master1") {
+		t.Errorf("master1 is not master or control plane")
+	}
+	if node.IsMaster("worker1") {
+		t.Errorf("IsMaster(worker1) = true, want false")
+	}
+	if ip, err := node.GetIP("worker1"); err != nil || ip != "192.168.1.10" {
+		t.Errorf("GetIP = %q, %v", ip, err)
+	}
+	if hostname, err := node.GetHostname("worker1"); err != nil || hostname != "worker1" {
+		t.Errorf("GetHostname = %q, %v", hostname, err)
+	}
+	if cidr, err := node.GetCIDR("worker1"); err != nil || cidr != "10.244.0.0/24" {
+		t.Errorf("GetCIDR = %q, %v", cidr, err)
+	}
+}
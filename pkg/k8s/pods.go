@@ -5,12 +5,9 @@ package k8s
 // 2. 精简代码, 比如返回值
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"strings"
-	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -18,24 +15,10 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
-	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/apimachinery/pkg/watch"
-
 	//_ "k8s.io/client-go/kubernetes/typed/core/v1"
-	"k8s.io/client-go/discovery"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/informers"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/kubernetes/scheme"
-	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/tools/remotecommand"
 )
 
 type Container struct {
@@ -57,433 +40,37 @@ type PodController struct {
 	metav1.OwnerReference `json:"ownerReference"`
 }
 type Pod struct {
-	kubeconfig string
-	namespace  string
-
-	ctx             context.Context
-	config          *rest.Config
-	restClient      *rest.RESTClient
-	clientset       *kubernetes.Clientset
-	dynamicClient   dynamic.Interface
-	discoveryClient *discovery.DiscoveryClient
-	informerFactory informers.SharedInformerFactory
-	informer        cache.SharedIndexInformer
-	client          typedcorev1.PodInterface
+	*Handler[corev1.Pod, corev1.PodList]
+}
 
-	Options *HandlerOptions
+var _ HandlerInterface[corev1.Pod, corev1.PodList] = (*Pod)(nil)
 
-	sync.Mutex
+// Pods returns a Pod handler working in the given namespace, the handler
+// shares the clients of the factory.
+func (f *Factory) Pods(namespace string) *Pod {
+	return &Pod{newHandler(f, ResourceKindPod, true, namespace,
+		func(namespace string) resourceClient[corev1.Pod, corev1.PodList] {
+			return f.clientset.CoreV1().Pods(namespace)
+		},
+		f.informerFactory.Core().V1().Pods().Informer)}
 }
 
 // new a Pod handler from kubeconfig or in-cluster config
-func NewPod(ctx context.Context, namespace, kubeconfig string) (pod *Pod, err error) {
-	var (
-		config          *rest.Config
-		restClient      *rest.RESTClient
-		clientset       *kubernetes.Clientset
-		dynamicClient   dynamic.Interface
-		discoveryClient *discovery.DiscoveryClient
-		informerFactory informers.SharedInformerFactory
-	)
-	pod = &Pod{}
-
-	// create rest config
-	if len(kubeconfig) != 0 {
-		// use the current context in kubeconfig
-		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
-		if err != nil {
-			return
-		}
-	} else {
-		// creates the in-cluster config
-		config, err = rest.InClusterConfig()
-		if err != nil {
-			return
-		}
-	}
-
-	// setup APIPath, GroupVersion and NegotiatedSerializer before initializing a RESTClient
-	config.APIPath = "api"
-	config.GroupVersion = &corev1.SchemeGroupVersion
-	//config.GroupVersion = &schema.GroupVersion{Group: "", Version: "v1"}
-	config.NegotiatedSerializer = scheme.Codecs
-	//config.UserAgent = rest.DefaultKubernetesUserAgent()
-
-	//// k8s cluster endpoint, eg: https://10.250.16.10:8443
-	//config.Host = "127.0.0.1"
-	//config.ContentConfig = rest.ContentConfig{
-	//    GroupVersion:         &corev1.SchemeGroupVersion,
-	//    NegotiatedSerializer: scheme.Codecs,
-	//}
-
-	// create a RESTClient for the given config
-	restClient, err = rest.RESTClientFor(config)
+func NewPod(ctx context.Context, namespace, kubeconfig string) (*Pod, error) {
+	factory, err := NewFactory(ctx, kubeconfig)
 	if err != nil {
-		return
-	}
-	// create a Clientset for the given config
-	clientset, err = kubernetes.NewForConfig(config)
-	if err != nil {
-		return
-	}
-	// create a dynamic client for the given config
-	dynamicClient, err = dynamic.NewForConfig(config)
-	if err != nil {
-		return
-	}
-	// create a DiscoveryClient for the given config
-	discoveryClient, err = discovery.NewDiscoveryClientForConfig(config)
-	if err != nil {
-		return
-	}
-	// create a sharedInformerFactory for all namespaces.
-	informerFactory = informers.NewSharedInformerFactory(clientset, time.Minute)
-
-	if len(namespace) == 0 {
-		namespace = metav1.NamespaceDefault
+		return nil, err
 	}
-	pod.kubeconfig = kubeconfig
-	pod.namespace = namespace
-	pod.ctx = ctx
-	pod.config = config
-	pod.restClient = restClient
-	pod.clientset = clientset
-	pod.dynamicClient = dynamicClient
-	pod.discoveryClient = discoveryClient
-	pod.informerFactory = informerFactory
-	pod.informer = informerFactory.Core().V1().Pods().Informer()
-	pod.client = clientset.CoreV1().Pods(namespace)
-	pod.Options = &HandlerOptions{}
-
-	return
-}
-func (p *Pod) Namespace() string {
-	return p.namespace
+	return factory.Pods(namespace), nil
 }
 func (in *Pod) DeepCopy() *Pod {
-	out := new(Pod)
-
-	out.kubeconfig = in.kubeconfig
-	out.namespace = in.namespace
-
-	out.ctx = in.ctx
-	out.config = in.config
-	out.restClient = in.restClient
-	out.clientset = in.clientset
-	out.dynamicClient = in.dynamicClient
-	out.discoveryClient = in.discoveryClient
-	out.informerFactory = in.informerFactory
-	out.informer = in.informer
-	out.client = in.client
-
-	out.Options = &HandlerOptions{}
-	out.Options.ListOptions = *in.Options.ListOptions.DeepCopy()
-	out.Options.GetOptions = *in.Options.GetOptions.DeepCopy()
-	out.Options.CreateOptions = *in.Options.CreateOptions.DeepCopy()
-	out.Options.UpdateOptions = *in.Options.UpdateOptions.DeepCopy()
-	out.Options.PatchOptions = *in.Options.PatchOptions.DeepCopy()
-	out.Options.ApplyOptions = *in.Options.ApplyOptions.DeepCopy()
-
-	return out
-}
-
-func (p *Pod) setNamespace(namespace string) {
-	p.Lock()
-	defer p.Unlock()
-	p.namespace = namespace
+	return &Pod{in.Handler.DeepCopy()}
 }
 func (p *Pod) WithNamespace(namespace string) *Pod {
-	pod := p.DeepCopy()
-	pod.setNamespace(namespace)
-	return pod
+	return &Pod{p.Handler.WithNamespace(namespace)}
 }
 func (p *Pod) WithDryRun() *Pod {
-	pod := p.DeepCopy()
-	pod.Options.CreateOptions.DryRun = []string{metav1.DryRunAll}
-	pod.Options.UpdateOptions.DryRun = []string{metav1.DryRunAll}
-	pod.Options.DeleteOptions.DryRun = []string{metav1.DryRunAll}
-	pod.Options.PatchOptions.DryRun = []string{metav1.DryRunAll}
-	pod.Options.ApplyOptions.DryRun = []string{metav1.DryRunAll}
-	return pod
-}
-func (p *Pod) SetLimit(limit int64) {
-	p.Lock()
-	defer p.Unlock()
-	p.Options.ListOptions.Limit = limit
-}
-func (p *Pod) SetTimeout(timeout int64) {
-	p.Lock()
-	defer p.Unlock()
-	p.Options.ListOptions.TimeoutSeconds = &timeout
-}
-func (p *Pod) SetForceDelete(force bool) {
-	p.Lock()
-	defer p.Unlock()
-	if force {
-		gracePeriodSeconds := int64(0)
-		p.Options.DeleteOptions.GracePeriodSeconds = &gracePeriodSeconds
-	} else {
-		p.Options.DeleteOptions = metav1.DeleteOptions{}
-	}
-}
-
-// CreateFromRaw create pod from map[string]interface{}
-func (p *Pod) CreateFromRaw(raw map[string]interface{}) (*corev1.Pod, error) {
-	pod := &corev1.Pod{}
-	err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, pod)
-	if err != nil {
-		return nil, err
-	}
-
-	var namespace string
-	if len(pod.Namespace) != 0 {
-		namespace = pod.Namespace
-	} else {
-		namespace = p.namespace
-	}
-
-	return p.clientset.CoreV1().Pods(namespace).Create(p.ctx, pod, p.Options.CreateOptions)
-}
-
-// CreateFromBytes create pod from bytes
-func (p *Pod) CreateFromBytes(data []byte) (*corev1.Pod, error) {
-	podJson, err := yaml.ToJSON(data)
-	if err != nil {
-		return nil, err
-	}
-
-	pod := &corev1.Pod{}
-	err = json.Unmarshal(podJson, pod)
-	if err != nil {
-		return nil, err
-	}
-
-	var namespace string
-	if len(pod.Namespace) != 0 {
-		namespace = pod.Namespace
-	} else {
-		namespace = p.namespace
-	}
-
-	//unstructMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
-	//if err != nil {
-	//    log.Error("ToUnstructured error")
-	//    log.Error(err)
-	//} else {
-	//    log.Infof("%#v", unstructMap)
-	//}
-	return p.clientset.CoreV1().Pods(namespace).Create(p.ctx, pod, p.Options.CreateOptions)
-}
-
-// CreateFromFile create pod from yaml file
-func (p *Pod) CreateFromFile(path string) (*corev1.Pod, error) {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	return p.CreateFromBytes(data)
-}
-
-// Create create pod from file, alias to "CreateFromFile"
-func (p *Pod) Create(path string) (*corev1.Pod, error) {
-	return p.CreateFromFile(path)
-}
-
-// UpdateFromRaw update pod from map[string]interface{}
-func (p *Pod) UpdateFromRaw(raw map[string]interface{}) (*corev1.Pod, error) {
-	pod := &corev1.Pod{}
-	err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, pod)
-	if err != nil {
-		return nil, err
-	}
-
-	var namespace string
-	if len(pod.Namespace) != 0 {
-		namespace = pod.Namespace
-	} else {
-		namespace = p.namespace
-	}
-
-	return p.clientset.CoreV1().Pods(namespace).Update(p.ctx, pod, p.Options.UpdateOptions)
-}
-
-// UpdateFromBytes update pod from bytes
-func (p *Pod) UpdateFromBytes(data []byte) (*corev1.Pod, error) {
-	podJson, err := yaml.ToJSON(data)
-	if err != nil {
-		return nil, err
-	}
-
-	pod := &corev1.Pod{}
-	err = json.Unmarshal(podJson, pod)
-	if err != nil {
-		return nil, err
-	}
-
-	var namespace string
-	if len(pod.Namespace) != 0 {
-		namespace = pod.Namespace
-	} else {
-		namespace = p.namespace
-	}
-
-	return p.clientset.CoreV1().Pods(namespace).Update(p.ctx, pod, p.Options.UpdateOptions)
-}
-
-// UpdateFromFile update pod from yaml file
-func (p *Pod) UpdateFromFile(path string) (*corev1.Pod, error) {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	return p.UpdateFromBytes(data)
-}
-
-// Update update pod from file, alias to "UpdateFromFile"
-func (p *Pod) Update(path string) (*corev1.Pod, error) {
-	return p.UpdateFromFile(path)
-}
-
-// ApplyFromRaw apply pod from map[string]interface{}
-func (p *Pod) ApplyFromRaw(raw map[string]interface{}) (*corev1.Pod, error) {
-	pod := &corev1.Pod{}
-	err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, pod)
-	if err != nil {
-		return nil, err
-	}
-
-	var namespace string
-	if len(pod.Namespace) != 0 {
-		namespace = pod.Namespace
-	} else {
-		namespace = p.namespace
-	}
-
-	pod, err = p.clientset.CoreV1().Pods(namespace).Create(p.ctx, pod, p.Options.CreateOptions)
-	if k8serrors.IsAlreadyExists(err) {
-		pod, err = p.clientset.CoreV1().Pods(namespace).Update(p.ctx, pod, p.Options.UpdateOptions)
-	}
-	return pod, err
-}
-
-// ApplyFromBytes apply pod from bytes
-func (p *Pod) ApplyFromBytes(data []byte) (pod *corev1.Pod, err error) {
-	pod, err = p.CreateFromBytes(data)
-	if k8serrors.IsAlreadyExists(err) {
-		log.Debug(err)
-		pod, err = p.UpdateFromBytes(data)
-	}
-	return
-}
-
-// ApplyFromFile apply pod from yaml file
-func (p *Pod) ApplyFromFile(path string) (pod *corev1.Pod, err error) {
-	pod, err = p.CreateFromFile(path)
-	if k8serrors.IsAlreadyExists(err) {
-		pod, err = p.UpdateFromFile(path)
-	}
-	return
-}
-
-// Apply apply pod from file, alias to "ApplyFromFile"
-func (p *Pod) Apply(path string) (*corev1.Pod, error) {
-	return p.ApplyFromFile(path)
-}
-
-// DeleteFromBytes delete pod from bytes
-func (p *Pod) DeleteFromBytes(data []byte) error {
-	podJson, err := yaml.ToJSON(data)
-	if err != nil {
-		return err
-	}
-
-	pod := &corev1.Pod{}
-	err = json.Unmarshal(podJson, pod)
-	if err != nil {
-		return err
-	}
-
-	var namespace string
-	if len(pod.Namespace) != 0 {
-		namespace = pod.Namespace
-	} else {
-		namespace = p.namespace
-	}
-
-	return p.WithNamespace(namespace).DeleteByName(pod.Name)
-}
-
-// DeleteFromFile delete pod from yaml file
-func (p *Pod) DeleteFromFile(path string) error {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return err
-	}
-	return p.DeleteFromBytes(data)
-}
-
-// DeleteByName delete pod by name
-func (p *Pod) DeleteByName(name string) error {
-	return p.clientset.CoreV1().Pods(p.namespace).Delete(p.ctx, name, p.Options.DeleteOptions)
-}
-
-// Delete delete pod by name, alias to "DeleteByName"
-func (p *Pod) Delete(name string) error {
-	return p.DeleteByName(name)
-}
-
-// GetFromBytes get pod from bytes
-func (p *Pod) GetFromBytes(data []byte) (*corev1.Pod, error) {
-	podJson, err := yaml.ToJSON(data)
-	if err != nil {
-		return nil, err
-	}
-
-	pod := &corev1.Pod{}
-	if err = json.Unmarshal(podJson, pod); err != nil {
-		return nil, err
-	}
-
-	var namespace string
-	if len(pod.Namespace) != 0 {
-		namespace = pod.Namespace
-	} else {
-		namespace = p.namespace
-	}
-
-	return p.WithNamespace(namespace).GetByName(pod.Name)
-}
-
-// GetFromFile get pod from yaml file
-func (p *Pod) GetFromFile(path string) (*corev1.Pod, error) {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	return p.GetFromBytes(data)
-}
-
-// GetByName get pod by name
-func (p *Pod) GetByName(name string) (*corev1.Pod, error) {
-	return p.clientset.CoreV1().Pods(p.namespace).Get(p.ctx, name, p.Options.GetOptions)
-}
-
-// Get get pod by name, alias to "GetByName"
-func (p *Pod) Get(name string) (pod *corev1.Pod, err error) {
-	return p.GetByName(name)
-}
-
-// ListByLabel list pods by labels
-func (p *Pod) ListByLabel(labels string) (*corev1.PodList, error) {
-	// TODO: 合并 ListOptions
-	listOptions := p.Options.ListOptions.DeepCopy()
-	listOptions.LabelSelector = labels
-	return p.clientset.CoreV1().Pods(p.namespace).List(p.ctx, *listOptions)
-}
-
-// List list pods by labels, alias to "ListByLabel"
-func (p *Pod) List(labels string) (*corev1.PodList, error) {
-	return p.ListByLabel(labels)
+	return &Pod{p.Handler.WithDryRun()}
 }
 
 // ListByNode list all pods in k8s node where the pod is running
@@ -497,17 +84,7 @@ func (p *Pod) ListByNode(name string) (*corev1.PodList, error) {
 	listOptions := p.Options.ListOptions.DeepCopy()
 	listOptions.FieldSelector = fieldSelector.String()
 
-	return p.clientset.CoreV1().Pods(metav1.NamespaceAll).List(p.ctx, *listOptions)
-}
-
-// ListByNamespace list all pods in the specified namespace
-func (p *Pod) ListByNamespace(namespace string) (*corev1.PodList, error) {
-	return p.WithNamespace(namespace).ListByLabel("")
-}
-
-// ListAll list all pods in k8s cluster where the pod is running
-func (p *Pod) ListAll() (*corev1.PodList, error) {
-	return p.WithNamespace(metav1.NamespaceAll).ListByLabel("")
+	return p.factory.clientset.CoreV1().Pods(metav1.NamespaceAll).List(p.ctx, *listOptions)
 }
 
 // get pod ip
@@ -677,10 +254,7 @@ func (p *Pod) GetPV(name string) (pvList []string, err error) {
 	}
 
 	// 先创建一个用来处理 PersistentVolumeClaim 的对象
-	pvcHandler, err = NewPersistentVolumeClaim(p.ctx, p.namespace, p.kubeconfig)
-	if err != nil {
-		return
-	}
+	pvcHandler = p.factory.PersistentVolumeClaims(p.namespace)
 	// 先获取 pvc list
 	pvcList, err = p.GetPVC(name)
 	if err != nil {
@@ -761,9 +335,7 @@ func (p *Pod) GetController(name string) (*PodController, error) {
 	switch strings.ToLower(ownerRef.Kind) {
 	case ResourceKindPod:
 		var pod *corev1.Pod
-		if podHandler, err = NewPod(p.ctx, p.namespace, p.kubeconfig); err != nil {
-			return nil, err
-		}
+		podHandler = p.factory.Pods(p.namespace)
 		if pod, err = podHandler.Get(oc.Name); err != nil {
 			return nil, err
 		}
@@ -773,9 +345,7 @@ func (p *Pod) GetController(name string) (*PodController, error) {
 		oc.CreationTimestamp = pod.CreationTimestamp
 	case ResourceKindDaemonSet:
 		var ds *appsv1.DaemonSet
-		if dsHandler, err = NewDaemonSet(p.ctx, p.namespace, p.kubeconfig); err != nil {
-			return nil, err
-		}
+		dsHandler = p.factory.DaemonSets(p.namespace)
 		if ds, err = dsHandler.Get(oc.Name); err != nil {
 			return nil, err
 		}
@@ -784,9 +354,7 @@ func (p *Pod) GetController(name string) (*PodController, error) {
 		oc.CreationTimestamp = ds.CreationTimestamp
 	case ResourceKindStatefulSet:
 		var sts *appsv1.StatefulSet
-		if stsHandler, err = NewStatefulSet(p.ctx, p.namespace, p.kubeconfig); err != nil {
-			return nil, err
-		}
+		stsHandler = p.factory.StatefulSets(p.namespace)
 		if sts, err = stsHandler.Get(oc.Name); err != nil {
 			return nil, err
 		}
@@ -795,9 +363,7 @@ func (p *Pod) GetController(name string) (*PodController, error) {
 		oc.CreationTimestamp = sts.CreationTimestamp
 	case ResourceKindJob:
 		var job *batchv1.Job
-		if jobHandler, err = NewJob(p.ctx, p.namespace, p.kubeconfig); err != nil {
-			return nil, err
-		}
+		jobHandler = p.factory.Jobs(p.namespace)
 		if job, err = jobHandler.Get(oc.Name); err != nil {
 			return nil, err
 		}
@@ -806,9 +372,7 @@ func (p *Pod) GetController(name string) (*PodController, error) {
 		oc.CreationTimestamp = job.CreationTimestamp
 	case ResourceKindReplicaSet:
 		var rs *appsv1.ReplicaSet
-		if rsHandler, err = NewReplicaSet(p.ctx, p.namespace, p.kubeconfig); err != nil {
-			return nil, err
-		}
+		rsHandler = p.factory.ReplicaSets(p.namespace)
 		if rs, err = rsHandler.Get(oc.Name); err != nil {
 			return nil, err
 		}
@@ -817,9 +381,7 @@ func (p *Pod) GetController(name string) (*PodController, error) {
 		oc.CreationTimestamp = rs.CreationTimestamp
 	case ResourceKindReplicationController:
 		var rc *corev1.ReplicationController
-		if rcHandler, err = NewReplicationController(p.ctx, p.namespace, p.kubeconfig); err != nil {
-			return nil, err
-		}
+		rcHandler = p.factory.ReplicationControllers(p.namespace)
 		if rc, err = rcHandler.Get(oc.Name); err != nil {
 			return nil, err
 		}
@@ -848,47 +410,9 @@ func (p *Pod) IsReady(name string) bool {
 	return false
 }
 
-// wait for the pod to be in the ready status
-func (p *Pod) WaitReady(name string, check bool) (err error) {
-	var (
-		watcher watch.Interface
-		timeout = int64(0)
-	)
-	// 在 watch 之前就先判断 pod 是否就绪, 如果就绪了就没必要 watch 了
-	if p.IsReady(name) {
-		return
-	}
-	// 是否判断 pod 是否存在
-	if check {
-		if _, err = p.Get(name); err != nil {
-			return
-		}
-	}
-	for {
-		// pod 没有就绪, 那么就开始监听 pod 的事件
-		listOptions := metav1.SingleObject(metav1.ObjectMeta{Name: name, Namespace: p.namespace})
-		listOptions.TimeoutSeconds = &timeout
-		watcher, err = p.clientset.CoreV1().Pods(p.namespace).Watch(p.ctx, listOptions)
-		for event := range watcher.ResultChan() {
-			switch event.Type {
-			case watch.Modified:
-				if p.IsReady(name) {
-					watcher.Stop()
-					return
-				}
-			case watch.Deleted:
-				watcher.Stop()
-				return fmt.Errorf("%s deleted", name)
-			case watch.Bookmark:
-				log.Debug("watch pod: bookmark")
-			case watch.Error:
-				log.Debug("watch pod: error")
-			}
-		}
-		// watcher 因为 keepalive 超时断开了连接, 关闭了 channel
-		log.Debug("watch pod: reconnect to kubernetes")
-		watcher.Stop()
-	}
+// wait for the pod to be in the ready status, it times out after DefaultWaitTimeout
+func (p *Pod) WaitReady(name string, check bool) error {
+	return p.waitReady(name, check)
 }
 
 // wait for the pod to be in the ready status
@@ -935,7 +459,7 @@ func (p *Pod) WaitReady2(name string, check bool) (err error) {
 		for {
 			listOptions := metav1.SingleObject(metav1.ObjectMeta{Name: name, Namespace: p.namespace})
 			listOptions.TimeoutSeconds = &timeout
-			watcher, err = p.clientset.CoreV1().Pods(p.namespace).Watch(p.ctx, listOptions)
+			watcher, err = p.factory.clientset.CoreV1().Pods(p.namespace).Watch(p.ctx, listOptions)
 			for event := range watcher.ResultChan() {
 				switch event.Type {
 				case watch.Modified:
@@ -965,103 +489,6 @@ func (p *Pod) WaitReady2(name string, check bool) (err error) {
 	return
 }
 
-// watch pods by labelSelector
-func (p *Pod) WatchByName(name string,
-	addFunc, modifyFunc, deleteFunc func(x interface{}), x interface{}) (err error) {
-	var (
-		watcher watch.Interface
-		timeout = int64(0)
-		isExist bool
-	)
-	for {
-		listOptions := metav1.SingleObject(metav1.ObjectMeta{Name: name, Namespace: p.namespace})
-		listOptions.TimeoutSeconds = &timeout
-		if watcher, err = p.clientset.CoreV1().Pods(p.namespace).Watch(p.ctx, listOptions); err != nil {
-			return
-		}
-		if _, err = p.Get(name); err != nil {
-			isExist = false // pod not exist
-		} else {
-			isExist = true // pod exist
-		}
-		for event := range watcher.ResultChan() {
-			switch event.Type {
-			case watch.Added:
-				if !isExist {
-					addFunc(x)
-				}
-				isExist = true
-			case watch.Modified:
-				modifyFunc(x)
-				isExist = true
-			case watch.Deleted:
-				deleteFunc(x)
-				isExist = false
-			case watch.Bookmark:
-				log.Debug("watch pod: bookmark")
-			case watch.Error:
-				log.Debug("watch pod: error")
-			}
-		}
-		// If event channel is closed, it means the server has closed the connection
-		log.Debug("watch pod: reconnect to kubernetes")
-		watcher.Stop()
-	}
-}
-
-// watch pods by labelSelector
-func (p *Pod) WatchByLabel(labelSelector string,
-	addFunc, modifyFunc, deleteFunc func(x interface{}), x interface{}) (err error) {
-	var (
-		watcher watch.Interface
-		podList *corev1.PodList
-		timeout = int64(0)
-		isExist bool
-	)
-	for {
-		if watcher, err = p.clientset.CoreV1().Pods(p.namespace).Watch(p.ctx,
-			metav1.ListOptions{LabelSelector: labelSelector, TimeoutSeconds: &timeout}); err != nil {
-			return
-		}
-		if podList, err = p.List(labelSelector); err != nil {
-			return
-		}
-		if len(podList.Items) == 0 {
-			isExist = false // pod not exist
-		} else {
-			isExist = true // pod exist
-		}
-		for event := range watcher.ResultChan() {
-			switch event.Type {
-			case watch.Added:
-				if !isExist {
-					addFunc(x)
-				}
-				isExist = true
-			case watch.Modified:
-				modifyFunc(x)
-				isExist = true
-			case watch.Deleted:
-				deleteFunc(x)
-				isExist = false
-			case watch.Bookmark:
-				log.Debug("watch pod: bookmark")
-			case watch.Error:
-				log.Debug("watch pod: error")
-			}
-		}
-		// If event channel is closed, it means the server has closed the connection
-		log.Debug("watch pod: reconnect to kubernetes")
-		watcher.Stop()
-	}
-}
-
-// watch pods by name, alias to "WatchByName"
-func (p *Pod) Watch(name string,
-	addFunc, modifyFunc, deleteFunc func(x interface{}), x interface{}) (err error) {
-	return p.WatchByName(name, addFunc, modifyFunc, deleteFunc, x)
-}
-
 //type PodExecOptions struct {
 //    metav1.TypeMeta `json:",inline"`
 //    Stdin bool `json:"stdin,omitempty" protobuf:"varint,1,opt,name=stdin"`
@@ -1081,10 +508,14 @@ func (p *Pod) Watch(name string,
 
 // executing remote processes.
 // ref:
-//    https://miminar.fedorapeople.org/_preview/openshift-enterprise/registry-redeploy/go_client/executing_remote_processes.html
-//    https://stackoverflow.com/questions/43314689/example-of-exec-in-k8ss-pod-by-using-go-client
-//    https://github.com/kubernetes/kubernetes/blob/v1.6.1/test/e2e/framework/exec_util.go
+//
+//	https://miminar.fedorapeople.org/_preview/openshift-enterprise/registry-redeploy/go_client/executing_remote_processes.html
+//	https://stackoverflow.com/questions/43314689/example-of-exec-in-k8ss-pod-by-using-go-client
+//	https://github.com/kubernetes/kubernetes/blob/v1.6.1/test/e2e/framework/exec_util.go
 func (p *Pod) Execute(podName, containerName string, command []string) (err error) {
+	if p.factory.config == nil {
+		return fmt.Errorf("the factory has no rest config, can't execute command in pod")
+	}
 	// wait pod to be ready
 	err = p.WaitReady(podName, true)
 	if err != nil {
@@ -1096,92 +527,52 @@ func (p *Pod) Execute(podName, containerName string, command []string) (err erro
 		return
 	}
 
-	// get corev1.Pod
-	pod, err := p.Get(podName)
-	if err != nil {
-		return
+	// Put the terminal into raw mode to prevent it echoing characters twice,
+	// the tty is allocated only if the stdin is a terminal, so it works in
+	// the non-terminal process too.
+	tty := terminal.IsTerminal(int(os.Stdin.Fd()))
+	if tty {
+		oldState, err := terminal.MakeRaw(0)
+		if err != nil {
+			return err
+		}
+		defer terminal.Restore(0, oldState)
 	}
 
+	// Connect the process  std(in,out,err) to the remote shell process.
 	// if containerName is empty, the default containerName default is the name of the
 	// first container in the pod.
-	if len(containerName) == 0 {
-		containerName = pod.Spec.Containers[0].Name
-	}
-
-	// Prepare the API URL used to execute another process within the Pod.  In
-	// this case, we'll run a remote shell.
-	req := p.restClient.Post().
-		Namespace(p.namespace).
-		Resource("pods").
-		Name(podName).
-		SubResource("exec").
-		VersionedParams(&corev1.PodExecOptions{
-			Container: containerName,
-			Command:   command,
-			Stdin:     true,
-			Stdout:    true,
-			Stderr:    true,
-			TTY:       true,
-		}, scheme.ParameterCodec)
-
-	exec, err := remotecommand.NewSPDYExecutor(p.config, "POST", req.URL())
-	if err != nil {
-		return
+	opts := ExecOptions{
+		Container: containerName,
+		Command:   command,
+		Stdin:     os.Stdin,
+		Stdout:    os.Stdout,
+		Stderr:    os.Stderr,
+		TTY:       tty,
 	}
-
-	// Put the terminal into raw mode to prevent it echoing characters twice
-	oldState, err := terminal.MakeRaw(0)
-	if err != nil {
-		return
+	// resize the remote tty when the local terminal is resized, eg: vim, top.
+	if tty {
+		ctx, cancel := context.WithCancel(p.ctx)
+		defer cancel()
+		opts.Resize = MonitorTerminalSize(ctx, int(os.Stdout.Fd()))
 	}
-	defer terminal.Restore(0, oldState)
-
-	// Connect the process  std(in,out,err) to the remote shell process.
-	err = exec.Stream(remotecommand.StreamOptions{
-		Stdin:  os.Stdin,
-		Stdout: os.Stdout,
-		Stderr: os.Stderr,
-		Tty:    true,
-	})
-	if err != nil {
-		return
-	}
-
-	return
+	return p.stream(podName, opts)
 }
 
 func (p *Pod) TestInformer(stopCh chan struct{}) {
-	p.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
+	p.RunInformer(
+		func(obj interface{}) {
 			myObj := obj.(metav1.Object)
 			log.Infof("New Pod Added to Store: %s", myObj.GetName())
 		},
-		UpdateFunc: func(oldObj, newObj interface{}) {
+		func(oldObj, newObj interface{}) {
 			oObj := oldObj.(metav1.Object)
 			nObj := newObj.(metav1.Object)
 			log.Infof("%s Pod Updated to %s", oObj.GetName(), nObj.GetName())
 		},
-		DeleteFunc: func(obj interface{}) {
+		func(obj interface{}) {
 			myObj := obj.(metav1.Object)
 			log.Infof("Pod Deleted from Store: %s", myObj.GetName())
 		},
-	})
-	p.informer.Run(stopCh)
-}
-
-// addFunc, updateFunc, stopChan
-// informer 的三个回调函数 addFunc, updateFunc, deleteFunc
-// 这个管道用来存放回调函数处理的 k8s 资源对象
-// RunInformer
-func (p *Pod) RunInformer(
-	addFunc func(obj interface{}),
-	updateFunc func(oldObj, newObj interface{}),
-	deleteFunc func(obj interface{}),
-	stopCh chan struct{}) {
-	p.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    addFunc,
-		UpdateFunc: updateFunc,
-		DeleteFunc: deleteFunc,
-	})
-	p.informer.Run(stopCh)
+		stopCh)
 }
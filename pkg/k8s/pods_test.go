@@ -0,0 +1,107 @@
+package k8s
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestPod(name string, ready bool, owners ...metav1.OwnerReference) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       testNamespace,
+			Labels:          map[string]string{"app": "nginx"},
+			OwnerReferences: owners,
+		},
+		Spec: corev1.PodSpec{
+			NodeName:   "node1",
+			Containers: []corev1.Container{{Name: "nginx", Image: "nginx:1.21"}},
+			Volumes: []corev1.Volume{{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "data"},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{
+			PodIP:             "10.244.0.10",
+			HostIP:            "192.168.1.10",
+			Conditions:        []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "nginx", Image: "nginx:1.21", Ready: ready}},
+		},
+	}
+}
+
+func TestPodIsReady(t *testing.T) {
+	pod := newTestFactory(newTestPod("ready", true), newTestPod("notready", false)).Pods(testNamespace)
+	if !pod.IsReady("ready") {
+		t.Errorf("IsReady(ready) = false, want true")
+	}
+	if pod.IsReady("notready") {
+		t.Errorf("IsReady(notready) = true, want false")
+	}
+	if pod.IsReady("notexist") {
+		t.Errorf("IsReady(notexist) = true, want false")
+	}
+}
+
+func TestPodGetters(t *testing.T) {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: testNamespace},
+		Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: "pv-data"},
+	}
+	pod := newTestFactory(newTestPod("nginx", true), pvc).Pods(testNamespace)
+
+	if ip, err := pod.GetIP("nginx"); err != nil || ip != "10.244.0.10" {
+		t.Errorf("GetIP = %q, %v", ip, err)
+	}
+	if ip, err := pod.GetNodeIP("nginx"); err != nil || ip != "192.168.1.10" {
+		t.Errorf("GetNodeIP = %q, %v", ip, err)
+	}
+	if node, err := pod.GetNodeName("nginx"); err != nil || node != "node1" {
+		t.Errorf("GetNodeName = %q, %v", node, err)
+	}
+	if containers, err := pod.GetContainers("nginx"); err != nil || len(containers) != 1 || containers[0].Image != "nginx:1.21" {
+		t.Errorf("GetContainers = %v, %v", containers, err)
+	}
+	if pvcs, err := pod.GetPVC("nginx"); err != nil || len(pvcs) != 1 || pvcs[0] != "data" {
+		t.Errorf("GetPVC = %v, %v", pvcs, err)
+	}
+	if pvs, err := pod.GetPV("nginx"); err != nil || len(pvs) != 1 || pvs[0] != "pv-data" {
+		t.Errorf("GetPV = %v, %v", pvs, err)
+	}
+}
+
+func TestPodGetController(t *testing.T) {
+	controller := true
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx-5d9f", Namespace: testNamespace, Labels: map[string]string{"app": "nginx"}},
+		Status:     appsv1.ReplicaSetStatus{Replicas: 2, ReadyReplicas: 1},
+	}
+	owner := metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: rs.Name, Controller: &controller}
+	pod := newTestFactory(rs, newTestPod("nginx-5d9f-abcde", true, owner), newTestPod("orphan", true)).Pods(testNamespace)
+
+	oc, err := pod.GetController("nginx-5d9f-abcde")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oc.Name != rs.Name || oc.Kind != "ReplicaSet" {
+		t.Errorf("controller = %s/%s, want ReplicaSet/%s", oc.Kind, oc.Name, rs.Name)
+	}
+	if oc.Ready != "1/2" {
+		t.Errorf("controller ready = %q, want %q", oc.Ready, "1/2")
+	}
+	if len(oc.Images) != 1 || oc.Images[0] != "nginx:1.21" {
+		t.Errorf("controller images = %v", oc.Images)
+	}
+	if _, err := pod.GetController("orphan"); err == nil {
+		t.Errorf("GetController(orphan) returns no error")
+	}
+}
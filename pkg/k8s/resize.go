@@ -0,0 +1,75 @@
+package k8s
+
+import (
+	"context"
+	"os"
+
+	"golang.org/x/crypto/ssh/terminal"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// sizeQueue is the remotecommand.TerminalSizeQueue of a resize channel.
+type sizeQueue struct {
+	ch   <-chan remotecommand.TerminalSize
+	done <-chan struct{}
+}
+
+// Next returns the next size of the terminal, nil if the channel is closed
+// or the stream is done.
+func (q sizeQueue) Next() *remotecommand.TerminalSize {
+	select {
+	case size, ok := <-q.ch:
+		if !ok {
+			return nil
+		}
+		return &size
+	case <-q.done:
+		return nil
+	}
+}
+
+// MonitorTerminalSize sends the size of the local terminal fd to the returned
+// channel, first the current size, then the new size every time the terminal
+// is resized (SIGWINCH), until ctx is done, then the channel is closed. It's
+// used as ExecOptions.Resize, eg:
+//
+//	ctx, cancel := context.WithCancel(context.Background())
+//	defer cancel()
+//	exitCode, err := p.Exec("nginx", k8s.ExecOptions{
+//		Command: []string{"bash"},
+//		Stdin:   os.Stdin,
+//		Stdout:  os.Stdout,
+//		TTY:     true,
+//		Resize:  k8s.MonitorTerminalSize(ctx, int(os.Stdout.Fd())),
+//	})
+//
+// The resize is not notified on windows, only the current size is sent.
+func MonitorTerminalSize(ctx context.Context, fd int) <-chan remotecommand.TerminalSize {
+	ch := make(chan remotecommand.TerminalSize, 1)
+	signals := make(chan os.Signal, 1)
+	notifyResize(signals)
+	go func() {
+		defer close(ch)
+		defer stopNotifyResize(signals)
+		var last remotecommand.TerminalSize
+		for {
+			if width, height, err := terminal.GetSize(fd); err == nil {
+				size := remotecommand.TerminalSize{Width: uint16(width), Height: uint16(height)}
+				if size != last {
+					select {
+					case ch <- size:
+						last = size
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+			select {
+			case <-signals:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package k8s
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func notifyResize(signals chan<- os.Signal) {
+	signal.Notify(signals, syscall.SIGWINCH)
+}
+
+func stopNotifyResize(signals chan<- os.Signal) {
+	signal.Stop(signals)
+}
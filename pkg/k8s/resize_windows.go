@@ -0,0 +1,8 @@
+package k8s
+
+import "os"
+
+// there is no SIGWINCH on windows, the terminal size is not monitored.
+func notifyResize(signals chan<- os.Signal) {}
+
+func stopNotifyResize(signals chan<- os.Signal) {}
@@ -0,0 +1,335 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// AnnotationRestartedAt is the pod template annotation bumped by Restart,
+	// same as "kubectl rollout restart".
+	AnnotationRestartedAt = "kubectl.kubernetes.io/restartedAt"
+	// AnnotationRevision is the revision of a deployment and its replicasets.
+	AnnotationRevision = "deployment.kubernetes.io/revision"
+	// AnnotationChangeCause is the reason of a revision, shown by History.
+	AnnotationChangeCause = "kubernetes.io/change-cause"
+)
+
+// annotations of the replicaset which are not copied to the deployment by
+// Undo, same as kubectl.
+var undoSkippedAnnotations = map[string]bool{
+	corev1.LastAppliedConfigAnnotation:          true,
+	AnnotationRevision:                          true,
+	"deployment.kubernetes.io/revision-history": true,
+	"deployment.kubernetes.io/desired-replicas": true,
+	"deployment.kubernetes.io/max-replicas":     true,
+	"deprecated.deployment.rollback.to":         true,
+}
+
+// RolloutRevision is a revision in the rollout history of a deployment,
+// statefulset or daemonset.
+type RolloutRevision struct {
+	Revision int64
+	// Name is the name of the replicaset of the deployment revision, or the
+	// controllerrevision of the statefulset and daemonset revision.
+	Name              string
+	ChangeCause       string
+	Template          corev1.PodTemplateSpec
+	CreationTimestamp metav1.Time
+}
+
+// restartPatch returns the strategic merge patch bumping the restartedAt
+// annotation of the pod template, the pods are recreated by the rollout.
+func restartPatch() []byte {
+	return []byte(fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`,
+		AnnotationRestartedAt, time.Now().Format(time.RFC3339)))
+}
+
+// isControlledBy reports whether the controller of obj is owner.
+func isControlledBy(obj, owner metav1.Object) bool {
+	ref := metav1.GetControllerOf(obj)
+	return ref != nil && ref.UID == owner.GetUID()
+}
+
+// findRevision returns the revision in the history, the previous revision
+// before current if revision is 0.
+func findRevision(history []RolloutRevision, revision, current int64) (*RolloutRevision, error) {
+	var found *RolloutRevision
+	for i := range history {
+		r := &history[i]
+		switch {
+		case revision != 0 && r.Revision == revision:
+			return r, nil
+		case revision == 0 && r.Revision < current && (found == nil || r.Revision > found.Revision):
+			found = r
+		}
+	}
+	if found == nil {
+		if revision == 0 {
+			return nil, fmt.Errorf("no rollout history found")
+		}
+		return nil, fmt.Errorf("unable to find specified revision %d in history", revision)
+	}
+	return found, nil
+}
+
+func sortRevisions(history []RolloutRevision) {
+	sort.Slice(history, func(i, j int) bool { return history[i].Revision < history[j].Revision })
+}
+
+// controllerRevisions returns the history of the statefulset or daemonset
+// from the controllerrevisions it owns.
+func (f *Factory) controllerRevisions(namespace string, owner metav1.Object, selector *metav1.LabelSelector) ([]RolloutRevision, error) {
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := f.clientset.AppsV1().ControllerRevisions(namespace).List(f.ctx,
+		metav1.ListOptions{LabelSelector: labelSelector.String()})
+	if err != nil {
+		return nil, err
+	}
+	var history []RolloutRevision
+	for i := range revisions.Items {
+		cr := &revisions.Items[i]
+		if !isControlledBy(cr, owner) {
+			continue
+		}
+		// the data is a patch replacing the pod template.
+		var data struct {
+			Spec struct {
+				Template corev1.PodTemplateSpec `json:"template"`
+			} `json:"spec"`
+		}
+		if len(cr.Data.Raw) != 0 {
+			if err := json.Unmarshal(cr.Data.Raw, &data); err != nil {
+				return nil, fmt.Errorf("controllerrevision %s: %w", cr.Name, err)
+			}
+		}
+		history = append(history, RolloutRevision{
+			Revision:          cr.Revision,
+			Name:              cr.Name,
+			ChangeCause:       cr.Annotations[AnnotationChangeCause],
+			Template:          data.Spec.Template,
+			CreationTimestamp: cr.CreationTimestamp,
+		})
+	}
+	sortRevisions(history)
+	return history, nil
+}
+
+// controllerRevisionData returns the data of the controllerrevision of the
+// statefulset or daemonset, it's the patch rolling back to the revision.
+// The data is nil if the revision is already the current one.
+func (f *Factory) controllerRevisionData(namespace string, owner metav1.Object, selector *metav1.LabelSelector, revision int64) ([]byte, error) {
+	history, err := f.controllerRevisions(namespace, owner, selector)
+	if err != nil {
+		return nil, err
+	}
+	current := int64(0)
+	for _, r := range history {
+		if r.Revision > current {
+			current = r.Revision
+		}
+	}
+	target, err := findRevision(history, revision, current)
+	if err != nil {
+		return nil, err
+	}
+	if target.Revision == current {
+		return nil, nil
+	}
+	cr, err := f.clientset.AppsV1().ControllerRevisions(namespace).Get(f.ctx, target.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return cr.Data.Raw, nil
+}
+
+// Restart restarts the pods of the deployment like "kubectl rollout restart",
+// by bumping the restartedAt annotation of the pod template.
+func (d *Deployment) Restart(name string) (*appsv1.Deployment, error) {
+	return d.client(d.namespace).Patch(d.ctx, name, types.StrategicMergePatchType, restartPatch(), d.Options.PatchOptions)
+}
+
+// Pause pauses the rollout of the deployment, the changes of the pod template
+// don't trigger a rollout until it's resumed.
+func (d *Deployment) Pause(name string) (*appsv1.Deployment, error) {
+	return d.client(d.namespace).Patch(d.ctx, name, types.StrategicMergePatchType, []byte(`{"spec":{"paused":true}}`), d.Options.PatchOptions)
+}
+
+// Resume resumes the paused rollout of the deployment.
+func (d *Deployment) Resume(name string) (*appsv1.Deployment, error) {
+	return d.client(d.namespace).Patch(d.ctx, name, types.StrategicMergePatchType, []byte(`{"spec":{"paused":false}}`), d.Options.PatchOptions)
+}
+
+// History returns the rollout history of the deployment, from the replicasets
+// it owns, sorted by revision.
+func (d *Deployment) History(name string) ([]RolloutRevision, error) {
+	deploy, err := d.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return d.history(deploy)
+}
+
+func (d *Deployment) history(deploy *appsv1.Deployment) ([]RolloutRevision, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deploy.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	replicaSets, err := d.factory.clientset.AppsV1().ReplicaSets(deploy.Namespace).List(d.ctx,
+		metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	var history []RolloutRevision
+	for i := range replicaSets.Items {
+		rs := &replicaSets.Items[i]
+		if !isControlledBy(rs, deploy) {
+			continue
+		}
+		revision, err := strconv.ParseInt(rs.Annotations[AnnotationRevision], 10, 64)
+		if err != nil {
+			log.Debugf("replicaset %s has no revision: %v", rs.Name, err)
+			continue
+		}
+		history = append(history, RolloutRevision{
+			Revision:          revision,
+			Name:              rs.Name,
+			ChangeCause:       rs.Annotations[AnnotationChangeCause],
+			Template:          rs.Spec.Template,
+			CreationTimestamp: rs.CreationTimestamp,
+		})
+	}
+	sortRevisions(history)
+	return history, nil
+}
+
+// Undo rolls the deployment back to the revision, or to the previous revision
+// if revision is 0, like "kubectl rollout undo". A paused deployment can't be
+// rolled back.
+func (d *Deployment) Undo(name string, revision int64) (*appsv1.Deployment, error) {
+	deploy, err := d.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if deploy.Spec.Paused {
+		return nil, fmt.Errorf("can't roll back paused deployment %s, resume it first", name)
+	}
+	history, err := d.history(deploy)
+	if err != nil {
+		return nil, err
+	}
+	current, _ := strconv.ParseInt(deploy.Annotations[AnnotationRevision], 10, 64)
+	target, err := findRevision(history, revision, current)
+	if err != nil {
+		return nil, err
+	}
+	if target.Revision == current {
+		log.Debugf("deployment %s is already at revision %d", name, current)
+		return deploy, nil
+	}
+
+	// replace the pod template with the template of the replicaset, without
+	// the pod-template-hash label added by the deployment controller.
+	template := target.Template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	annotations := make(map[string]string)
+	for key, value := range deploy.Annotations {
+		annotations[key] = value
+	}
+	delete(annotations, AnnotationChangeCause)
+	rs, err := d.factory.clientset.AppsV1().ReplicaSets(deploy.Namespace).Get(d.ctx, target.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range rs.Annotations {
+		if !undoSkippedAnnotations[key] {
+			annotations[key] = value
+		}
+	}
+	patch, err := json.Marshal([]map[string]interface{}{
+		{"op": "replace", "path": "/spec/template", "value": template},
+		{"op": "replace", "path": "/metadata/annotations", "value": annotations},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d.client(d.namespace).Patch(d.ctx, name, types.JSONPatchType, patch, d.Options.PatchOptions)
+}
+
+// Restart restarts the pods of the statefulset like "kubectl rollout restart".
+func (s *StatefulSet) Restart(name string) (*appsv1.StatefulSet, error) {
+	return s.client(s.namespace).Patch(s.ctx, name, types.StrategicMergePatchType, restartPatch(), s.Options.PatchOptions)
+}
+
+// History returns the rollout history of the statefulset, from the
+// controllerrevisions it owns, sorted by revision.
+func (s *StatefulSet) History(name string) ([]RolloutRevision, error) {
+	sts, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.factory.controllerRevisions(sts.Namespace, sts, sts.Spec.Selector)
+}
+
+// Undo rolls the statefulset back to the revision, or to the previous
+// revision if revision is 0.
+func (s *StatefulSet) Undo(name string, revision int64) (*appsv1.StatefulSet, error) {
+	sts, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.factory.controllerRevisionData(sts.Namespace, sts, sts.Spec.Selector, revision)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		log.Debugf("statefulset %s is already at revision %d", name, revision)
+		return sts, nil
+	}
+	return s.client(s.namespace).Patch(s.ctx, name, types.StrategicMergePatchType, data, s.Options.PatchOptions)
+}
+
+// Restart restarts the pods of the daemonset like "kubectl rollout restart".
+func (d *DaemonSet) Restart(name string) (*appsv1.DaemonSet, error) {
+	return d.client(d.namespace).Patch(d.ctx, name, types.StrategicMergePatchType, restartPatch(), d.Options.PatchOptions)
+}
+
+// History returns the rollout history of the daemonset, from the
+// controllerrevisions it owns, sorted by revision.
+func (d *DaemonSet) History(name string) ([]RolloutRevision, error) {
+	ds, err := d.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return d.factory.controllerRevisions(ds.Namespace, ds, ds.Spec.Selector)
+}
+
+// Undo rolls the daemonset back to the revision, or to the previous revision
+// if revision is 0.
+func (d *DaemonSet) Undo(name string, revision int64) (*appsv1.DaemonSet, error) {
+	ds, err := d.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.factory.controllerRevisionData(ds.Namespace, ds, ds.Spec.Selector, revision)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		log.Debugf("daemonset %s is already at revision %d", name, revision)
+		return ds, nil
+	}
+	return d.client(d.namespace).Patch(d.ctx, name, types.StrategicMergePatchType, data, d.Options.PatchOptions)
+}
@@ -0,0 +1,193 @@
+package k8s
+
+import (
+	"fmt"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestTemplate(image string) corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "nginx"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: image}}},
+	}
+}
+
+func newTestOwnerRef(kind, name string, uid types.UID) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: kind, Name: name, UID: uid, Controller: &controller}}
+}
+
+func newTestReplicaSet(revision int, owner types.UID) *appsv1.ReplicaSet {
+	template := newTestTemplate(fmt.Sprintf("nginx:%d", revision))
+	template.Labels[appsv1.DefaultDeploymentUniqueLabelKey] = fmt.Sprintf("hash%d", revision)
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("nginx-%s-%d", owner, revision),
+			Namespace: testNamespace,
+			Labels:    map[string]string{"app": "nginx"},
+			Annotations: map[string]string{
+				AnnotationRevision:    fmt.Sprint(revision),
+				AnnotationChangeCause: fmt.Sprintf("image nginx:%d", revision),
+			},
+			OwnerReferences: newTestOwnerRef("Deployment", "nginx", owner),
+		},
+		Spec: appsv1.ReplicaSetSpec{Template: template},
+	}
+}
+
+func TestDeploymentRollout(t *testing.T) {
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "nginx",
+			Namespace:   testNamespace,
+			UID:         "deploy",
+			Annotations: map[string]string{AnnotationRevision: "3", AnnotationChangeCause: "image nginx:3"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+			Template: newTestTemplate("nginx:3"),
+		},
+	}
+	d := newTestFactory(deploy,
+		newTestReplicaSet(2, "deploy"), newTestReplicaSet(1, "deploy"), newTestReplicaSet(3, "deploy"),
+		newTestReplicaSet(4, "other"),
+	).Deployments(testNamespace)
+
+	history, err := d.History("nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 || history[0].Revision != 1 || history[2].Revision != 3 || history[1].ChangeCause != "image nginx:2" {
+		t.Fatalf("history = %+v", history)
+	}
+
+	// undo to the previous revision.
+	undone, err := d.Undo("nginx", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := undone.Spec.Template.Spec.Containers[0].Image; image != "nginx:2" {
+		t.Errorf("image = %s, want nginx:2", image)
+	}
+	if _, ok := undone.Spec.Template.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok {
+		t.Errorf("template labels = %v, want no pod-template-hash", undone.Spec.Template.Labels)
+	}
+	if cause := undone.Annotations[AnnotationChangeCause]; cause != "image nginx:2" {
+		t.Errorf("change-cause = %q", cause)
+	}
+	if undone.Annotations[AnnotationRevision] != "3" {
+		t.Errorf("revision annotation = %q, want kept", undone.Annotations[AnnotationRevision])
+	}
+	if _, err := d.Undo("nginx", 9); err == nil {
+		t.Error("undo to a revision not in the history succeeded")
+	}
+
+	// a paused deployment can't be rolled back.
+	paused, err := d.Pause("nginx")
+	if err != nil || !paused.Spec.Paused {
+		t.Fatalf("Pause: %v, paused = %v", err, paused.Spec.Paused)
+	}
+	if _, err := d.Undo("nginx", 1); err == nil {
+		t.Error("undo a paused deployment succeeded")
+	}
+	if resumed, err := d.Resume("nginx"); err != nil || resumed.Spec.Paused {
+		t.Fatalf("Resume: %v", err)
+	}
+
+	restarted, err := d.Restart("nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := restarted.Spec.Template.Annotations[AnnotationRestartedAt]; !ok {
+		t.Errorf("template annotations = %v, want restartedAt", restarted.Spec.Template.Annotations)
+	}
+}
+
+func newTestControllerRevision(kind string, revision int64, owner types.UID) *appsv1.ControllerRevision {
+	data := fmt.Sprintf(`{"spec":{"template":{"$patch":"replace","metadata":{"labels":{"app":"nginx"}},"spec":{"containers":[{"name":"nginx","image":"nginx:%d"}]}}}}`, revision)
+	return &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            fmt.Sprintf("nginx-%s-%d", owner, revision),
+			Namespace:       testNamespace,
+			Labels:          map[string]string{"app": "nginx"},
+			OwnerReferences: newTestOwnerRef(kind, "nginx", owner),
+		},
+		Data:     runtime.RawExtension{Raw: []byte(data)},
+		Revision: revision,
+	}
+}
+
+func TestStatefulSetDaemonSetRollout(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: testNamespace, UID: "sts"},
+		Spec:       appsv1.StatefulSetSpec{Selector: selector, Template: newTestTemplate("nginx:2")},
+	}
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: testNamespace, UID: "ds"},
+		Spec:       appsv1.DaemonSetSpec{Selector: selector, Template: newTestTemplate("nginx:2")},
+	}
+	f := newTestFactory(sts, ds,
+		newTestControllerRevision("StatefulSet", 1, "sts"), newTestControllerRevision("StatefulSet", 2, "sts"),
+		newTestControllerRevision("DaemonSet", 1, "ds"), newTestControllerRevision("DaemonSet", 2, "ds"),
+	)
+
+	history, err := f.StatefulSets(testNamespace).History("nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Template.Spec.Containers[0].Image != "nginx:1" {
+		t.Fatalf("statefulset history = %+v", history)
+	}
+	// undo to the current revision doesn't patch the objects.
+	if current, err := f.StatefulSets(testNamespace).Undo("nginx", 2); err != nil || current.Spec.Template.Spec.Containers[0].Image != "nginx:2" {
+		t.Fatalf("statefulset undo to the current revision = %+v, %v", current, err)
+	}
+	if current, err := f.DaemonSets(testNamespace).Undo("nginx", 2); err != nil || current.Spec.Template.Spec.Containers[0].Image != "nginx:2" {
+		t.Fatalf("daemonset undo to the current revision = %+v, %v", current, err)
+	}
+	for _, action := range f.clientset.(*fake.Clientset).Actions() {
+		if action.GetVerb() == "patch" {
+			t.Errorf("%s patched, want unchanged", action.GetResource().Resource)
+		}
+	}
+	if _, err := f.StatefulSets(testNamespace).Undo("nginx", 9); err == nil {
+		t.Error("statefulset undo to a revision not in the history succeeded")
+	}
+	if _, err := f.DaemonSets(testNamespace).Undo("nginx", 9); err == nil {
+		t.Error("daemonset undo to a revision not in the history succeeded")
+	}
+
+	undoneSts, err := f.StatefulSets(testNamespace).Undo("nginx", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := undoneSts.Spec.Template.Spec.Containers[0].Image; image != "nginx:1" {
+		t.Errorf("statefulset image = %s, want nginx:1", image)
+	}
+
+	if history, err = f.DaemonSets(testNamespace).History("nginx"); err != nil || len(history) != 2 {
+		t.Fatalf("daemonset history = %+v, %v", history, err)
+	}
+	undoneDs, err := f.DaemonSets(testNamespace).Undo("nginx", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := undoneDs.Spec.Template.Spec.Containers[0].Image; image != "nginx:1" {
+		t.Errorf("daemonset image = %s, want nginx:1", image)
+	}
+	restarted, err := f.DaemonSets(testNamespace).Restart("nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := restarted.Spec.Template.Annotations[AnnotationRestartedAt]; !ok {
+		t.Errorf("template annotations = %v, want restartedAt", restarted.Spec.Template.Annotations)
+	}
+}
@@ -0,0 +1,244 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// ErrProgressDeadlineExceeded is returned by RolloutStatus and WaitRollout if
+// the deployment exceeded its progress deadline, the rollout won't complete
+// without a change of the deployment.
+var ErrProgressDeadlineExceeded = errors.New("progress deadline exceeded")
+
+// RolloutStatus is the rollout status of a deployment, statefulset or
+// daemonset, same as "kubectl rollout status".
+type RolloutStatus struct {
+	// Message describes the progress of the rollout,
+	// eg: "Waiting for deployment "nginx" rollout to finish: 1 of 3 updated replicas are available..."
+	Message string
+	// Done means the rollout is complete.
+	Done bool
+
+	Generation         int64
+	ObservedGeneration int64
+	// Replicas is the desired replicas of the deployment and statefulset, or
+	// the desired number of scheduled pods of the daemonset.
+	Replicas          int32
+	UpdatedReplicas   int32
+	ReadyReplicas     int32
+	AvailableReplicas int32
+}
+
+// deploymentRolloutStatus returns the rollout status of the deployment, it
+// returns ErrProgressDeadlineExceeded if the Progressing condition has the
+// reason ProgressDeadlineExceeded.
+func deploymentRolloutStatus(deploy *appsv1.Deployment) (*RolloutStatus, error) {
+	status := &RolloutStatus{
+		Generation:         deploy.Generation,
+		ObservedGeneration: deploy.Status.ObservedGeneration,
+		Replicas:           1,
+		UpdatedReplicas:    deploy.Status.UpdatedReplicas,
+		ReadyReplicas:      deploy.Status.ReadyReplicas,
+		AvailableReplicas:  deploy.Status.AvailableReplicas,
+	}
+	if deploy.Spec.Replicas != nil {
+		status.Replicas = *deploy.Spec.Replicas
+	}
+	// the status is stale until the controller observes the latest spec.
+	if deploy.Generation > deploy.Status.ObservedGeneration {
+		status.Message = "Waiting for deployment spec update to be observed..."
+		return status, nil
+	}
+	for _, cond := range deploy.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+			return status, fmt.Errorf("deployment %q: %w", deploy.Name, ErrProgressDeadlineExceeded)
+		}
+	}
+	switch {
+	case status.UpdatedReplicas < status.Replicas:
+		status.Message = fmt.Sprintf("Waiting for deployment %q rollout to finish: %d out of %d new replicas have been updated...",
+			deploy.Name, status.UpdatedReplicas, status.Replicas)
+	case deploy.Status.Replicas > status.UpdatedReplicas:
+		status.Message = fmt.Sprintf("Waiting for deployment %q rollout to finish: %d old replicas are pending termination...",
+			deploy.Name, deploy.Status.Replicas-status.UpdatedReplicas)
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		status.Message = fmt.Sprintf("Waiting for deployment %q rollout to finish: %d of %d updated replicas are available...",
+			deploy.Name, status.AvailableReplicas, status.UpdatedReplicas)
+	default:
+		status.Message = fmt.Sprintf("deployment %q successfully rolled out", deploy.Name)
+		status.Done = true
+	}
+	return status, nil
+}
+
+// statefulSetRolloutStatus returns the rollout status of the statefulset,
+// only the RollingUpdate strategy is supported.
+func statefulSetRolloutStatus(sts *appsv1.StatefulSet) (*RolloutStatus, error) {
+	status := &RolloutStatus{
+		Generation:         sts.Generation,
+		ObservedGeneration: sts.Status.ObservedGeneration,
+		Replicas:           1,
+		UpdatedReplicas:    sts.Status.UpdatedReplicas,
+		ReadyReplicas:      sts.Status.ReadyReplicas,
+		AvailableReplicas:  sts.Status.AvailableReplicas,
+	}
+	if sts.Spec.Replicas != nil {
+		status.Replicas = *sts.Spec.Replicas
+	}
+	if sts.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		return status, fmt.Errorf("rollout status is only available for %s strategy type", appsv1.RollingUpdateStatefulSetStrategyType)
+	}
+	if sts.Status.ObservedGeneration == 0 || sts.Generation > sts.Status.ObservedGeneration {
+		status.Message = "Waiting for statefulset spec update to be observed..."
+		return status, nil
+	}
+	if status.ReadyReplicas < status.Replicas {
+		status.Message = fmt.Sprintf("Waiting for %d pods to be ready...", status.Replicas-status.ReadyReplicas)
+		return status, nil
+	}
+	// only the pods with ordinal >= partition are updated.
+	if rollingUpdate := sts.Spec.UpdateStrategy.RollingUpdate; rollingUpdate != nil && rollingUpdate.Partition != nil {
+		if partition := *rollingUpdate.Partition; partition > 0 && status.UpdatedReplicas < status.Replicas-partition {
+			status.Message = fmt.Sprintf("Waiting for partitioned roll out to finish: %d out of %d new pods have been updated...",
+				status.UpdatedReplicas, status.Replicas-partition)
+			return status, nil
+		}
+		status.Message = fmt.Sprintf("partitioned roll out complete: %d new pods have been updated...", status.UpdatedReplicas)
+		status.Done = true
+		return status, nil
+	}
+	if sts.Status.UpdateRevision != sts.Status.CurrentRevision {
+		status.Message = fmt.Sprintf("waiting for statefulset rolling update to complete %d pods at revision %s...",
+			status.UpdatedReplicas, sts.Status.UpdateRevision)
+		return status, nil
+	}
+	status.Message = fmt.Sprintf("statefulset rolling update complete %d pods at revision %s...",
+		sts.Status.CurrentReplicas, sts.Status.CurrentRevision)
+	status.Done = true
+	return status, nil
+}
+
+// daemonSetRolloutStatus returns the rollout status of the daemonset, only
+// the RollingUpdate strategy is supported.
+func daemonSetRolloutStatus(ds *appsv1.DaemonSet) (*RolloutStatus, error) {
+	status := &RolloutStatus{
+		Generation:         ds.Generation,
+		ObservedGeneration: ds.Status.ObservedGeneration,
+		Replicas:           ds.Status.DesiredNumberScheduled,
+		UpdatedReplicas:    ds.Status.UpdatedNumberScheduled,
+		ReadyReplicas:      ds.Status.NumberReady,
+		AvailableReplicas:  ds.Status.NumberAvailable,
+	}
+	if ds.Spec.UpdateStrategy.Type != appsv1.RollingUpdateDaemonSetStrategyType {
+		return status, fmt.Errorf("rollout status is only available for %s strategy type", appsv1.RollingUpdateDaemonSetStrategyType)
+	}
+	if ds.Generation > ds.Status.ObservedGeneration {
+		status.Message = "Waiting for daemon set spec update to be observed..."
+		return status, nil
+	}
+	switch {
+	case status.UpdatedReplicas < status.Replicas:
+		status.Message = fmt.Sprintf("Waiting for daemon set %q rollout to finish: %d out of %d new pods have been updated...",
+			ds.Name, status.UpdatedReplicas, status.Replicas)
+	case status.AvailableReplicas < status.Replicas:
+		status.Message = fmt.Sprintf("Waiting for daemon set %q rollout to finish: %d of %d updated pods are available...",
+			ds.Name, status.AvailableReplicas, status.Replicas)
+	default:
+		status.Message = fmt.Sprintf("daemon set %q successfully rolled out", ds.Name)
+		status.Done = true
+	}
+	return status, nil
+}
+
+// waitRollout watches the object until its rollout status is done, progress
+// is called every time the status message changes. It returns immediately if
+// rolloutStatus returns an error, eg: the progress deadline is exceeded. No
+// timeout if timeout is 0.
+func (h *Handler[T, TList]) waitRollout(name string, timeout time.Duration,
+	rolloutStatus func(obj *T) (*RolloutStatus, error), progress func(RolloutStatus)) error {
+	ctx, cancel := h.ctx, context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(h.ctx, timeout)
+	}
+	defer cancel()
+
+	var last *RolloutStatus
+	err := h.watchUntil(ctx, name, func(obj *T) (bool, error) {
+		if obj == nil {
+			return false, fmt.Errorf("%s %s not found", h.kind, name)
+		}
+		status, err := rolloutStatus(obj)
+		if err != nil {
+			return false, err
+		}
+		if progress != nil && (last == nil || last.Message != status.Message) {
+			progress(*status)
+		}
+		last = status
+		return status.Done, nil
+	})
+	if err != nil && ctx.Err() != nil && last != nil {
+		return fmt.Errorf("wait for the rollout of %s %s: %w, %s", h.kind, name, err, last.Message)
+	}
+	return err
+}
+
+// RolloutStatus returns the rollout status of the deployment, like
+// "kubectl rollout status --watch=false". The error wraps
+// ErrProgressDeadlineExceeded if the deployment exceeded its progress deadline.
+func (d *Deployment) RolloutStatus(name string) (*RolloutStatus, error) {
+	deploy, err := d.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return deploymentRolloutStatus(deploy)
+}
+
+// WaitRollout waits for the rollout of the deployment to complete, like
+// "kubectl rollout status". Unlike WaitReady, it waits until the new
+// replicaset is fully rolled out, not only available. progress is called
+// when the status changes, it can be nil, eg:
+//
+//	d.WaitRollout("nginx", 5*time.Minute, func(s k8s.RolloutStatus) { log.Info(s.Message) })
+//
+// It fails fast with ErrProgressDeadlineExceeded if the deployment exceeded
+// its progress deadline, no timeout if timeout is 0.
+func (d *Deployment) WaitRollout(name string, timeout time.Duration, progress func(RolloutStatus)) error {
+	return d.waitRollout(name, timeout, deploymentRolloutStatus, progress)
+}
+
+// RolloutStatus returns the rollout status of the statefulset, only the
+// RollingUpdate strategy is supported.
+func (s *StatefulSet) RolloutStatus(name string) (*RolloutStatus, error) {
+	sts, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return statefulSetRolloutStatus(sts)
+}
+
+// WaitRollout waits for the rollout of the statefulset to complete, progress
+// is called when the status changes, no timeout if timeout is 0.
+func (s *StatefulSet) WaitRollout(name string, timeout time.Duration, progress func(RolloutStatus)) error {
+	return s.waitRollout(name, timeout, statefulSetRolloutStatus, progress)
+}
+
+// RolloutStatus returns the rollout status of the daemonset, only the
+// RollingUpdate strategy is supported.
+func (d *DaemonSet) RolloutStatus(name string) (*RolloutStatus, error) {
+	ds, err := d.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return daemonSetRolloutStatus(ds)
+}
+
+// WaitRollout waits for the rollout of the daemonset to complete, progress
+// is called when the status changes, no timeout if timeout is 0.
+func (d *DaemonSet) WaitRollout(name string, timeout time.Duration, progress func(RolloutStatus)) error {
+	return d.waitRollout(name, timeout, daemonSetRolloutStatus, progress)
+}
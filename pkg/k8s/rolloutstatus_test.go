@@ -0,0 +1,184 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestRolloutDeployment(replicas, updated, available, total int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: testNamespace, Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Replicas:           total,
+			UpdatedReplicas:    updated,
+			AvailableReplicas:  available,
+		},
+	}
+}
+
+func TestDeploymentRolloutStatus(t *testing.T) {
+	stale := newTestRolloutDeployment(3, 3, 3, 3)
+	stale.Status.ObservedGeneration = 1
+	deadline := newTestRolloutDeployment(3, 1, 1, 3)
+	deadline.Status.Conditions = []appsv1.DeploymentCondition{{Type: appsv1.DeploymentProgressing, Reason: "ProgressDeadlineExceeded"}}
+
+	tests := []struct {
+		deploy *appsv1.Deployment
+		want   string
+		done   bool
+	}{
+		{stale, "spec update to be observed", false},
+		{newTestRolloutDeployment(3, 1, 1, 3), "1 out of 3 new replicas have been updated", false},
+		// the old replicaset is still available, WaitReady returns but the rollout isn't done.
+		{newTestRolloutDeployment(3, 3, 3, 4), "1 old replicas are pending termination", false},
+		{newTestRolloutDeployment(3, 3, 2, 3), "2 of 3 updated replicas are available", false},
+		{newTestRolloutDeployment(3, 3, 3, 3), "successfully rolled out", true},
+	}
+	for _, test := range tests {
+		status, err := deploymentRolloutStatus(test.deploy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(status.Message, test.want) || status.Done != test.done {
+			t.Errorf("status = %q, %v, want %q, %v", status.Message, status.Done, test.want, test.done)
+		}
+	}
+	if _, err := deploymentRolloutStatus(deadline); !errors.Is(err, ErrProgressDeadlineExceeded) {
+		t.Errorf("error = %v, want ErrProgressDeadlineExceeded", err)
+	}
+}
+
+func TestStatefulSetDaemonSetRolloutStatus(t *testing.T) {
+	replicas, partition := int32(3), int32(1)
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Generation: 1},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:       &replicas,
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType},
+		},
+		Status: appsv1.StatefulSetStatus{
+			ObservedGeneration: 1,
+			ReadyReplicas:      3,
+			CurrentReplicas:    3,
+			UpdatedReplicas:    1,
+			CurrentRevision:    "nginx-1",
+			UpdateRevision:     "nginx-2",
+		},
+	}
+	if status, err := statefulSetRolloutStatus(sts); err != nil || status.Done || !strings.Contains(status.Message, "at revision nginx-2") {
+		t.Errorf("status = %+v, %v", status, err)
+	}
+	sts.Spec.UpdateStrategy.RollingUpdate = &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition}
+	if status, err := statefulSetRolloutStatus(sts); err != nil || status.Done || !strings.Contains(status.Message, "1 out of 2 new pods") {
+		t.Errorf("partitioned status = %+v, %v", status, err)
+	}
+	sts.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType}
+	if _, err := statefulSetRolloutStatus(sts); err == nil {
+		t.Error("rollout status of the OnDelete statefulset succeeded")
+	}
+
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Generation: 1},
+		Spec: appsv1.DaemonSetSpec{
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.RollingUpdateDaemonSetStrategyType},
+		},
+		Status: appsv1.DaemonSetStatus{ObservedGeneration: 1, DesiredNumberScheduled: 2, UpdatedNumberScheduled: 2, NumberAvailable: 1},
+	}
+	if status, err := daemonSetRolloutStatus(ds); err != nil || status.Done || !strings.Contains(status.Message, "1 of 2 updated pods") {
+		t.Errorf("daemonset status = %+v, %v", status, err)
+	}
+	ds.Status.NumberAvailable = 2
+	if status, err := daemonSetRolloutStatus(ds); err != nil || !status.Done {
+		t.Errorf("daemonset status = %+v, %v", status, err)
+	}
+}
+
+func TestDeploymentWaitRollout(t *testing.T) {
+	d := newTestFactory(newTestRolloutDeployment(2, 0, 0, 2)).Deployments(testNamespace)
+	client := d.factory.clientset.AppsV1().Deployments(testNamespace)
+
+	if err := d.WaitRollout("notexist", time.Second, nil); err == nil {
+		t.Error("WaitRollout(notexist) returns no error")
+	}
+
+	progress := make(chan RolloutStatus, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- d.WaitRollout("nginx", 10*time.Second, func(s RolloutStatus) { progress <- s })
+	}()
+	if s := <-progress; !strings.Contains(s.Message, "0 out of 2") {
+		t.Fatalf("first progress = %q", s.Message)
+	}
+	for _, deploy := range []*appsv1.Deployment{
+		newTestRolloutDeployment(2, 2, 1, 2),
+		newTestRolloutDeployment(2, 2, 2, 2),
+	} {
+		if s := updateUntilProgress(t, client.UpdateStatus, deploy, progress); s.UpdatedReplicas != 2 || s.AvailableReplicas != deploy.Status.AvailableReplicas {
+			t.Errorf("progress = %+v", s)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// fail fast if the progress deadline is exceeded.
+	if _, err := client.Update(d.ctx, newTestRolloutDeployment(3, 2, 2, 2), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	go func() { done <- d.WaitRollout("nginx", 10*time.Second, func(s RolloutStatus) { progress <- s }) }()
+	<-progress
+	deadline := newTestRolloutDeployment(3, 2, 2, 2)
+	deadline.Status.Conditions = []appsv1.DeploymentCondition{{Type: appsv1.DeploymentProgressing, Reason: "ProgressDeadlineExceeded"}}
+	timeout := time.After(5 * time.Second)
+	for failed := false; !failed; {
+		if _, err := client.UpdateStatus(d.ctx, deadline, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case err := <-done:
+			if !errors.Is(err, ErrProgressDeadlineExceeded) {
+				t.Errorf("error = %v, want ErrProgressDeadlineExceeded", err)
+			}
+			failed = true
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("WaitRollout doesn't fail after the progress deadline is exceeded")
+		}
+	}
+
+	// timeout.
+	if _, err := client.UpdateStatus(d.ctx, newTestRolloutDeployment(3, 2, 2, 2), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WaitRollout("nginx", 50*time.Millisecond, nil); err == nil || !strings.Contains(err.Error(), "2 out of 3") {
+		t.Errorf("error = %v, want timeout", err)
+	}
+}
+
+// updateUntilProgress keeps updating the deployment until the watch of
+// WaitRollout observes it and reports the progress.
+func updateUntilProgress(t *testing.T,
+	update func(ctx context.Context, deploy *appsv1.Deployment, opts metav1.UpdateOptions) (*appsv1.Deployment, error),
+	deploy *appsv1.Deployment, progress chan RolloutStatus) RolloutStatus {
+	timeout := time.After(10 * time.Second)
+	for {
+		if _, err := update(context.TODO(), deploy, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case s := <-progress:
+			return s
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("WaitRollout doesn't observe the update")
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+// ErrReplicasPrecondition is returned by Scale if the current replicas don't
+// equal ScaleOptions.CurrentReplicas.
+var ErrReplicasPrecondition = errors.New("current replicas precondition failed")
+
+// scalePollInterval is how often Scale checks the status replicas when it
+// waits, same as kubectl.
+var scalePollInterval = time.Second
+
+// ScaleOptions is the options of Scale, like the flags of "kubectl scale".
+type ScaleOptions struct {
+	// CurrentReplicas is the precondition of Scale, the object is only scaled
+	// if its current replicas equals it. nil means no precondition.
+	CurrentReplicas *int32
+	// Wait waits until the status replicas equals the desired replicas.
+	Wait bool
+	// Timeout is the timeout of Wait, no timeout if 0.
+	Timeout time.Duration
+}
+
+// scaleClient is implemented by the typed clients of the kinds with the scale
+// subresource, eg: typedappsv1.DeploymentInterface, and by dynamicScaleClient.
+type scaleClient interface {
+	GetScale(ctx context.Context, name string, opts metav1.GetOptions) (*autoscalingv1.Scale, error)
+	UpdateScale(ctx context.Context, name string, scale *autoscalingv1.Scale, opts metav1.UpdateOptions) (*autoscalingv1.Scale, error)
+}
+
+// dynamicScaleClient gets and updates the scale subresource with the dynamic
+// client, so any kind with the scale subresource can be scaled, eg: CRDs.
+type dynamicScaleClient struct {
+	client dynamic.ResourceInterface
+}
+
+func (c dynamicScaleClient) GetScale(ctx context.Context, name string, opts metav1.GetOptions) (*autoscalingv1.Scale, error) {
+	obj, err := c.client.Get(ctx, name, opts, "scale")
+	if err != nil {
+		return nil, err
+	}
+	return toScale(obj)
+}
+
+func (c dynamicScaleClient) UpdateScale(ctx context.Context, name string, scale *autoscalingv1.Scale, opts metav1.UpdateOptions) (*autoscalingv1.Scale, error) {
+	unstructuredScale, err := runtime.DefaultUnstructuredConverter.ToUnstructured(scale)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{Object: unstructuredScale}
+	obj.SetGroupVersionKind(autoscalingv1.SchemeGroupVersion.WithKind("Scale"))
+	obj.SetName(name)
+	if obj, err = c.client.Update(ctx, obj, opts, "scale"); err != nil {
+		return nil, err
+	}
+	return toScale(obj)
+}
+
+func toScale(obj *unstructured.Unstructured) (*autoscalingv1.Scale, error) {
+	scale := &autoscalingv1.Scale{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, scale); err != nil {
+		return nil, err
+	}
+	return scale, nil
+}
+
+// scaleTo sets the replicas of the scale subresource, it's retried if the
+// object is changed by others between get and update, the precondition is
+// checked again.
+func scaleTo(ctx context.Context, client scaleClient, kind, name string, replicas int32,
+	updateOptions metav1.UpdateOptions, opts []ScaleOptions) (*autoscalingv1.Scale, error) {
+	var options ScaleOptions
+	if len(opts) != 0 {
+		options = opts[0]
+	}
+	var scale *autoscalingv1.Scale
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := client.GetScale(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if options.CurrentReplicas != nil && current.Spec.Replicas != *options.CurrentReplicas {
+			return fmt.Errorf("%s %s: expected replicas to be %d, was %d: %w",
+				kind, name, *options.CurrentReplicas, current.Spec.Replicas, ErrReplicasPrecondition)
+		}
+		// the resourceVersion of current is kept, so the update fails with
+		// conflict if the object is changed after get.
+		current.Spec.Replicas = replicas
+		scale, err = client.UpdateScale(ctx, name, current, updateOptions)
+		return err
+	})
+	if err != nil || !options.Wait || len(updateOptions.DryRun) != 0 {
+		return scale, err
+	}
+
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+	err = wait.PollImmediateUntil(scalePollInterval, func() (bool, error) {
+		current, err := client.GetScale(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		scale = current
+		return current.Status.Replicas == replicas, nil
+	}, ctx.Done())
+	if err != nil {
+		return scale, fmt.Errorf("wait for %s %s to be scaled to %d replicas: %w, current replicas: %d",
+			kind, name, replicas, err, scale.Status.Replicas)
+	}
+	return scale, nil
+}
+
+// scaleClient returns the typed client of the handler as a scaleClient.
+func (h *Handler[T, TList]) scaleClient() (scaleClient, error) {
+	client, ok := any(h.client(h.namespace)).(scaleClient)
+	if !ok {
+		return nil, fmt.Errorf("%s has no scale subresource", h.kind)
+	}
+	return client, nil
+}
+
+func (h *Handler[T, TList]) getScale(name string) (*autoscalingv1.Scale, error) {
+	client, err := h.scaleClient()
+	if err != nil {
+		return nil, err
+	}
+	return client.GetScale(h.ctx, name, h.Options.GetOptions)
+}
+
+func (h *Handler[T, TList]) scale(name string, replicas int32, opts []ScaleOptions) (*autoscalingv1.Scale, error) {
+	client, err := h.scaleClient()
+	if err != nil {
+		return nil, err
+	}
+	return scaleTo(h.ctx, client, h.kind, name, replicas, h.Options.UpdateOptions, opts)
+}
+
+// GetScale returns the scale subresource of the deployment.
+func (d *Deployment) GetScale(name string) (*autoscalingv1.Scale, error) {
+	return d.getScale(name)
+}
+
+// Scale sets the replicas of the deployment with the scale subresource, like
+// "kubectl scale". the optional opts sets the precondition of the current
+// replicas and whether to wait until it's scaled, eg:
+//
+//	current := int32(1)
+//	d.Scale("nginx", 3, k8s.ScaleOptions{CurrentReplicas: &current, Wait: true, Timeout: time.Minute})
+func (d *Deployment) Scale(name string, replicas int32, opts ...ScaleOptions) (*autoscalingv1.Scale, error) {
+	return d.scale(name, replicas, opts)
+}
+
+// GetScale returns the scale subresource of the statefulset.
+func (s *StatefulSet) GetScale(name string) (*autoscalingv1.Scale, error) {
+	return s.getScale(name)
+}
+
+// Scale sets the replicas of the statefulset with the scale subresource.
+func (s *StatefulSet) Scale(name string, replicas int32, opts ...ScaleOptions) (*autoscalingv1.Scale, error) {
+	return s.scale(name, replicas, opts)
+}
+
+// GetScale returns the scale subresource of the replicaset.
+func (r *ReplicaSet) GetScale(name string) (*autoscalingv1.Scale, error) {
+	return r.getScale(name)
+}
+
+// Scale sets the replicas of the replicaset with the scale subresource.
+func (r *ReplicaSet) Scale(name string, replicas int32, opts ...ScaleOptions) (*autoscalingv1.Scale, error) {
+	return r.scale(name, replicas, opts)
+}
+
+// GetScale returns the scale subresource of the replicationcontroller.
+func (r *ReplicationController) GetScale(name string) (*autoscalingv1.Scale, error) {
+	return r.getScale(name)
+}
+
+// Scale sets the replicas of the replicationcontroller with the scale subresource.
+func (r *ReplicationController) Scale(name string, replicas int32, opts ...ScaleOptions) (*autoscalingv1.Scale, error) {
+	return r.scale(name, replicas, opts)
+}
+
+// dynamicScaleClient returns the scaleClient of the kind with the dynamic
+// client, the resource of the kind is found by discovery.
+func (f *Factory) dynamicScaleClient(gvk schema.GroupVersionKind, namespace string) (scaleClient, error) {
+	mapper := f.RESTMapper()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		mapper.Reset()
+		mapping, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return dynamicScaleClient{f.dynamicClient.Resource(mapping.Resource)}, nil
+	}
+	if len(namespace) == 0 {
+		namespace = metav1.NamespaceDefault
+	}
+	return dynamicScaleClient{f.dynamicClient.Resource(mapping.Resource).Namespace(namespace)}, nil
+}
+
+// GetScale returns the scale subresource of any kind with it, including the
+// CRDs with the scale subresource enabled, eg:
+//
+//	f.GetScale(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Foo"}, "default", "foo")
+func (f *Factory) GetScale(gvk schema.GroupVersionKind, namespace, name string) (*autoscalingv1.Scale, error) {
+	client, err := f.dynamicScaleClient(gvk, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetScale(f.ctx, name, metav1.GetOptions{})
+}
+
+// Scale sets the replicas of any kind with the scale subresource, including
+// the CRDs, see Deployment.Scale for the opts.
+func (f *Factory) Scale(gvk schema.GroupVersionKind, namespace, name string, replicas int32, opts ...ScaleOptions) (*autoscalingv1.Scale, error) {
+	client, err := f.dynamicScaleClient(gvk, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return scaleTo(f.ctx, client, gvk.Kind, name, replicas, metav1.UpdateOptions{}, opts)
+}
@@ -0,0 +1,138 @@
+package k8s
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestScale(t *testing.T) {
+	one := int32(1)
+	f := newTestFactory(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: testNamespace}, Spec: appsv1.DeploymentSpec{Replicas: &one}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: testNamespace}, Spec: appsv1.ReplicaSetSpec{Replicas: &one}},
+		&corev1.ReplicationController{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: testNamespace}, Spec: corev1.ReplicationControllerSpec{Replicas: &one}},
+	)
+	d := f.Deployments(testNamespace)
+
+	if _, err := d.Scale("nginx", 3); err != nil {
+		t.Fatal(err)
+	}
+	scale, err := d.GetScale("nginx")
+	if err != nil || scale.Spec.Replicas != 3 {
+		t.Fatalf("GetScale = %+v, %v", scale, err)
+	}
+	if deploy, _ := d.Get("nginx"); *deploy.Spec.Replicas != 3 {
+		t.Errorf("replicas = %d, want 3", *deploy.Spec.Replicas)
+	}
+
+	// the precondition of the current replicas.
+	if _, err := d.Scale("nginx", 5, ScaleOptions{CurrentReplicas: &one}); !errors.Is(err, ErrReplicasPrecondition) {
+		t.Errorf("error = %v, want ErrReplicasPrecondition", err)
+	}
+	three := int32(3)
+	if scale, err := d.Scale("nginx", 5, ScaleOptions{CurrentReplicas: &three}); err != nil || scale.Spec.Replicas != 5 {
+		t.Errorf("Scale = %+v, %v", scale, err)
+	}
+
+	if _, err := f.ReplicaSets(testNamespace).Scale("nginx", 2); err != nil {
+		t.Fatal(err)
+	}
+	if rs, _ := f.ReplicaSets(testNamespace).Get("nginx"); *rs.Spec.Replicas != 2 {
+		t.Errorf("replicaset replicas = %d, want 2", *rs.Spec.Replicas)
+	}
+	if _, err := f.ReplicationControllers(testNamespace).Scale("nginx", 0); err != nil {
+		t.Fatal(err)
+	}
+	if rc, _ := f.ReplicationControllers(testNamespace).Get("nginx"); *rc.Spec.Replicas != 0 {
+		t.Errorf("replicationcontroller replicas = %d, want 0", *rc.Spec.Replicas)
+	}
+}
+
+func TestScaleWait(t *testing.T) {
+	defer func(interval time.Duration) { scalePollInterval = interval }(scalePollInterval)
+	scalePollInterval = 10 * time.Millisecond
+
+	one := int32(1)
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: testNamespace},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &one},
+		Status:     appsv1.StatefulSetStatus{Replicas: 1},
+	}
+	s := newTestFactory(sts).StatefulSets(testNamespace)
+
+	if _, err := s.Scale("nginx", 2, ScaleOptions{Wait: true, Timeout: 50 * time.Millisecond}); err == nil {
+		t.Error("Scale doesn't time out while the status replicas isn't updated")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.Scale("nginx", 3, ScaleOptions{Wait: true, Timeout: 10 * time.Second})
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	live, err := s.Get("nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	live.Status.Replicas = 3
+	if _, err := s.factory.clientset.AppsV1().StatefulSets(testNamespace).UpdateStatus(s.ctx, live, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Scale doesn't return after the statefulset is scaled")
+	}
+}
+
+func TestFactoryScale(t *testing.T) {
+	f := newTestFactory()
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Foo"}
+	foo := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"replicas": int64(1)},
+	}}
+	foo.SetGroupVersionKind(gvk)
+	foo.SetName("foo")
+	foo.SetNamespace(testNamespace)
+	gvr := gvk.GroupVersion().WithResource("foos")
+	if _, err := f.dynamicClient.Resource(gvr).Namespace(testNamespace).Create(f.ctx, foo, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	one := int32(1)
+	if scale, err := f.Scale(gvk, testNamespace, "foo", 4, ScaleOptions{CurrentReplicas: &one}); err != nil || scale.Spec.Replicas != 4 {
+		t.Fatalf("Scale = %+v, %v", scale, err)
+	}
+	scale, err := f.GetScale(gvk, testNamespace, "foo")
+	if err != nil || scale.Spec.Replicas != 4 {
+		t.Fatalf("GetScale = %+v, %v", scale, err)
+	}
+	// the discovery is done once for the scale calls.
+	discoveries := 0
+	for _, action := range f.clientset.(*fake.Clientset).Actions() {
+		if action.GetResource().Resource == "group" {
+			discoveries++
+		}
+	}
+	if discoveries != 1 {
+		t.Errorf("%d discoveries, want 1", discoveries)
+	}
+	live, _ := f.dynamicClient.Resource(gvr).Namespace(testNamespace).Get(f.ctx, "foo", metav1.GetOptions{})
+	if replicas, _, _ := unstructured.NestedInt64(live.Object, "spec", "replicas"); replicas != 4 {
+		t.Errorf("foo replicas = %d, want 4", replicas)
+	}
+	if _, err := f.GetScale(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Bar"}, testNamespace, "bar"); err == nil {
+		t.Error("GetScale of an unknown kind succeeded")
+	}
+}
@@ -0,0 +1,277 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// TailOptions selects the containers and the lines of TailLogs, like the
+// flags of stern.
+type TailOptions struct {
+	// Container selects the containers by name, all containers if it's nil.
+	Container *regexp.Regexp
+	// InitContainers tails the init containers too.
+	InitContainers bool
+	// Include only sends the lines matching any of the regexps.
+	Include []*regexp.Regexp
+	// Exclude drops the lines matching any of the regexps.
+	Exclude []*regexp.Regexp
+
+	// SinceSeconds, TailLines and Timestamps are same as LogOptions, they
+	// apply to every container attached.
+	SinceSeconds *int64
+	TailLines    *int64
+	Timestamps   bool
+}
+
+// TailLine is a log line sent by TailLogs, the trailing newline is removed.
+type TailLine struct {
+	Namespace string
+	Pod       string
+	Container string
+	Line      string
+}
+
+// String returns the line prefixed with "pod/container", eg:
+// "nginx-6799fc88d8-6k2sq/nginx 10.244.0.1 - - "GET / HTTP/1.1" 200".
+func (l TailLine) String() string {
+	return l.Pod + "/" + l.Container + " " + l.Line
+}
+
+// match reports whether the line passes the include and exclude filters.
+func (o TailOptions) match(line string) bool {
+	for _, re := range o.Exclude {
+		if re.MatchString(line) {
+			return false
+		}
+	}
+	if len(o.Include) == 0 {
+		return true
+	}
+	for _, re := range o.Include {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// tailer follows the logs of the pods of a label selector, the containers
+// are attached when they start, and detached when the pod is deleted.
+type tailer struct {
+	p    *Pod
+	opts TailOptions
+	ch   chan TailLine
+
+	wg sync.WaitGroup
+	// containers is the streams of the containers attached, keyed by
+	// "namespace/pod/container".
+	containers map[string]*containerStream
+}
+
+// containerStream is the log stream of a container, it's canceled when the
+// container restarts or the pod is deleted.
+type containerStream struct {
+	restartCount int32
+	cancel       context.CancelFunc
+}
+
+// TailLogs follows the logs of every container of the pods matching the
+// label selector, eg: "app=nginx", and sends the lines to the returned
+// channel until ctx is done, then the channel is closed. The pods created
+// later, eg: by a rollout or scale up, are attached automatically, and the
+// deleted pods are dropped. The terminated containers are attached only if
+// they are terminated when TailLogs is called. eg:
+//
+//	lines, err := p.TailLogs(ctx, "app=nginx", k8s.TailOptions{Exclude: []*regexp.Regexp{regexp.MustCompile("healthz")}})
+//	for line := range lines {
+//		fmt.Println(line)
+//	}
+func (p *Pod) TailLogs(ctx context.Context, label string, opts TailOptions) (<-chan TailLine, error) {
+	pods, err := p.factory.clientset.CoreV1().Pods(p.namespace).List(ctx, metav1.ListOptions{LabelSelector: label})
+	if err != nil {
+		return nil, err
+	}
+	events, err := p.WatchEvents(ctx, WatchOptions{LabelSelector: label, ResourceVersion: pods.ResourceVersion})
+	if err != nil {
+		return nil, err
+	}
+	t := &tailer{
+		p:          p,
+		opts:       opts,
+		ch:         make(chan TailLine),
+		containers: make(map[string]*containerStream),
+	}
+	go func() {
+		defer close(t.ch)
+		for i := range pods.Items {
+			t.attach(ctx, &pods.Items[i], true)
+		}
+		for event := range events {
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				t.attach(ctx, event.New, false)
+			case watch.Deleted:
+				t.detach(event.Old)
+			}
+		}
+		for _, stream := range t.containers {
+			stream.cancel()
+		}
+		t.wg.Wait()
+	}()
+	return t.ch, nil
+}
+
+// attach follows the logs of the running containers of the pod which are not
+// attached yet, or restarted since they were attached. The terminated
+// containers are attached only if initial is true, eg: the pods listed when
+// TailLogs is called.
+func (t *tailer) attach(ctx context.Context, pod *corev1.Pod, initial bool) {
+	podKey := pod.Namespace + "/" + pod.Name
+	statuses := pod.Status.ContainerStatuses
+	if t.opts.InitContainers {
+		statuses = append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), statuses...)
+	}
+	for _, status := range statuses {
+		if status.State.Running == nil && (!initial || status.State.Terminated == nil) {
+			continue
+		}
+		if t.opts.Container != nil && !t.opts.Container.MatchString(status.Name) {
+			continue
+		}
+		key := podKey + "/" + status.Name
+		if stream, ok := t.containers[key]; ok {
+			if stream.restartCount == status.RestartCount {
+				continue
+			}
+			// the container restarted, stop following the previous one.
+			stream.cancel()
+		}
+		streamCtx, cancel := context.WithCancel(ctx)
+		t.containers[key] = &containerStream{restartCount: status.RestartCount, cancel: cancel}
+		t.wg.Add(1)
+		go func(container string) {
+			defer t.wg.Done()
+			if err := t.follow(streamCtx, pod.Namespace, pod.Name, container); err != nil {
+				log.Debugf("tail logs of %s/%s: %v", pod.Name, container, err)
+			}
+		}(status.Name)
+	}
+}
+
+// detach stops following the logs of the deleted pod.
+func (t *tailer) detach(pod *corev1.Pod) {
+	podKey := pod.Namespace + "/" + pod.Name
+	for key, stream := range t.containers {
+		if strings.HasPrefix(key, podKey+"/") {
+			stream.cancel()
+			delete(t.containers, key)
+		}
+	}
+}
+
+// follow sends the log lines of the container until the container exits or
+// ctx is done.
+func (t *tailer) follow(ctx context.Context, namespace, name, container string) error {
+	opts := LogOptions{
+		Follow:       true,
+		SinceSeconds: t.opts.SinceSeconds,
+		TailLines:    t.opts.TailLines,
+		Timestamps:   t.opts.Timestamps,
+	}
+	stream, err := t.p.factory.clientset.CoreV1().Pods(namespace).
+		GetLogs(name, opts.podLogOptions(container)).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	streams := []logStream{{container: container, stream: stream}}
+	return copyLogs(ctx, streams, true, func(container string, line []byte) error {
+		text := strings.TrimSuffix(string(line), "\n")
+		if !t.opts.match(text) {
+			return nil
+		}
+		select {
+		case t.ch <- TailLine{Namespace: namespace, Pod: name, Container: container, Line: text}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// selectorString returns the label selector of the workload as a string.
+func selectorString(kind, name string, selector *metav1.LabelSelector) (string, error) {
+	if selector == nil {
+		return "", fmt.Errorf("%s %s has no selector", kind, name)
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return "", err
+	}
+	return s.String(), nil
+}
+
+// TailLogs follows the logs of the pods of the deployment, the pods of the
+// new replicasets are attached during a rollout, see Pod.TailLogs.
+func (d *Deployment) TailLogs(ctx context.Context, name string, opts TailOptions) (<-chan TailLine, error) {
+	deploy, err := d.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	label, err := selectorString(d.kind, name, deploy.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	return d.factory.Pods(d.namespace).TailLogs(ctx, label, opts)
+}
+
+// TailLogs follows the logs of the pods of the statefulset, see Pod.TailLogs.
+func (s *StatefulSet) TailLogs(ctx context.Context, name string, opts TailOptions) (<-chan TailLine, error) {
+	sts, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	label, err := selectorString(s.kind, name, sts.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	return s.factory.Pods(s.namespace).TailLogs(ctx, label, opts)
+}
+
+// TailLogs follows the logs of the pods of the daemonset, see Pod.TailLogs.
+func (d *DaemonSet) TailLogs(ctx context.Context, name string, opts TailOptions) (<-chan TailLine, error) {
+	ds, err := d.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	label, err := selectorString(d.kind, name, ds.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	return d.factory.Pods(d.namespace).TailLogs(ctx, label, opts)
+}
+
+// TailLogs follows the logs of the pods of the job, see Pod.TailLogs. The
+// pods are selected by the label "job-name" if the job has no selector yet.
+func (j *Job) TailLogs(ctx context.Context, name string, opts TailOptions) (<-chan TailLine, error) {
+	job, err := j.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	label := "job-name=" + name
+	if job.Spec.Selector != nil {
+		if label, err = selectorString(j.kind, name, job.Spec.Selector); err != nil {
+			return nil, err
+		}
+	}
+	return j.factory.Pods(j.namespace).TailLogs(ctx, label, opts)
+}
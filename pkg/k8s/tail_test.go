@@ -0,0 +1,137 @@
+package k8s
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newRunningPod(name string, restartCount int32) *corev1.Pod {
+	pod := newTestPod(name, true)
+	pod.Status.ContainerStatuses[0].State.Running = &corev1.ContainerStateRunning{}
+	pod.Status.ContainerStatuses[0].RestartCount = restartCount
+	return pod
+}
+
+func nextLine(t *testing.T, lines <-chan TailLine) TailLine {
+	t.Helper()
+	select {
+	case line, ok := <-lines:
+		if !ok {
+			t.Fatal("lines channel closed")
+		}
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("no line received")
+	}
+	return TailLine{}
+}
+
+func TestTailLogs(t *testing.T) {
+	f := newTestFactory(newRunningPod("a", 0), newTestPod("pending", false), newTestDeployment("nginx", true))
+	watcher := watch.NewFakeWithChanSize(10, false)
+	f.clientset.(*fake.Clientset).PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		return true, watcher, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	lines, err := f.Deployments(testNamespace).TailLogs(ctx, "nginx", TailOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line := nextLine(t, lines); line.String() != "a/nginx fake logs" {
+		t.Errorf("line = %q, want a/nginx fake logs", line.String())
+	}
+
+	// the pods started later are attached, the containers restarted are
+	// attached again.
+	watcher.Add(newRunningPod("b", 0))
+	if line := nextLine(t, lines); line.Pod != "b" || line.Container != "nginx" {
+		t.Errorf("line = %+v, want the logs of b", line)
+	}
+	watcher.Modify(newRunningPod("a", 1))
+	if line := nextLine(t, lines); line.Pod != "a" {
+		t.Errorf("line = %+v, want the logs of the restarted a", line)
+	}
+
+	cancel()
+	for range lines {
+	}
+}
+
+func TestTailOptionsMatch(t *testing.T) {
+	opts := TailOptions{
+		Include: []*regexp.Regexp{regexp.MustCompile("GET"), regexp.MustCompile("POST")},
+		Exclude: []*regexp.Regexp{regexp.MustCompile("/healthz")},
+	}
+	tests := map[string]bool{
+		`"GET / HTTP/1.1" 200`:        true,
+		`"POST /api HTTP/1.1" 201`:    true,
+		`"GET /healthz HTTP/1.1" 200`: false,
+		`starting nginx`:              false,
+	}
+	for line, want := range tests {
+		if got := opts.match(line); got != want {
+			t.Errorf("match(%q) = %v, want %v", line, got, want)
+		}
+	}
+	if !(TailOptions{}).match("anything") {
+		t.Error("the line doesn't match empty options")
+	}
+}
+
+func TestTailLogsRestart(t *testing.T) {
+	terminated := newTestPod("done", false)
+	terminated.Status.ContainerStatuses[0].State.Terminated = &corev1.ContainerStateTerminated{ExitCode: 0}
+	f := newTestFactory(newRunningPod("a", 0), terminated)
+	watcher := watch.NewFakeWithChanSize(10, false)
+	f.clientset.(*fake.Clientset).PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		return true, watcher, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	lines, err := f.Pods(testNamespace).TailLogs(ctx, "app=nginx", TailOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the containers terminated before TailLogs are attached.
+	got := map[string]int{}
+	for i := 0; i < 2; i++ {
+		got[nextLine(t, lines).Pod]++
+	}
+	if got["a"] != 1 || got["done"] != 1 {
+		t.Fatalf("lines = %v, want one line of a and done", got)
+	}
+
+	// a is restarted once, the events with the same restart count are not
+	// attached again, and the pods terminated later are not attached.
+	watcher.Modify(newRunningPod("a", 1))
+	watcher.Modify(newRunningPod("a", 1))
+	exited := newRunningPod("a", 1)
+	exited.Status.ContainerStatuses[0].State = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}}
+	watcher.Modify(exited)
+	watcher.Modify(terminated)
+	late := terminated.DeepCopy()
+	late.Name = "late"
+	watcher.Add(late)
+	if line := nextLine(t, lines); line.Pod != "a" {
+		t.Errorf("line = %+v, want the logs of the restarted a", line)
+	}
+	select {
+	case line := <-lines:
+		t.Errorf("duplicated line %+v", line)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	cancel()
+	for range lines {
+	}
+}
@@ -1,42 +1,85 @@
 package k8s
 
 import (
+	"hybfkuf/pkg/k8s/apply"
+
+	"context"
+	"io"
+	"time"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
-type HandlerInterface interface {
-	CreateFromRaw(raw map[string]interface{}) (interface{}, error)
-	CreateFromBytes(data []byte) (interface{}, error)
-	CreateFromFile(path string) (interface{}, error)
-	Create(path string) (interface{}, error)
-
-	UpdateFromRaw(raw map[string]interface{}) (interface{}, error)
-	UpdateFromBytes(data []byte) (interface{}, error)
-	UpdateFromFile(path string) (interface{}, error)
-	Update(path string) (interface{}, error)
+// Interface is the kind-agnostic part of HandlerInterface, every handler
+// implements it, so it can be used to write tooling that works on handlers
+// of different kinds, eg: delete the same name from a set of handlers.
+type Interface interface {
+	Kind() string
+	Namespace() string
 
-	ApplyFromRaw(raw map[string]interface{}) (interface{}, error)
-	ApplyFromBytes(data []byte) (interface{}, error)
-	ApplyFromFile(path string) (interface{}, error)
-	Apply(path string) (interface{}, error)
+	SetLimit(limit int64)
+	SetTimeout(timeout int64)
+	SetForceDelete(force bool)
+	SetPropagationPolicy(policy string)
+	SetApplyMode(mode ApplyMode)
+	SetFieldManager(name string)
+	SetForceConflicts(force bool)
+	SetRender(render RenderFunc)
+	SetRenderOutput(w io.Writer)
 
-	DeleteByName(data []byte) error
+	DeleteByName(name string) error
 	DeleteFromBytes(data []byte) error
 	DeleteFromFile(path string) error
 	Delete(name string) error
 
-	GetByName(name string) (interface{}, error)
-	GetFromBytes(name string) (interface{}, error)
-	GetFromFile(path string) (interface{}, error)
-	Get(name string) (interface{}, error)
-
-	ListByLabel(label string) (interface{}, error)
-	ListAll() (interface{}, error)
-	List(label string) (interface{}, error)
+	DiffFromBytes(data []byte) (string, error)
+	DiffFromFile(path string) (string, error)
+	Diff(path string) (string, error)
 
 	WatchByName(name string, addFunc, modifyFunc, deleteFunc func(x interface{}), x interface{}) error
 	WatchByLabel(label string, addFunc, modifyFunc, deleteFunc func(x interface{}), x interface{}) error
 	Watch(name string, addFunc, modifyFunc, deleteFunc func(x interface{}), x interface{}) error
+	WaitFor(name string, condition Condition, timeout time.Duration) error
+
+	RunInformer(addFunc func(obj interface{}), updateFunc func(oldObj, newObj interface{}), deleteFunc func(obj interface{}), stopCh chan struct{})
+	AddIndexers(indexers cache.Indexers) error
+}
+
+// HandlerInterface is implemented by every handler, T is the typed object,
+// eg: corev1.Pod, and TList is its list type, eg: corev1.PodList.
+//
+//	var _ HandlerInterface[corev1.Pod, corev1.PodList] = (*Pod)(nil)
+type HandlerInterface[T, TList any] interface {
+	Interface
+
+	CreateFromRaw(raw map[string]interface{}) (*T, error)
+	CreateFromBytes(data []byte) (*T, error)
+	CreateFromFile(path string) (*T, error)
+	Create(path string) (*T, error)
+
+	UpdateFromRaw(raw map[string]interface{}) (*T, error)
+	UpdateFromBytes(data []byte) (*T, error)
+	UpdateFromFile(path string) (*T, error)
+	Update(path string) (*T, error)
+
+	ApplyFromRaw(raw map[string]interface{}) (*T, error)
+	ApplyFromBytes(data []byte) (*T, error)
+	ApplyFromFile(path string) (*T, error)
+	Apply(path string) (*T, error)
+
+	GetByName(name string) (*T, error)
+	GetFromBytes(data []byte) (*T, error)
+	GetFromFile(path string) (*T, error)
+	Get(name string) (*T, error)
+
+	ListByLabel(label string) (*TList, error)
+	ListByNamespace(namespace string) (*TList, error)
+	ListAll() (*TList, error)
+	List(label string) (*TList, error)
+
+	WatchEvents(ctx context.Context, opts WatchOptions) (<-chan Event[T], error)
+	Lister() *Lister[T]
 }
 
 // k8s resource name
@@ -60,21 +103,23 @@ const (
 	ResourceKindReplicaSets            = "replicasets"
 	ResourceKindReplicationControllers = "replicationcontrollers"
 
-	//CLUSTERROLEBINDING    = "clusterrolebinding"
-	//CLUSTERROLE           = "clusterrole"
-	//CONFIGMAP             = "configmap"
-	//INGRES                = "ingress"
-	//INGRESSCLASS          = "ingressclass"
-	//NAMESPACE             = "namespace"
-	//NETWORKPOLICY         = "networkpolicy"
-	//NODE                  = "node"
-	//PERSISTENTVOLUMECLAIM = "persistentvolumeclaim"
-	//PERSISTENTVOLUME      = "persistentvolume"
-	//ROLEBINDING           = "rolebinding"
-	//ROLE                  = "role"
-	//SECRET                = "secret"
-	//SERVICEACCOUNT        = "serviceaccount"
-	//SERVICE               = "service"
+	// others
+	ResourceKindClusterRoleBinding    = "clusterrolebinding"
+	ResourceKindClusterRole           = "clusterrole"
+	ResourceKindConfigMap             = "configmap"
+	ResourceKindIngress               = "ingress"
+	ResourceKindIngressClass          = "ingressclass"
+	ResourceKindNamespace             = "namespace"
+	ResourceKindNetworkPolicy         = "networkpolicy"
+	ResourceKindNode                  = "node"
+	ResourceKindPersistentVolumeClaim = "persistentvolumeclaim"
+	ResourceKindPersistentVolume      = "persistentvolume"
+	ResourceKindRoleBinding           = "rolebinding"
+	ResourceKindRole                  = "role"
+	ResourceKindSecret                = "secret"
+	ResourceKindServiceAccount        = "serviceaccount"
+	ResourceKindService               = "service"
+	ResourceKindStorageClass          = "storageclass"
 )
 
 //type ListOptions struct {
@@ -135,10 +180,42 @@ type HandlerOptions struct {
 	ApplyOptions  metav1.ApplyOptions
 	UpdateOptions metav1.UpdateOptions
 	PatchOptions  metav1.PatchOptions
+
+	// ApplyMode is the strategy used by the Apply methods, default to
+	// ApplyModeServerSide.
+	ApplyMode ApplyMode
+
+	// Render renders the manifests of the *FromBytes and *FromFile methods,
+	// nil means the manifests are not rendered.
+	Render RenderFunc
+	// RenderOutput is where the rendered manifests are dumped to.
+	RenderOutput io.Writer
 }
 
+// ApplyMode is the strategy used by the Apply methods of the handlers and ApplyF.
+type ApplyMode = apply.Mode
+
+const (
+	// ApplyModeServerSide sends the object as an ApplyPatchType patch, the api
+	// server merges it with the live object and tracks the field ownership.
+	// ApplyOptions.FieldManager and ApplyOptions.Force are used.
+	ApplyModeServerSide = apply.ModeServerSide
+	// ApplyModeCreateOrUpdate creates the object, and replaces it with a full
+	// update if it already exists.
+	ApplyModeCreateOrUpdate = apply.ModeCreateOrUpdate
+	// ApplyModeClientSide works like "kubectl apply" without --server-side,
+	// the manifest is saved in the last-applied-configuration annotation and
+	// a three-way merge patch is sent, fields removed from the manifest are
+	// removed from the live object too.
+	ApplyModeClientSide = apply.ModeClientSide
+)
+
+// RenderFunc renders a manifest before it's decoded, eg: apply.Template(values)
+// or apply.Env(nil), see HandlerOptions.Render.
+type RenderFunc = apply.RenderFunc
+
 const (
-	FieldManager = "client-go"
+	FieldManager = apply.DefaultFieldManager
 )
 
 const (
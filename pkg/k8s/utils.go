@@ -147,4 +147,6 @@ func GetNodeInfo(ctx context.Context, kubeconfig string) (nodeInfoMap map[string
 
 /*
 1. 重复 apply 一个 pvc 会失败,因为 pvc.spec.volumeName 绑定的 pv 不允许修改
+   Apply 默认使用 server-side apply (ApplyModeServerSide), 只提交 manifest 中的字段, 不会再修改 volumeName.
+   ApplyModeCreateOrUpdate 仍然会有这个问题.
 */
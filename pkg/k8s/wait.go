@@ -0,0 +1,204 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// DefaultWaitTimeout is the timeout of WaitReady, WaitFinish and WaitNotExist,
+// and the methods creating or updating an object and waiting for it to be
+// ready. 0 means no timeout, use WaitFor for a different timeout per call.
+var DefaultWaitTimeout = 5 * time.Minute
+
+// ErrNotFound is returned by the conditions of WaitFor if the object doesn't
+// exist, except ConditionDeleted.
+var ErrNotFound = errors.New("not found")
+
+// Condition is the condition WaitFor waits for, like "kubectl wait --for".
+// obj is the object converted to unstructured, with apiVersion and kind, or
+// nil if the object doesn't exist. Returning an error stops the wait.
+type Condition func(obj *unstructured.Unstructured) (bool, error)
+
+// ConditionDeleted is met if the object doesn't exist, like "--for=delete".
+func ConditionDeleted(obj *unstructured.Unstructured) (bool, error) {
+	return obj == nil, nil
+}
+
+// ConditionReady is met if the object is ready, it's the same as IsReady of
+// the handler of the kind, eg: the pod has the Ready condition, the deployment
+// has the Available condition, the job is complete. The objects of other kinds
+// are ready if they have the Ready condition.
+func ConditionReady(obj *unstructured.Unstructured) (bool, error) {
+	if obj == nil {
+		return false, ErrNotFound
+	}
+	status := func(field string) int64 {
+		value, _, _ := unstructured.NestedInt64(obj.Object, "status", field)
+		return value
+	}
+	switch obj.GetKind() {
+	case "Deployment":
+		return hasCondition(obj, "Available", "True"), nil
+	case "Job":
+		return hasCondition(obj, "Complete", "True"), nil
+	case "StatefulSet":
+		replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		if !found {
+			replicas = 1
+		}
+		return status("availableReplicas") == replicas, nil
+	case "DaemonSet":
+		desired := status("desiredNumberScheduled")
+		return status("currentNumberScheduled") == desired &&
+			status("numberAvailable") == desired &&
+			status("numberReady") == desired, nil
+	case "ReplicaSet", "ReplicationController":
+		replicas := status("replicas")
+		return status("availableReplicas") == replicas &&
+			status("fullyLabeledReplicas") == replicas &&
+			status("readyReplicas") == replicas, nil
+	}
+	return hasCondition(obj, "Ready", "True"), nil
+}
+
+// ConditionStatus returns the Condition met if the object has the status
+// condition of the type and status, like "--for=condition=Available=True".
+// Both are case insensitive, status is "True" if empty.
+func ConditionStatus(conditionType, status string) Condition {
+	if len(status) == 0 {
+		status = "True"
+	}
+	return func(obj *unstructured.Unstructured) (bool, error) {
+		if obj == nil {
+			return false, ErrNotFound
+		}
+		return hasCondition(obj, conditionType, status), nil
+	}
+}
+
+// ConditionJSONPath returns the Condition met if the value of the jsonpath
+// expression equals value, like "--for=jsonpath='{.status.phase}'=Running".
+// The braces of the expression are optional. It's an error if the expression
+// matches more than one value.
+func ConditionJSONPath(expression, value string) Condition {
+	if !strings.HasPrefix(expression, "{") {
+		expression = "{" + expression + "}"
+	}
+	parser := jsonpath.New("condition").AllowMissingKeys(true)
+	parseErr := parser.Parse(expression)
+	return func(obj *unstructured.Unstructured) (bool, error) {
+		if parseErr != nil {
+			return false, fmt.Errorf("jsonpath %s: %w", expression, parseErr)
+		}
+		if obj == nil {
+			return false, ErrNotFound
+		}
+		results, err := parser.FindResults(obj.Object)
+		if err != nil {
+			return false, fmt.Errorf("jsonpath %s: %w", expression, err)
+		}
+		var values []interface{}
+		for _, result := range results {
+			for _, v := range result {
+				values = append(values, v.Interface())
+			}
+		}
+		switch len(values) {
+		case 0:
+			return false, nil
+		case 1:
+			return fmt.Sprint(values[0]) == value, nil
+		}
+		return false, fmt.Errorf("jsonpath %s matches %d values, want 1", expression, len(values))
+	}
+}
+
+// hasCondition reports whether the object has the status condition.
+func hasCondition(obj *unstructured.Unstructured, conditionType, status string) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if strings.EqualFold(fmt.Sprint(cond["type"]), conditionType) &&
+			strings.EqualFold(fmt.Sprint(cond["status"]), status) {
+			return true
+		}
+	}
+	return false
+}
+
+// toUnstructured converts the typed object to unstructured, with the
+// apiVersion and kind from the scheme.
+func toUnstructured(obj any) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	if runtimeObj, ok := obj.(runtime.Object); ok {
+		if gvks, _, err := scheme.Scheme.ObjectKinds(runtimeObj); err == nil && len(gvks) != 0 {
+			u.SetGroupVersionKind(gvks[0])
+		}
+	}
+	return u, nil
+}
+
+// WaitFor waits for the object to meet the condition, like "kubectl wait".
+// It watches the object and checks the condition on every change, the watch is
+// resumed from the last resourceVersion if it's closed by the server. It stops
+// when the handler context is done, no timeout if timeout is 0. eg:
+//
+//	p.WaitFor("nginx", k8s.ConditionJSONPath("{.status.phase}", "Running"), time.Minute)
+//	p.WaitFor("nginx", k8s.ConditionDeleted, time.Minute)
+func (h *Handler[T, TList]) WaitFor(name string, condition Condition, timeout time.Duration) error {
+	ctx, cancel := h.ctx, context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(h.ctx, timeout)
+	}
+	defer cancel()
+
+	err := h.watchUntil(ctx, name, func(obj *T) (bool, error) {
+		if obj == nil {
+			return condition(nil)
+		}
+		u, err := toUnstructured(obj)
+		if err != nil {
+			return false, err
+		}
+		return condition(u)
+	})
+	if err != nil {
+		return fmt.Errorf("wait for %s %s: %w", h.kind, name, err)
+	}
+	return nil
+}
+
+// waitReady waits for the object to be ready, it's the WaitReady of the
+// handlers. It returns an error if the object is deleted, or doesn't exist and
+// check is true, otherwise it waits for the object to be created. It times out
+// after DefaultWaitTimeout.
+func (h *Handler[T, TList]) waitReady(name string, check bool) error {
+	existed := false
+	return h.WaitFor(name, func(obj *unstructured.Unstructured) (bool, error) {
+		if obj == nil {
+			if existed {
+				return false, fmt.Errorf("%s deleted", name)
+			}
+			if !check {
+				return false, nil
+			}
+		}
+		existed = true
+		return ConditionReady(obj)
+	}, DefaultWaitTimeout)
+}
@@ -0,0 +1,190 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestConditions(t *testing.T) {
+	pod, err := toUnstructured(newTestPod("nginx", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pod.GetKind() != "Pod" || pod.GetAPIVersion() != "v1" {
+		t.Fatalf("kind = %s %s, want v1 Pod", pod.GetAPIVersion(), pod.GetKind())
+	}
+	notReady, _ := toUnstructured(newTestPod("nginx", false))
+	job, _ := toUnstructured(newTestJob("pi", batchv1.JobComplete))
+
+	tests := []struct {
+		name      string
+		condition Condition
+		obj       *unstructured.Unstructured
+		want      bool
+	}{
+		{"ready", ConditionReady, pod, true},
+		{"not ready", ConditionReady, notReady, false},
+		{"job ready", ConditionReady, job, true},
+		{"status", ConditionStatus("ready", ""), pod, true},
+		{"status false", ConditionStatus("Ready", "false"), notReady, true},
+		{"jsonpath", ConditionJSONPath("{.status.podIP}", "10.244.0.10"), pod, true},
+		{"jsonpath without braces", ConditionJSONPath(".spec.containers[0].image", "nginx:1.21"), pod, true},
+		{"jsonpath not equal", ConditionJSONPath(".spec.nodeName", "node2"), pod, false},
+		{"jsonpath missing", ConditionJSONPath(".status.phase", "Running"), pod, false},
+	}
+	for _, test := range tests {
+		got, err := test.condition(test.obj)
+		if err != nil || got != test.want {
+			t.Errorf("%s: %v, %v, want %v", test.name, got, err, test.want)
+		}
+	}
+
+	sidecar := newTestPod("nginx", true)
+	sidecar.Spec.Containers = append(sidecar.Spec.Containers, corev1.Container{Name: "sidecar"})
+	obj, _ := toUnstructured(sidecar)
+	if _, err := ConditionJSONPath(".spec.containers[*].name", "nginx")(obj); err == nil {
+		t.Error("jsonpath matching 2 values succeeded")
+	}
+	if _, err := ConditionReady(nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ConditionReady(nil) error = %v, want ErrNotFound", err)
+	}
+	if deleted, _ := ConditionDeleted(nil); !deleted {
+		t.Error("ConditionDeleted(nil) = false")
+	}
+}
+
+func TestWaitFor(t *testing.T) {
+	p := newTestFactory(newTestPod("nginx", false)).Pods(testNamespace)
+
+	// timeout
+	err := p.WaitFor("nginx", ConditionReady, 50*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want context.DeadlineExceeded", err)
+	}
+	// the object doesn't exist.
+	if err := p.WaitFor("notexist", ConditionReady, time.Second); !errors.Is(err, ErrNotFound) {
+		t.Errorf("error = %v, want ErrNotFound", err)
+	}
+	if err := p.WaitFor("notexist", ConditionDeleted, time.Second); err != nil {
+		t.Errorf("WaitFor(notexist, deleted) = %v", err)
+	}
+
+	// the handler context is canceled.
+	ctx, cancel := context.WithCancel(context.TODO())
+	canceled := p.DeepCopy()
+	canceled.ctx = ctx
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	if err := canceled.WaitFor("nginx", ConditionReady, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+
+	// the pod is deleted.
+	done := make(chan error, 1)
+	go func() { done <- p.WaitFor("nginx", ConditionDeleted, 10*time.Second) }()
+	time.Sleep(50 * time.Millisecond)
+	if err := p.factory.clientset.CoreV1().Pods(testNamespace).Delete(p.ctx, "nginx", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJobWait(t *testing.T) {
+	j := newTestFactory(newTestJob("pi", "")).Jobs(testNamespace)
+	client := j.factory.clientset.BatchV1().Jobs(testNamespace)
+
+	done := make(chan error, 1)
+	go func() { done <- j.WaitFinish("pi") }()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(10 * time.Second)
+	for finished := false; !finished; {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+			finished = true
+		case <-ticker.C:
+			if _, err := client.UpdateStatus(j.ctx, newTestJob("pi", batchv1.JobFailed), metav1.UpdateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		case <-timeout:
+			t.Fatal("WaitFinish doesn't return after the job failed")
+		}
+	}
+
+	go func() { done <- j.WaitNotExist("pi") }()
+	time.Sleep(50 * time.Millisecond)
+	if err := client.Delete(j.ctx, "pi", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefaultWaitTimeout(t *testing.T) {
+	f := newTestFactory(newTestPod("nginx", false), newTestJob("pi", ""))
+	timeout := DefaultWaitTimeout
+	defer func() { DefaultWaitTimeout = timeout }()
+	DefaultWaitTimeout = 50 * time.Millisecond
+
+	if err := f.Pods(testNamespace).WaitReady("nginx", true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitReady error = %v, want context.DeadlineExceeded", err)
+	}
+	if err := f.Jobs(testNamespace).WaitFinish("pi"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitFinish error = %v, want context.DeadlineExceeded", err)
+	}
+	if err := f.Jobs(testNamespace).WaitNotExist("pi"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitNotExist error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestWaitForErrorBackoff(t *testing.T) {
+	f := newTestFactory(newTestPod("nginx", false))
+	interval := watchRetryInterval
+	defer func() { watchRetryInterval = interval }()
+	watchRetryInterval = 100 * time.Millisecond
+
+	// every watch fails with an error event other than expired.
+	var gets, watches int32
+	clientset := f.clientset.(*fake.Clientset)
+	clientset.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		atomic.AddInt32(&gets, 1)
+		return false, nil, nil
+	})
+	clientset.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		atomic.AddInt32(&watches, 1)
+		watcher := watch.NewFakeWithChanSize(1, false)
+		watcher.Error(&metav1.Status{Status: metav1.StatusFailure, Code: 500, Reason: metav1.StatusReasonInternalError})
+		return true, watcher, nil
+	})
+
+	err := f.Pods(testNamespace).WaitFor("nginx", ConditionReady, 350*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want context.DeadlineExceeded", err)
+	}
+	if n := atomic.LoadInt32(&watches); n > 5 {
+		t.Errorf("%d watches in 350ms, want at most 5", n)
+	}
+	if n := atomic.LoadInt32(&gets); n > 5 {
+		t.Errorf("%d gets in 350ms, want at most 5", n)
+	}
+}
@@ -0,0 +1,292 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// watchRetryInterval is how long WatchEvents waits before it watches again
+// if the watch failed.
+var watchRetryInterval = time.Second
+
+// Event is a typed watch event of WatchEvents.
+type Event[T any] struct {
+	// Type is watch.Added, watch.Modified, watch.Deleted, or watch.Bookmark
+	// if WatchOptions.Bookmarks is true.
+	Type watch.EventType
+	// Old is the object before the event, nil if it's not seen before, eg: the
+	// Added events. It's the last state of the object for Deleted.
+	Old *T
+	// New is the object after the event, nil for Deleted. For Bookmark, only
+	// its resourceVersion is set.
+	New *T
+}
+
+// WatchOptions selects the objects of WatchEvents and where to start.
+type WatchOptions struct {
+	// Name watches the object of the name only.
+	Name          string
+	LabelSelector string
+	FieldSelector string
+	// ResourceVersion is where the watch starts. If it's empty, the objects
+	// are listed first and the watch starts from the resourceVersion of the list.
+	ResourceVersion string
+	// SendInitialEvents sends an Added event for every listed object before
+	// the changes, like "kubectl get --watch". Ignored if ResourceVersion is set.
+	SendInitialEvents bool
+	// Bookmarks sends the Bookmark events to the channel, they are used to
+	// resume the watch even if it's false.
+	Bookmarks bool
+}
+
+// eventWatcher keeps the objects it has seen, so the events carry the old
+// objects, and the changes missed during a relist are sent as events.
+type eventWatcher[T, TList any] struct {
+	h       *Handler[T, TList]
+	opts    WatchOptions
+	ch      chan Event[T]
+	objects map[string]*T
+}
+
+// WatchEvents watches the objects and sends the typed events to the returned
+// channel, until ctx is done, then the channel is closed. The watch is resumed
+// from the last resourceVersion when it's closed by the server, the objects
+// are listed again if the resourceVersion is expired (410 Gone), and the
+// changes missed are sent as events. eg:
+//
+//	events, err := p.WatchEvents(ctx, k8s.WatchOptions{LabelSelector: "app=nginx"})
+//	for event := range events {
+//		if event.Type == watch.Modified && event.Old.Status.Phase != event.New.Status.Phase {
+//			log.Infof("pod %s: %s -> %s", event.New.Name, event.Old.Status.Phase, event.New.Status.Phase)
+//		}
+//	}
+func (h *Handler[T, TList]) WatchEvents(ctx context.Context, opts WatchOptions) (<-chan Event[T], error) {
+	w := &eventWatcher[T, TList]{h: h, opts: opts, ch: make(chan Event[T]), objects: make(map[string]*T)}
+	resourceVersion := opts.ResourceVersion
+	var initial []Event[T]
+	if len(resourceVersion) == 0 {
+		var err error
+		if resourceVersion, initial, err = w.list(ctx); err != nil {
+			return nil, err
+		}
+		if !opts.SendInitialEvents {
+			initial = nil
+		}
+	}
+	go func() {
+		defer close(w.ch)
+		for _, event := range initial {
+			if !w.send(ctx, event) {
+				return
+			}
+		}
+		w.run(ctx, resourceVersion)
+	}()
+	return w.ch, nil
+}
+
+func (w *eventWatcher[T, TList]) listOptions() metav1.ListOptions {
+	opts := metav1.ListOptions{LabelSelector: w.opts.LabelSelector, FieldSelector: w.opts.FieldSelector}
+	if len(w.opts.Name) != 0 {
+		nameSelector := "metadata.name=" + w.opts.Name
+		if len(opts.FieldSelector) != 0 {
+			nameSelector += "," + opts.FieldSelector
+		}
+		opts.FieldSelector = nameSelector
+	}
+	return opts
+}
+
+// list lists the objects and replaces the objects seen, it returns the
+// resourceVersion of the list and the events of the changes since last list.
+func (w *eventWatcher[T, TList]) list(ctx context.Context) (string, []Event[T], error) {
+	list, err := w.h.client(w.h.namespace).List(ctx, w.listOptions())
+	if err != nil {
+		return "", nil, err
+	}
+	listObj, ok := any(list).(runtime.Object)
+	if !ok {
+		return "", nil, fmt.Errorf("%T is not a runtime.Object", list)
+	}
+	listMeta, err := meta.ListAccessor(listObj)
+	if err != nil {
+		return "", nil, err
+	}
+	items, err := meta.ExtractList(listObj)
+	if err != nil {
+		return "", nil, err
+	}
+	var events []Event[T]
+	objects := make(map[string]*T, len(items))
+	for _, item := range items {
+		obj, ok := any(item).(*T)
+		if !ok || !w.matches(obj) {
+			continue
+		}
+		key, _ := objectKey(obj)
+		objects[key] = obj
+		old, ok := w.objects[key]
+		switch {
+		case !ok:
+			events = append(events, Event[T]{Type: watch.Added, New: obj})
+		case resourceVersionOf(old) != resourceVersionOf(obj):
+			events = append(events, Event[T]{Type: watch.Modified, Old: old, New: obj})
+		}
+	}
+	for key, old := range w.objects {
+		if _, ok := objects[key]; !ok {
+			events = append(events, Event[T]{Type: watch.Deleted, Old: old})
+		}
+	}
+	w.objects = objects
+	return listMeta.GetResourceVersion(), events, nil
+}
+
+// run watches from the resourceVersion until ctx is done.
+func (w *eventWatcher[T, TList]) run(ctx context.Context, resourceVersion string) {
+	for ctx.Err() == nil {
+		// the resourceVersion is expired, list again and send the changes missed.
+		if len(resourceVersion) == 0 {
+			var (
+				events []Event[T]
+				err    error
+			)
+			if resourceVersion, events, err = w.list(ctx); err != nil {
+				log.Debugf("watch %s: list: %v", w.h.kind, err)
+				sleepRetry(ctx)
+				continue
+			}
+			for _, event := range events {
+				if !w.send(ctx, event) {
+					return
+				}
+			}
+		}
+		listOptions := w.listOptions()
+		listOptions.ResourceVersion = resourceVersion
+		listOptions.AllowWatchBookmarks = true
+		watcher, err := w.h.client(w.h.namespace).Watch(ctx, listOptions)
+		if err != nil {
+			log.Debugf("watch %s: %v", w.h.kind, err)
+			if k8serrors.IsResourceExpired(err) || k8serrors.IsGone(err) {
+				resourceVersion = ""
+			} else {
+				sleepRetry(ctx)
+			}
+			continue
+		}
+		resourceVersion = w.consume(ctx, watcher, resourceVersion)
+		watcher.Stop()
+		log.Debugf("watch %s: reconnect to kubernetes from resourceVersion %q", w.h.kind, resourceVersion)
+	}
+}
+
+// consume sends the events of the watcher until it's closed, it returns the
+// last resourceVersion, or empty if it's expired. It waits watchRetryInterval
+// before returning on the other error events.
+func (w *eventWatcher[T, TList]) consume(ctx context.Context, watcher watch.Interface, resourceVersion string) string {
+	for {
+		var event watch.Event
+		select {
+		case <-ctx.Done():
+			return resourceVersion
+		case e, ok := <-watcher.ResultChan():
+			if !ok {
+				return resourceVersion
+			}
+			event = e
+		}
+		if event.Type == watch.Error {
+			err := k8serrors.FromObject(event.Object)
+			log.Debugf("watch %s: %v", w.h.kind, err)
+			if k8serrors.IsResourceExpired(err) || k8serrors.IsGone(err) {
+				return ""
+			}
+			// back off, the server may keep failing the watch.
+			sleepRetry(ctx)
+			return resourceVersion
+		}
+		obj, ok := any(event.Object).(*T)
+		if !ok {
+			continue
+		}
+		resourceVersion = resourceVersionOf(obj)
+		if event.Type == watch.Bookmark {
+			if w.opts.Bookmarks && !w.send(ctx, Event[T]{Type: watch.Bookmark, New: obj}) {
+				return resourceVersion
+			}
+			continue
+		}
+		if !w.matches(obj) {
+			continue
+		}
+		key, _ := objectKey(obj)
+		old := w.objects[key]
+		e := Event[T]{Type: event.Type, Old: old, New: obj}
+		if event.Type == watch.Deleted {
+			delete(w.objects, key)
+			e = Event[T]{Type: watch.Deleted, Old: obj}
+		} else {
+			w.objects[key] = obj
+		}
+		if !w.send(ctx, e) {
+			return resourceVersion
+		}
+	}
+}
+
+// matches reports whether the object has the name of the options, in case
+// the field selector is not supported, eg: the fake clientset.
+func (w *eventWatcher[T, TList]) matches(obj *T) bool {
+	if len(w.opts.Name) == 0 {
+		return true
+	}
+	accessor, err := meta.Accessor(obj)
+	return err == nil && accessor.GetName() == w.opts.Name
+}
+
+func (w *eventWatcher[T, TList]) send(ctx context.Context, event Event[T]) bool {
+	select {
+	case w.ch <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sleepRetry waits watchRetryInterval before a failed watch is retried, or
+// until ctx is done.
+func sleepRetry(ctx context.Context) {
+	select {
+	case <-time.After(watchRetryInterval):
+	case <-ctx.Done():
+	}
+}
+
+// objectKey returns "namespace/name" of the object.
+func objectKey(obj any) (string, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", err
+	}
+	if len(accessor.GetNamespace()) == 0 {
+		return accessor.GetName(), nil
+	}
+	return accessor.GetNamespace() + "/" + accessor.GetName(), nil
+}
+
+func resourceVersionOf(obj any) string {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return accessor.GetResourceVersion()
+}
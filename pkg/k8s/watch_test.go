@@ -0,0 +1,206 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func nextEvent(t *testing.T, events <-chan Event[corev1.Pod]) Event[corev1.Pod] {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("events channel closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+	return Event[corev1.Pod]{}
+}
+
+func TestWatchEvents(t *testing.T) {
+	p := newTestFactory(newTestPod("a", true)).Pods(testNamespace)
+	client := p.factory.clientset.CoreV1().Pods(testNamespace)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	events, err := p.WatchEvents(ctx, WatchOptions{LabelSelector: "app=nginx", SendInitialEvents: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, events); event.Type != watch.Added || event.Old != nil || event.New.Name != "a" {
+		t.Fatalf("initial event = %v %v", event.Type, event.New)
+	}
+
+	if _, err := client.Create(p.ctx, newTestPod("b", false), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, events); event.Type != watch.Added || event.New.Name != "b" {
+		t.Fatalf("event = %v %v, want Added b", event.Type, event.New)
+	}
+	if _, err := client.Update(p.ctx, newTestPod("b", true), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	event := nextEvent(t, events)
+	if event.Type != watch.Modified || event.Old == nil || event.Old.Status.ContainerStatuses[0].Ready || !event.New.Status.ContainerStatuses[0].Ready {
+		t.Fatalf("event = %v, old = %v, new = %v, want Modified from not ready to ready", event.Type, event.Old, event.New)
+	}
+	if err := client.Delete(p.ctx, "a", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, events); event.Type != watch.Deleted || event.New != nil || event.Old.Name != "a" {
+		t.Fatalf("event = %v %v, want Deleted a", event.Type, event.Old)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchEventsResume(t *testing.T) {
+	f := newTestFactory(newTestPod("a", true))
+	p := f.Pods(testNamespace)
+	clientset := f.clientset.(*fake.Clientset)
+
+	// the first two watches are driven by the test, the rest are served by
+	// the object tracker.
+	watchers := []*watch.FakeWatcher{watch.NewFakeWithChanSize(10, false), watch.NewFakeWithChanSize(10, false)}
+	resourceVersions := make(chan string, 10)
+	clientset.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		resourceVersions <- action.(k8stesting.WatchAction).GetWatchRestrictions().ResourceVersion
+		if len(watchers) == 0 {
+			return false, nil, nil
+		}
+		watcher := watchers[0]
+		watchers = watchers[1:]
+		return true, watcher, nil
+	})
+	first, second := watchers[0], watchers[1]
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	events, err := p.WatchEvents(ctx, WatchOptions{Bookmarks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-resourceVersions
+
+	// b is only known by the watch.
+	first.Add(newTestPod("b", true))
+	if event := nextEvent(t, events); event.Type != watch.Added || event.New.Name != "b" {
+		t.Fatalf("event = %v %v, want Added b", event.Type, event.New)
+	}
+	bookmark := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "100"}}
+	first.Action(watch.Bookmark, bookmark)
+	if event := nextEvent(t, events); event.Type != watch.Bookmark || event.New.ResourceVersion != "100" {
+		t.Fatalf("event = %v %v, want Bookmark", event.Type, event.New)
+	}
+
+	// the watch is closed by the server, it's resumed from the bookmark.
+	first.Stop()
+	if rv := <-resourceVersions; rv != "100" {
+		t.Errorf("watch resumed from resourceVersion %q, want 100", rv)
+	}
+
+	// the resourceVersion is expired, the objects are listed again and the
+	// changes missed are sent.
+	if _, err := clientset.CoreV1().Pods(testNamespace).Create(p.ctx, newTestPod("c", true), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	second.Error(&metav1.Status{Status: metav1.StatusFailure, Code: 410, Reason: metav1.StatusReasonExpired})
+	got := make(map[string]watch.EventType)
+	for i := 0; i < 2; i++ {
+		event := nextEvent(t, events)
+		obj := event.New
+		if obj == nil {
+			obj = event.Old
+		}
+		got[obj.Name] = event.Type
+	}
+	if got["b"] != watch.Deleted || got["c"] != watch.Added || len(got) != 2 {
+		t.Errorf("events after relist = %v, want b deleted and c added", got)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchEventsErrorBackoff(t *testing.T) {
+	f := newTestFactory(newTestPod("a", true))
+	interval := watchRetryInterval
+	defer func() { watchRetryInterval = interval }()
+	watchRetryInterval = 200 * time.Millisecond
+
+	// the first watch is driven by the test, the rest are never closed.
+	watchers := []watch.Interface{watch.NewFakeWithChanSize(10, false)}
+	watcher := watchers[0].(*watch.FakeWatcher)
+	watched := make(chan time.Time, 10)
+	f.clientset.(*fake.Clientset).PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		watched <- time.Now()
+		if len(watchers) == 0 {
+			return true, watch.NewFake(), nil
+		}
+		w := watchers[0]
+		watchers = watchers[1:]
+		return true, w, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	events, err := f.Pods(testNamespace).WatchEvents(ctx, WatchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-watched
+
+	// an error other than expired, the watch is resumed after the interval.
+	start := time.Now()
+	watcher.Error(&metav1.Status{Status: metav1.StatusFailure, Code: 500, Reason: metav1.StatusReasonInternalError})
+	select {
+	case reconnected := <-watched:
+		if elapsed := reconnected.Sub(start); elapsed < watchRetryInterval {
+			t.Errorf("watch reconnected after %v, want at least %v", elapsed, watchRetryInterval)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch not reconnected")
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchByLabelExisting(t *testing.T) {
+	p := newTestFactory(newTestPod("a", true)).Pods(testNamespace).DeepCopy()
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	p.ctx = ctx
+
+	added := make(chan interface{}, 10)
+	done := make(chan error, 1)
+	nop := func(x interface{}) {}
+	go func() {
+		done <- p.WatchByLabel("app=nginx", func(x interface{}) { added <- x }, nop, nop, "a")
+	}()
+	// the pod exists before the watch.
+	select {
+	case x := <-added:
+		if x != "a" {
+			t.Errorf("addFunc called with %v, want a", x)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("addFunc is not called for the existing pod")
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("WatchByLabel = %v, want context.Canceled", err)
+	}
+}